@@ -0,0 +1,230 @@
+/*
+Package client is a Go client for the HTTP API exposed by the find-frustration
+"serve" subcommand.  It submits problems and decodes responses into typed
+structs so that callers need not construct HTTP requests by hand.
+
+The API is described formally by openapi.yaml in the top-level directory of
+the find-frustration repository.
+*/
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Job statuses
+const (
+	JobQueued  = "queued"  // Waiting for a free worker
+	JobRunning = "running" // Being analyzed
+	JobDone    = "done"    // Completed successfully
+	JobFailed  = "failed"  // Completed unsuccessfully
+)
+
+// A Score is a single number in [0, 1] summarizing how frustrated a graph is,
+// along with the components from which it was computed.
+type Score struct {
+	Score            float64    `json:"score"`             // Weighted mean of the three components
+	CycleFraction    float64    `json:"cycle_fraction"`    // Fraction of base cycles that are frustrated
+	WeightedFraction float64    `json:"weighted_fraction"` // Fraction of cycle energy gaps belonging to frustrated cycles
+	IndexFraction    float64    `json:"index_fraction"`    // Frustration-index bound relative to its maximum of half the edges
+	Weights          [3]float64 `json:"weights"`           // Weights applied to the three components
+}
+
+// A VertexResult tallies the cycles in which a vertex appears.
+type VertexResult struct {
+	Name                string `json:"name"`                  // Vertex name
+	Frustrated          bool   `json:"frustrated"`            // true if more often in frustrated than non-frustrated cycles
+	FrustratedCycles    int    `json:"frustrated_cycles"`     // Number of frustrated cycles containing the vertex
+	NonFrustratedCycles int    `json:"non_frustrated_cycles"` // Number of non-frustrated cycles containing the vertex
+}
+
+// An EdgeResult tallies the cycles in which an edge appears.
+type EdgeResult struct {
+	Vertices            [2]string `json:"vertices"`              // Edge endpoints
+	Frustrated          bool      `json:"frustrated"`            // true if more often in frustrated than non-frustrated cycles
+	FrustratedCycles    int       `json:"frustrated_cycles"`     // Number of frustrated cycles containing the edge
+	NonFrustratedCycles int       `json:"non_frustrated_cycles"` // Number of non-frustrated cycles containing the edge
+}
+
+// A CycleResult says whether a cycle is frustrated.
+type CycleResult struct {
+	Vertices   []string `json:"vertices"`   // Vertices in cycle order
+	Frustrated bool     `json:"frustrated"` // true if the cycle is frustrated
+}
+
+// A VariableResult associates a bqpjson variable ID with its name.
+type VariableResult struct {
+	ID   int    `json:"id"`   // bqpjson variable ID
+	Name string `json:"name"` // Name given by the input's metadata ("" if none)
+}
+
+// A Summary presents aggregate frustration statistics.
+type Summary struct {
+	FrustratedVertices  int     `json:"frustrated_vertices"`  // Number of frustrated vertices
+	TotalVertices       int     `json:"total_vertices"`       // Total number of vertices
+	VertexFraction      float64 `json:"vertex_fraction"`      // Fraction of vertices that are frustrated
+	FrustratedEdges     int     `json:"frustrated_edges"`     // Number of frustrated edges
+	TotalEdges          int     `json:"total_edges"`          // Total number of edges
+	EdgeFraction        float64 `json:"edge_fraction"`        // Fraction of edges that are frustrated
+	FrustratedCycles    int     `json:"frustrated_cycles"`    // Number of frustrated cycles
+	TotalCycles         int     `json:"total_cycles"`         // Total number of cycles analyzed
+	CycleFraction       float64 `json:"cycle_fraction"`       // Fraction of cycles that are frustrated
+	BaseCycles          int     `json:"base_cycles"`          // Number of base cycles
+	ElementaryCycles    int     `json:"elementary_cycles"`    // Number of elementary cycles (0 if not computed)
+	FrustrationPossible bool    `json:"frustration_possible"` // false if the graph is acyclic
+}
+
+// Results represents everything learned from a frustration analysis.
+type Results struct {
+	Vertices  []VertexResult   `json:"vertices"`            // Per-vertex tallies
+	Edges     []EdgeResult     `json:"edges"`               // Per-edge tallies
+	Cycles    []CycleResult    `json:"cycles"`              // Per-cycle frustration
+	Summary   Summary          `json:"summary"`             // Aggregate statistics
+	Variables []VariableResult `json:"variables,omitempty"` // Variable ID-to-name table (bqpjson with var_names only)
+}
+
+// A Job is a single analysis submitted to the server's job queue.
+type Job struct {
+	ID        string    `json:"id"`                // Unique job identifier
+	Status    string    `json:"status"`            // One of the Job* status constants
+	Format    string    `json:"format"`            // Input format
+	AllCycles bool      `json:"all_cycles"`        // true if elementary cycles are analyzed
+	Seed      int64     `json:"seed"`              // Seed of the job's pseudorandom choices
+	Submitted time.Time `json:"submitted"`         // Time at which the job was submitted
+	Finished  time.Time `json:"finished"`          // Time at which the job completed
+	Error     string    `json:"error,omitempty"`   // Reason for failure
+	Results   *Results  `json:"results,omitempty"` // Analysis results (nil until done)
+}
+
+// An APIError is an error reported by the server.
+type APIError struct {
+	StatusCode int    // HTTP status code
+	Message    string // Server's explanation of the error
+}
+
+// Error returns the server's explanation along with the HTTP status.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("find-frustration server: %s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// A Client communicates with a find-frustration server.
+type Client struct {
+	BaseURL    string       // Server URL, e.g., "http://localhost:8080"
+	Token      string       // Access token sent as a bearer token ("" for none)
+	HTTPClient *http.Client // Client used for requests (nil for http.DefaultClient)
+}
+
+// New returns a client for the server at a given base URL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// do sends a request and decodes a JSON response into out.  Non-2xx
+// responses are returned as an *APIError.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, out interface{}) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&e) != nil || e.Error == "" {
+			e.Error = http.StatusText(resp.StatusCode)
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Score computes the frustration score of a problem in the given input
+// format ("" for the server's default).  If weights is nil, the server's
+// default score weights are used.
+func (c *Client) Score(ctx context.Context, problem io.Reader, format string, weights []float64) (Score, error) {
+	q := url.Values{}
+	if format != "" {
+		q.Set("format", format)
+	}
+	if weights != nil {
+		ws := make([]string, len(weights))
+		for i, wt := range weights {
+			ws[i] = strconv.FormatFloat(wt, 'g', -1, 64)
+		}
+		q.Set("weights", strings.Join(ws, ","))
+	}
+	var sc Score
+	err := c.do(ctx, http.MethodPost, "/score", q, problem, &sc)
+	return sc, err
+}
+
+// SubmitJob queues a problem in the given input format ("" for the server's
+// default) for asynchronous analysis and returns the newly queued job.
+// allCycles requests analysis of elementary rather than base cycles.
+func (c *Client) SubmitJob(ctx context.Context, problem io.Reader, format string, allCycles bool) (Job, error) {
+	q := url.Values{}
+	if format != "" {
+		q.Set("format", format)
+	}
+	if allCycles {
+		q.Set("all_cycles", "true")
+	}
+	var j Job
+	err := c.do(ctx, http.MethodPost, "/jobs", q, problem, &j)
+	return j, err
+}
+
+// Job returns the current state of the job with a given ID.
+func (c *Client) Job(ctx context.Context, id string) (Job, error) {
+	var j Job
+	err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, nil, &j)
+	return j, err
+}
+
+// WaitJob polls the job with a given ID at a given interval until it
+// finishes or ctx is done.  A job that fails is returned along with an error
+// giving the reason.
+func (c *Client) WaitJob(ctx context.Context, id string, interval time.Duration) (Job, error) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		j, err := c.Job(ctx, id)
+		switch {
+		case err != nil:
+			return j, err
+		case j.Status == JobFailed:
+			return j, fmt.Errorf("job %s failed: %s", j.ID, j.Error)
+		case j.Status == JobDone:
+			return j, nil
+		}
+		select {
+		case <-ctx.Done():
+			return j, ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
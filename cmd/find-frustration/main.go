@@ -0,0 +1,985 @@
+/*
+find-frustration reads a graph and reports various statistics on how
+much frustration exists in the graph when treated as an Ising or QUBO
+problem.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lanl/find-frustration/frustration"
+)
+
+// notify is used to output error messages.
+var notify *log.Logger
+
+// An edgeList is a repeatable command-line flag that accumulates edges, each
+// specified as "u,v".
+type edgeList [][2]string
+
+// String formats an edgeList as a space-separated list of "u,v" pairs.
+func (el *edgeList) String() string {
+	ss := make([]string, len(*el))
+	for i, e := range *el {
+		ss[i] = e[0] + "," + e[1]
+	}
+	return strings.Join(ss, " ")
+}
+
+// Set parses a "u,v" pair and appends it to an edgeList in canonical order.
+func (el *edgeList) Set(s string) error {
+	uv := strings.Split(s, ",")
+	if len(uv) != 2 || uv[0] == "" || uv[1] == "" {
+		return fmt.Errorf("expected an edge of the form u,v but saw %q", s)
+	}
+	u, v := strings.TrimSpace(uv[0]), strings.TrimSpace(uv[1])
+	if u > v {
+		u, v = v, u
+	}
+	*el = append(*el, [2]string{u, v})
+	return nil
+}
+
+func main() {
+	// Report fatal errors and exit.
+	notify = log.New(os.Stderr, os.Args[0]+": ", 0)
+	frustration.Notify = notify
+	defer func() {
+		if r := recover(); r != nil {
+			flushOutput()
+			if fe, ok := r.(frustration.FatalError); ok {
+				notify.Fatal(fe)
+			}
+			panic(r)
+		}
+	}()
+
+	// Determine which subcommand to run.
+	cmd := ""
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "cycles", "compare-solvers", "score", "hardness", "embedding-score", "serve", "audit", "generate", "merge", "sweep", "stats":
+			cmd = os.Args[1]
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		}
+	}
+
+	// Parse the command line.
+	var err error
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [cycles | compare-solvers | score | hardness | embedding-score | serve | audit | generate | merge | sweep | stats] [options] [input-file | shard-file... | directory]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	inFmt := ""
+	flag.StringVar(&inFmt, "format", "qubist", `input file format: "qubist" (default), "qubo", "qmasm", "bqpjson", "bqpjson-batch", "ffg", "maxcut", "coloring", "sapi", "graphml", "mtx", "csv", or "tsv"`)
+	flag.StringVar(&inFmt, "f", "qubist", "shorthand for --format")
+	outFile := ""
+	flag.StringVar(&outFile, "output", "", "output file name (default: standard output)")
+	flag.StringVar(&outFile, "o", "", "shorthand for --output")
+	colors := flag.Int("colors", 0, `number of colors with which to encode a "coloring" input (default: 0, one more than the maximum degree)`)
+	gmlWeight := flag.String("graphml-weight", "weight", `edge attribute of a "graphml" input that holds each coupler strength, prefixed with "-" to negate it (e.g., "-weight" for a signed network, whose positive edges are ferromagnetic)`)
+	gmlField := flag.String("graphml-field", "h", `node attribute of a "graphml" input that holds each vertex's field, if declared`)
+	delim := flag.String("delimiter", "", `column delimiter of a "csv" or "tsv" input, a single character or "tab" (default: "", "," for csv and a tab for tsv)`)
+	sapiH := flag.String("sapi-h", "", `file containing the h vector of a "sapi" input, whose input file contains the J dictionary (default: "", no fields)`)
+	vNames := flag.String("vertex-names", "id", `how to name bqpjson variables in the output: "id" (default, integer variable IDs) or "metadata" (names from the metadata's var_names)`)
+	allCycs := flag.Bool("all-cycles", false, "Analyze every elementary cycle rather than only the base cycles (slow for graphs with many cycles; default: false)")
+	var ropts frustration.ReportOptions
+	flag.BoolVar(&ropts.Explain, "explain", false, "Explain why each frustrated cycle is frustrated (default: false)")
+	flag.BoolVar(&ropts.Centrality, "edge-centrality", false, "Rank edges by the fraction of cycles through them that are frustrated (default: false)")
+	flag.BoolVar(&ropts.EnergyGaps, "energy-gaps", false, "Report the energy penalty of resolving each frustrated cycle (default: false)")
+	flag.BoolVar(&ropts.Symmetry, "symmetry-classes", false, "Collapse symmetric vertices and edges into one output line per class (default: false)")
+	flag.BoolVar(&ropts.SignFlips, "sign-flips", false, "Report how many cycles flipping each edge's sign would fix and break (default: false)")
+	flag.StringVar(&ropts.CoeffView, "coeff-view", frustration.ViewIsing, `convention in which to report coefficients: "ising" (default) or "qubo" (QUBO inputs only)`)
+	flag.StringVar(&ropts.Cycles, "cycles", frustration.CyclesAll, `which cycles to output: "all" (default), "frustrated-only", or "none"`)
+	flag.StringVar(&ropts.Histogram, "cycle-histogram", "", `draw a histogram of frustrated and non-frustrated cycles by length: "unicode" or "ascii" (default: "", none)`)
+	flag.IntVar(&ropts.MinLen, "min-len", 0, "Output only cycles of at least this many edges (default: 0)")
+	flag.IntVar(&ropts.MaxLen, "max-len", 0, "Output only cycles of at most this many edges (default: 0, unlimited)")
+	flag.IntVar(&ropts.Reservoir, "cycle-reservoir", 0, "Output only a sample of this many frustrated and this many non-frustrated cycles, stratified by length (default: 0, all cycles)")
+	flag.BoolVar(&ropts.VertexFields, "vertex-fields", false, "Report each frustrated vertex's field and incident coupling (default: false)")
+	vRule := flag.String("vertex-rule", frustration.RuleMajority, `rule for deeming a vertex frustrated: "majority" (default), "any", "ratio:R", or "weighted"`)
+	eRule := flag.String("edge-rule", frustration.RuleMajority, `rule for deeming an edge frustrated: "majority" (default), "any", "ratio:R", or "weighted"`)
+	restarts := flag.Int("switching", -1, "Estimate the frustration index by local switching with this many random restarts (default: -1, disabled)")
+	baseFile := flag.String("assert-baseline", "", "JSON results of an earlier analysis; fail if frustration has increased beyond --baseline-tolerances")
+	baseTols := flag.String("baseline-tolerances", "0,0,0", "Comma-separated amounts by which the frustrated vertex, edge, and cycle fractions may exceed those of --assert-baseline")
+	outFmt := flag.String("output-format", "text", `format of the analysis's output: "text" (default), for tagged lines, or "json", for the same document as --save-results`)
+	saveRes := flag.String("save-results", "", "File to which to save the results in JSON format, e.g., for a later --assert-baseline")
+	dotOut := flag.String("frustration-dot", "", "File to which to write the graph in Graphviz DOT format with frustrated edges and vertices highlighted")
+	matOut := flag.String("matrices-out", "", "Prefix of files to which to write the signed adjacency and cycle-edge incidence matrices in Matrix Market format")
+	afRestarts := flag.Int("antiferromagnet", -1, "If every coupling is antiferromagnetic, report odd cycles and a maximum cut, found with this many random restarts, instead of the full analysis (default: -1, disabled)")
+	cutRestarts := flag.Int("frustrated-cut", -1, "Find the bipartition that cuts the most frustrated edges, by local search with this many random restarts (default: -1, disabled)")
+	remediate := flag.Bool("remediate", false, "Output a ranked plan of coupler edits that greedily eliminates frustrated cycles (default: false)")
+	remEdits := flag.String("remediate-edits", "flip,reweight,remove", `comma-separated kinds of coupler edit --remediate may propose: "flip", "reweight", and/or "remove"`)
+	basisStats := flag.Bool("basis-stats", false, "Report the lengths of the base cycles and how heavily they overlap (default: false)")
+	sbm := flag.Bool("sbm", false, "Fit a two-group signed stochastic block model to the graph (default: false)")
+	balCheck := flag.Bool("balance-check", true, "Exit early with a gauge transformation if the graph contains no frustration")
+	timing := flag.Bool("timing", false, "Report the wall-clock time spent in each phase of the analysis (default: false)")
+	outBuf := flag.String("output-buffer", "", "Buffer up to this much output, e.g., 64M, and write it in the background so that a slow output sink does not stall the analysis (default: unbuffered)")
+	flushInt := flag.Duration("flush-interval", time.Second, "Interval at which --output-buffer flushes buffered output")
+	threads := flag.Int("threads", 0, "Number of goroutines that classify cycles (default: 0, GOMAXPROCS)")
+	maxMem := flag.String("max-memory", "", "Maximum memory to use, e.g., 512M or 16G, before stopping early (default: unlimited)")
+	seed := flag.Int64("seed", 0, "Seed for every pseudorandom choice, making runs reproducible (default: 0, seeded from the clock)")
+	sweeps := flag.Int("sweeps", 1000, "Number of Monte Carlo sweeps performed by the annealing and tempering solvers")
+	saveGraph := flag.String("save-graph", "", "File to which to save the parsed graph")
+	saveFmt := flag.String("save-format", "ffg", `format of the --save-graph file: "ffg" (default, find-frustration's binary format), "qubist", "qmasm", "dot", or "graphml"`)
+	ppOut := flag.String("preprocessed-out", "", "File to which to save the graph as analyzed, after clipping and preprocessing")
+	ppFmt := flag.String("preprocessed-format", "ffg", `format of the --preprocessed-out file, accepting the same values as --save-format`)
+	suppress := flag.String("suppress", "", `comma-separated warning codes, e.g., "W001,W005", whose warnings to discard (default: "", none)`)
+	failWarn := flag.Bool("fail-on-warning", false, `Exit with status 3 if any unsuppressed warning of severity "warning" was issued (default: false)`)
+	record := flag.String("record", "", "Zip file in which to archive the run's input, flags, seed, version, and output for a later --replay")
+	replay := flag.String("replay", "", "Zip file written by --record whose run to repeat, exiting with status 4 if the output differs")
+	var wopts frustration.WriteOptions
+	flag.IntVar(&wopts.EdgeBins, "edge-bins", 5, "Number of distinct edge widths in dot and graphml output")
+	flag.StringVar(&wopts.EdgeBinning, "edge-binning", "quantile", `how dot and graphml output assigns edges to width bins by |J|: "quantile" (default) or "linear"`)
+	forest := flag.Bool("spanning-forest", false, "Report a maximum-weight spanning forest and the chords it forces to be frustrated (default: false)")
+	scoreWts := flag.String("score-weights", "1,1,1", "Comma-separated weights of the cycle, weighted, and index components of a score")
+	listen := flag.String("listen", ":8080", `address on which the "serve" subcommand listens`)
+	jobDir := flag.String("job-dir", "", `directory in which the "serve" subcommand persists asynchronous jobs (default: "", jobs disabled)`)
+	maxJobs := flag.Int("max-jobs", 1, `maximum number of asynchronous jobs the "serve" subcommand runs at once`)
+	jobTimeout := flag.Duration("job-timeout", time.Hour, `maximum time for which an asynchronous job's cycle computations may run (0: unlimited)`)
+	jobRetention := flag.Duration("job-retention", 7*24*time.Hour, `time for which the "serve" subcommand keeps a finished asynchronous job (0: forever)`)
+	redact := flag.Bool("redact-names", false, `Show asynchronous job results' vertex names only to the job's submitter (default: false)`)
+	var limits frustration.InputLimits
+	flag.IntVar(&limits.MaxLineBytes, "max-line-bytes", 1<<20, "Maximum length in bytes of a line of textual input (0: unlimited)")
+	flag.IntVar(&limits.MaxNameBytes, "max-name-bytes", 1024, "Maximum length in bytes of a vertex name (0: unlimited)")
+	maxJobBytes := flag.Int64("max-job-bytes", 64<<20, "maximum size in bytes of a problem submitted to the \"serve\" subcommand")
+	solFile := flag.String("solutions", "", `file of candidate solutions for the "audit" subcommand`)
+	solFmt := flag.String("solution-format", "auto", `format of the --solutions file: "auto" (default), "bqpjson", "dimod", "text", or "qmasm"`)
+	byMacro := flag.Bool("by-macro", false, "Break down frustration by the QMASM macro instantiation from which each edge arose (default: false)")
+	softBetas := flag.String("soft-frustration", "", "Report thermally weighted frustration at these comma-separated inverse temperatures")
+	noise := flag.Float64("noise", 0, "Standard deviation of Gaussian noise to add to every field and coupler strength when estimating how robust each cycle's frustration is (default: 0, disabled)")
+	noiseN := flag.Int("noise-realizations", 1000, "Number of noisy realizations of the graph to classify with --noise")
+	gaugeInv := flag.Bool("gauge-invariant", false, "Output only quantities that gauge transformations (spin switchings) leave unchanged, adding frustrated-plaquette counts and frustration-index bounds (default: false)")
+	fiMode := flag.String("frustration-index", "", `compute the frustration index: "exact" or "heuristic" (default: not computed)`)
+	fixSpin := flag.Bool("fix-spin", false, "Break the global spin-flip symmetry of field-free problems by fixing one spin in the solvers (default: false)")
+	preproc := flag.Bool("preprocess", false, "Fix variables by dominance and persistency before running solvers (default: false)")
+	nSamples := flag.Int("sample-cycles", 0, "Estimate frustration from this many randomly sampled cycles instead of a cycle basis (default: 0, disabled)")
+	triangles := flag.Bool("triangles", false, "Report frustrated triangles, counted from the signed adjacency matrix, instead of analyzing cycles (default: false)")
+	sampleWt := flag.String("sample-weighting", "uniform", `distribution from which --sample-cycles draws: "uniform" (default) or "abs-j", to root cycles at edges drawn uniformly or in proportion to |J|, or "cycle-space", to draw elementary cycles uniformly`)
+	trivRatio := flag.Float64("trivial-ratio", 0, "Report frustrated cycles whose weakest coupler is at most this fraction of every other as trivially resolvable (default: 0, disabled)")
+	exclTriv := flag.Bool("exclude-trivial", false, "Exclude trivially resolvable frustrated cycles from all other statistics (default: false)")
+	byPrefix := flag.String("by-prefix", "", `characters that end the family prefix of a variable name (e.g., "[_"), for breaking down frustration by variable family (default: "", disabled)`)
+	aggK := flag.Int("aggregate-only", 0, "Report only aggregate statistics, naming a --by-prefix family only if it contains at least this many variables (default: 0, disabled)")
+	subFile := flag.String("subset", "", "File listing variables of interest, for reporting frustration inside, outside, and on the boundary of that subset")
+	anoms := flag.Bool("anomalies", false, "Flag couplers in frustrated cycles whose sign or magnitude looks like a typo (default: false)")
+	anomFactor := flag.Float64("anomaly-factor", 1000, "Ratio to the median coupler magnitude beyond which --anomalies flags a coupler")
+	fcore := flag.Bool("frustration-core", false, "Report the vertices that remain after peeling away all vertices in no frustrated cycle (default: false)")
+	exact := flag.Bool("exact-arithmetic", false, "Sum coefficients and determine coupling signs with exact rational arithmetic (slower; qubist and qubo inputs only; default: false)")
+	cancelTol := flag.Float64("cancel-tolerance", 1e-6, "Warn about couplers whose weight is at most this fraction of the total magnitude of the terms summed into it (0: never warn)")
+	fldConf := flag.Bool("field-conflicts", false, "Report vertices whose field opposes the pressure exerted by their neighbors' fields through their couplers (default: false)")
+	fprint := flag.Bool("fingerprint", false, "Report a hash of the frustrated substructure that is independent of vertex names (default: false)")
+	byKind := flag.Bool("by-edge-kind", false, "Break down frustration by edge kind: chain, logical, penalty, or encoding (default: false)")
+	byGadget := flag.Bool("by-gadget", false, "Recognize one-hot, equality, and independent-set penalty gadgets and break down frustration by gadget kind versus problem edges (default: false)")
+	embFile := flag.String("embedding", "", "JSON file mapping each logical variable to its physical qubits, used to label chain edges")
+	logFile := flag.String("logical", "", `file containing the logical problem of which the input is an embedding, for the "embedding-score" subcommand`)
+	logFmt := flag.String("logical-format", "", `format of the --logical file, accepting the same values as --format (default: "", the same as --format)`)
+	budget := flag.Float64("cycle-budget", 1e6, "Maximum estimated number of elementary cycles for which --all-cycles will proceed")
+	var combine frustration.CombineRule
+	flag.BoolVar(&combine.Shrinking, "shrinking-only", false, "With --all-cycles, combine only cycles whose combination is shorter than both (default: false)")
+	flag.IntVar(&combine.MaxLen, "combine-max-len", 0, "With --all-cycles, discard combined cycles of more than this many edges (default: 0, unlimited)")
+	maxCycLen := flag.Int("max-cycle-length", 0, "With --all-cycles, enumerate only the elementary cycles of at most this many edges, without combining base cycles (default: 0, unlimited)")
+	force := flag.Bool("force", false, "Proceed with --all-cycles even if the estimated number of elementary cycles exceeds --cycle-budget (default: false)")
+	clipH := flag.String("clip-h", "", `Clip external fields to the range "lo,hi" supported by the hardware and report the resulting changes in frustration (default: "", no clipping)`)
+	clipJ := flag.String("clip-j", "", `Clip coupler strengths to the range "lo,hi" supported by the hardware and report the resulting changes in frustration (default: "", no clipping)`)
+	preprocs := flag.String("preprocessors", "", `comma-separated list of preprocessors to apply to the graph before finding cycles (available: "dominance", "core")`)
+	minBasis := flag.Bool("minimum-basis", false, "Analyze a minimum cycle basis, made of the shortest possible cycles, rather than the fundamental cycles of a random spanning tree (default: false)")
+	finder := flag.String("cycle-finder", "", "registered cycle finder to use instead of the one implied by --all-cycles and --through-edge")
+	classifier := flag.String("classifier", "sign-parity", `registered classifier that decides which cycles are frustrated: "sign-parity" (default), "coupler-parity", "min-coupling:THETA", "softened:BETA", or one registered by a site-specific file`)
+	flag.StringVar(classifier, "frustration-def", "sign-parity", "synonym for --classifier")
+	extraReps := flag.String("reporters", "", "comma-separated list of additional registered reporters to run after the built-in reports")
+	var through edgeList
+	flag.Var(&through, "through-edge", "Analyze only cycles passing through edge u,v (repeatable)")
+	var gopts frustration.GeneratorOptions
+	flag.StringVar(&gopts.Topology, "topology", "lattice", `topology for the "generate" subcommand: "lattice" (default), "hypercube", "regular", or "small-world"`)
+	flag.StringVar(&gopts.Dims, "dims", "8x8x8", `side lengths of a generated lattice, or the dimension of a generated hypercube`)
+	flag.BoolVar(&gopts.Periodic, "periodic", false, "Give generated lattices periodic boundary conditions (default: false)")
+	flag.IntVar(&gopts.Vertices, "vertices", 100, "Number of vertices in a generated regular or small-world graph")
+	flag.IntVar(&gopts.Degree, "degree", 3, "Vertex degree of a generated regular or small-world graph")
+	flag.Float64Var(&gopts.Rewire, "rewire", 0.1, "Probability of rewiring each edge of a generated small-world graph")
+	flag.StringVar(&gopts.Disorder, "disorder", "pm", `coupler distribution for the "generate" subcommand: "pm" (default, J = ±1) or "gaussian"`)
+	cycFmt := flag.String("cycle-format", "ndjson", `format for the "cycles" subcommand: "ndjson" (default) or "edges"`)
+	sweepPat := flag.String("sweep-pattern", "", `regular expression whose first parenthesized subexpression extracts the parameter from each file name for the "sweep" subcommand (default: "", the last number in the name)`)
+	shardStr := flag.String("shard", "", `analyze only shard i of N ("i/N") of the cycles or sampled cycles, writing partial results for the "merge" subcommand`)
+	coord := flag.String("coordinator", "", `address on which to listen for workers among which to distribute the analysis (default: "", not distributed)`)
+	nWorkers := flag.Int("workers", 2, "number of workers among which --coordinator divides the analysis")
+	workAddr := flag.String("worker", "", "host:port of a coordinator from which to accept work, instead of reading an input file")
+	flag.Parse()
+	var replayed *sessionReplay
+	if *replay != "" {
+		if cmd != "" {
+			frustration.Abortf("--replay takes the subcommand from the record")
+		}
+		replayed = openReplay(*replay)
+		cmd = replayed.Command()
+		defer replayed.Discard()
+		replayed.RestoreFlags()
+	}
+	var shard *frustration.ShardSpec
+	if *shardStr != "" {
+		sh := frustration.ParseShard(*shardStr)
+		shard = &sh
+		switch {
+		case cmd != "" && cmd != "cycles":
+			frustration.Abortf(`--shard applies only to the default analysis and the "cycles" subcommand`)
+		case *seed == 0:
+			frustration.Abortf("--shard requires an explicit --seed so that every shard finds the same cycles")
+		}
+	}
+	if *coord != "" {
+		switch {
+		case cmd != "":
+			frustration.Abortf("--coordinator applies only to the default analysis")
+		case shard != nil:
+			frustration.Abortf("--coordinator and --shard are mutually exclusive")
+		case *finder != "" || len(through) > 0:
+			frustration.Abortf("--coordinator supports neither --cycle-finder nor --through-edge")
+		}
+	}
+	if (*baseFile != "" || *saveRes != "") && (cmd != "" || shard != nil || *coord != "" || *nSamples > 0) {
+		frustration.Abortf("--assert-baseline and --save-results apply only to the default, unsharded, unsampled analysis")
+	}
+	if *matOut != "" && (cmd != "" || shard != nil || *coord != "" || *nSamples > 0) {
+		frustration.Abortf("--matrices-out applies only to the default, unsharded, unsampled analysis")
+	}
+	if *dotOut != "" && (cmd != "" || shard != nil || *coord != "" || *nSamples > 0) {
+		frustration.Abortf("--frustration-dot applies only to the default, unsharded, unsampled analysis")
+	}
+	if *ppOut != "" && ((cmd != "" && cmd != "cycles") || *nSamples > 0) {
+		frustration.Abortf("--preprocessed-out applies only to the default analysis and the \"cycles\" subcommand, and not to sampling")
+	}
+	switch {
+	case *noise < 0:
+		frustration.Abortf("--noise must be nonnegative but saw %v", *noise)
+	case *noise == 0:
+	case cmd != "" || shard != nil || *coord != "" || *nSamples > 0:
+		frustration.Abortf("--noise applies only to the default, unsharded, unsampled analysis")
+	case *classifier != "sign-parity":
+		frustration.Abortf("--noise requires --classifier=sign-parity")
+	case *exact:
+		frustration.Abortf("--noise cannot be combined with --exact-arithmetic")
+	case *noiseN <= 0:
+		frustration.Abortf("--noise-realizations must be positive but saw %d", *noiseN)
+	}
+	if *triangles {
+		switch {
+		case cmd != "" || shard != nil || *coord != "" || *nSamples > 0:
+			frustration.Abortf("--triangles applies only to the default, unsharded, unsampled analysis")
+		case *classifier != "sign-parity":
+			frustration.Abortf("--triangles requires --classifier=sign-parity")
+		case *baseFile != "" || *saveRes != "" || *matOut != "" || *dotOut != "" || *noise != 0:
+			frustration.Abortf("--triangles cannot be combined with options that require a cycle analysis")
+		}
+	}
+	cls := frustration.LookupClassifier(*classifier)
+	if *classifier != "sign-parity" && (*nSamples > 0 || *afRestarts >= 0) {
+		frustration.Abortf("--sample-cycles and --antiferromagnet support only the sign-parity classifier")
+	}
+	ropts.Rules = frustration.ClassRules{Vertex: frustration.ParseMembershipRule(*vRule), Edge: frustration.ParseMembershipRule(*eRule)}
+	if ropts.Rules.Weighted() && (cmd == "merge" || shard != nil || *coord != "") {
+		frustration.Abortf("The weighted membership rule is incompatible with --shard, --coordinator, and the \"merge\" subcommand")
+	}
+	var jsonOut bool
+	switch *outFmt {
+	case "text":
+	case "json":
+		if cmd != "" {
+			frustration.Abortf("--output-format=json applies only to the default analysis")
+		}
+		frustration.CheckJSONFlags()
+		jsonOut = true
+	default:
+		frustration.Abortf("Unrecognized output format %q", *outFmt)
+	}
+	if *gaugeInv {
+		if cmd != "" {
+			frustration.Abortf("--gauge-invariant applies only to the default analysis")
+		}
+		frustration.CheckGaugeFlags()
+	}
+	var clipRangeH, clipRangeJ *frustration.CoeffRange
+	if *clipH != "" {
+		r := frustration.ParseCoeffRange(*clipH)
+		clipRangeH = &r
+	}
+	if *clipJ != "" {
+		r := frustration.ParseCoeffRange(*clipJ)
+		clipRangeJ = &r
+	}
+	if *aggK < 0 {
+		frustration.Abortf("--aggregate-only must be nonnegative but saw %d", *aggK)
+	}
+	if *aggK > 0 {
+		if cmd != "" {
+			frustration.Abortf("--aggregate-only applies only to the default analysis")
+		}
+		frustration.CheckAggregateFlags()
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	frustration.SuppressWarnings(*suppress)
+	checkWarnings := func() {
+		if n := frustration.SevereWarnings(); *failWarn && n > 0 {
+			notify.Printf("Exiting with failure because of %d warning(s) and --fail-on-warning", n)
+			os.Exit(3)
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			panic(r) // Report the error, not the warnings.
+		}
+		checkWarnings()
+	}()
+
+	// Record or replay the run if requested.
+	var rec *sessionRecorder
+	if *record != "" {
+		checkRecordable(cmd)
+		rec = newSessionRecorder(*record)
+		defer func() {
+			r := recover()
+			rec.Close(r == nil)
+			if r != nil {
+				panic(r)
+			}
+		}()
+	}
+	if replayed != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				panic(r)
+			}
+			if n := replayed.Close(); n > 0 {
+				notify.Printf("The replay's output differs from that recorded in %s in %d line(s)", *replay, n)
+				os.Exit(4)
+			}
+			notify.Printf("The replay reproduced the output recorded in %s", *replay)
+		}()
+	}
+
+	// Open the output file.
+	var w io.Writer = os.Stdout
+	if outFile != "" {
+		f, err := os.Create(outFile)
+		frustration.CheckError(err)
+		defer f.Close()
+		w = f
+	}
+	if rec != nil {
+		w = rec.TeeOutput(w)
+	}
+	if replayed != nil {
+		w = replayed.TeeOutput(w)
+	}
+	if *outBuf != "" {
+		output = newStreamWriter(w, int(frustration.ParseSize(*outBuf)), *flushInt)
+		w = output
+		defer func() { frustration.CheckError(output.Close()) }()
+	}
+	if *threads < 0 {
+		frustration.Abortf("--threads must be nonnegative")
+	}
+	if *maxMem != "" {
+		frustration.StartMemoryWatchdog(frustration.ParseSize(*maxMem))
+	}
+
+	// Generate a problem instead of reading one if requested.
+	if cmd == "generate" {
+		if flag.NArg() > 0 {
+			notify.Fatal(`The "generate" subcommand does not accept an input file`)
+		}
+		prov := frustration.NewProvenance(cmd, "", "", *seed)
+		prov.InputFile = "" // Nothing is read.
+		wopts.Provenance = &prov
+		frustration.WriteGraph(*saveFmt, w, frustration.Generate(gopts, rand.New(rand.NewSource(*seed))), wopts)
+		return
+	}
+
+	// Merge the partial results of a sharded run if requested.
+	if cmd == "merge" {
+		sfs := make([]frustration.ShardFile, flag.NArg())
+		for i, fn := range flag.Args() {
+			f, err := os.Open(fn)
+			frustration.CheckError(err)
+			sfs[i] = frustration.ReadShardFile(f)
+			frustration.CheckError(f.Close())
+		}
+		frustration.OutputMergedShards(w, sfs, ropts.Rules)
+		return
+	}
+
+	// Analyze a directory of instances that sweep a parameter if requested.
+	if cmd == "sweep" {
+		if flag.NArg() != 1 {
+			notify.Fatal(`The "sweep" subcommand requires exactly one directory`)
+		}
+		re := frustration.ParseSweepPattern(*sweepPat)
+		frustration.OutputSweepCSV(w, frustration.SweepDirectory(flag.Arg(0), inFmt, re, cls, *allCycs, ropts.Rules, limits, *seed))
+		return
+	}
+
+	// Perform work assigned by a coordinator if requested.
+	if *workAddr != "" {
+		if flag.NArg() > 0 {
+			notify.Fatal("A worker does not accept an input file")
+		}
+		frustration.RunWorker(*workAddr)
+		return
+	}
+
+	// Run as a server if requested.
+	if cmd == "serve" {
+		srv := &frustration.Server{Weights: frustration.ParseScoreWeights(*scoreWts), Redact: *redact, Seed: *seed, Limits: limits, MaxInput: *maxJobBytes}
+		if *jobDir != "" {
+			srv.Jobs = frustration.NewJobQueue(*jobDir, *maxJobs, *maxJobBytes, *budget, *seed, limits, *jobTimeout, *jobRetention)
+		}
+		srv.Serve(*listen)
+		return
+	}
+
+	// Open the input file.
+	var r io.Reader
+	switch flag.NArg() {
+	case 0:
+		// Read from standard input.
+		r = os.Stdin
+	case 1:
+		// Read from the named file.
+		r, err = os.Open(flag.Arg(0))
+		frustration.CheckError(err)
+	default:
+		notify.Fatal("More than one input file was specified")
+	}
+	if rec != nil {
+		r = rec.TeeInput(r)
+	}
+
+	// Hash the input as we read it so the output can record where it came
+	// from.
+	hr := frustration.NewHashingReader(r)
+	prov := frustration.NewProvenance(cmd, flag.Arg(0), inFmt, *seed)
+	if replayed != nil {
+		prov = replayed.Provenance(prov)
+	}
+	if rec != nil {
+		rec.Prov = &prov
+	}
+	wopts.Provenance = &prov
+	rng := rand.New(rand.NewSource(*seed))
+
+	switch *vNames {
+	case "id", "metadata":
+	default:
+		frustration.Abortf("Unrecognized vertex naming %q", *vNames)
+	}
+
+	// Analyze each problem in a batch individually.
+	if inFmt == "bqpjson-batch" {
+		frustration.OutputBatchResults(w, hr, cls, *allCycs, *vNames == "metadata", ropts.Rules, prov, rng)
+		return
+	}
+
+	// Read the input file into a graph and begin the output with its
+	// provenance.
+	timer := frustration.NewPhaseTimer()
+	var g frustration.Graph
+	parser := frustration.LookupParser(inFmt)
+	if *exact {
+		if *preprocs != "" {
+			frustration.Abortf("--exact-arithmetic cannot be combined with --preprocessors, which compute in floating point")
+		}
+		parser = frustration.LookupExactParser(inFmt)
+	}
+	if cp, ok := parser.(frustration.ColoringParser); ok && *colors > 0 {
+		cp.Colors = *colors
+		parser = cp
+	}
+	if gp, ok := parser.(frustration.GraphMLParser); ok {
+		gp.Weight, gp.Field = *gmlWeight, *gmlField
+		parser = gp
+	}
+	if dp, ok := parser.(frustration.DelimitedParser); ok && *delim != "" {
+		dp.Delimiter = frustration.ParseDelimiter(*delim)
+		parser = dp
+	}
+	if sp, ok := parser.(frustration.SAPIParser); ok && *sapiH != "" {
+		f, err := os.Open(*sapiH)
+		frustration.CheckError(err)
+		defer f.Close()
+		sp.Fields = limits.Guard(inFmt, f)
+		parser = sp
+	}
+	timer.Time("parse", func() { g = limits.Parse(parser, inFmt, hr) })
+	prov.InputSHA256 = hr.Sum()
+	if *vNames == "metadata" {
+		g = g.WithVarNames()
+	}
+	switch {
+	case cmd == "cycles" && *cycFmt == "ndjson":
+		prov.WriteNDJSON(w)
+	case cmd == "cycles":
+		prov.WriteText(w, "#")
+	case shard != nil, *coord != "":
+		// The shard files will record the provenance.
+	case jsonOut:
+		// The JSON document will record the provenance.
+	default:
+		prov.WriteText(w, "#PROV")
+	}
+
+	// Output only structural statistics if requested.
+	if cmd == "stats" {
+		frustration.OutputGraphStats(w, g)
+		return
+	}
+	var q2v map[string]string
+	if *embFile != "" {
+		f, err := os.Open(*embFile)
+		frustration.CheckError(err)
+		q2v = frustration.ReadEmbedding(f)
+		g.EKind = g.EmbeddingEdgeKinds(q2v)
+		frustration.CheckError(f.Close())
+	}
+	switch ropts.CoeffView {
+	case frustration.ViewIsing:
+	case frustration.ViewQUBO:
+		if g.QEs == nil {
+			frustration.Abortf("--coeff-view=%s requires QUBO input (qubo format or Boolean bqpjson)", frustration.ViewQUBO)
+		}
+		if *preprocs != "" {
+			frustration.Abortf("--coeff-view=%s cannot be combined with --preprocessors, which alter the coefficients", frustration.ViewQUBO)
+		}
+	default:
+		frustration.Abortf("Unrecognized coefficient view %q", ropts.CoeffView)
+	}
+	switch ropts.Cycles {
+	case frustration.CyclesAll, frustration.CyclesFrustrated, frustration.CyclesNone:
+	default:
+		frustration.Abortf("Unrecognized cycle filter %q", ropts.Cycles)
+	}
+	switch ropts.Histogram {
+	case "", frustration.HistUnicode, frustration.HistASCII:
+	default:
+		frustration.Abortf("Unrecognized histogram style %q", ropts.Histogram)
+	}
+	if ropts.Reservoir < 0 {
+		frustration.Abortf("--cycle-reservoir must be nonnegative")
+	}
+
+	// Clip the coefficients to the hardware's ranges if requested.  The
+	// unclipped graph is retained for comparison.
+	orig := g
+	if *clipH != "" || *clipJ != "" {
+		switch {
+		case *exact:
+			frustration.Abortf("--clip-h and --clip-j cannot be combined with --exact-arithmetic")
+		case *preprocs != "":
+			frustration.Abortf("--clip-h and --clip-j cannot be combined with --preprocessors, which alter the coefficients")
+		case ropts.CoeffView == frustration.ViewQUBO:
+			frustration.Abortf("--clip-h and --clip-j cannot be combined with --coeff-view=%s", frustration.ViewQUBO)
+		}
+		g = g.Clipped(clipRangeH, clipRangeJ)
+	}
+	if *saveGraph != "" {
+		f, err := os.Create(*saveGraph)
+		frustration.CheckError(err)
+		frustration.WriteGraph(*saveFmt, f, g, wopts)
+		frustration.CheckError(f.Close())
+	}
+
+	// Compare solvers, compute a score, predict hardness, or audit
+	// solutions if requested.
+	switch cmd {
+	case "audit":
+		if *solFile == "" {
+			notify.Fatal(`The "audit" subcommand requires --solutions`)
+		}
+		f, err := os.Open(*solFile)
+		frustration.CheckError(err)
+		sols := frustration.ReadSolutions(*solFmt, f)
+		frustration.CheckError(f.Close())
+		frustration.OutputAudit(w, g, sols, cls, rng)
+		return
+	case "compare-solvers":
+		frustration.OutputSolverComparison(w, g, *sweeps, *preproc, *fixSpin, rng)
+		return
+	case "score":
+		fmt.Fprint(w, frustration.ComputeScore(g, frustration.ParseScoreWeights(*scoreWts), cls, rng))
+		return
+	case "hardness":
+		fmt.Fprint(w, frustration.ComputeHardness(g, cls, rng))
+		return
+	case "embedding-score":
+		switch {
+		case *embFile == "" || *logFile == "":
+			frustration.Abortf(`The "embedding-score" subcommand requires --embedding and --logical`)
+		case *classifier != "sign-parity":
+			frustration.Abortf(`The "embedding-score" subcommand presumes --classifier=sign-parity`)
+		}
+		if *logFmt == "" {
+			*logFmt = inFmt
+		}
+		f, err := os.Open(*logFile)
+		frustration.CheckError(err)
+		lg := frustration.ReadGraph(*logFmt, f, limits)
+		frustration.CheckError(f.Close())
+		chains := make(map[string]frustration.Empty)
+		for _, v := range q2v {
+			chains[v] = frustration.Empty{}
+		}
+		frustration.OutputEmbeddingQuality(w, frustration.ComputeEmbeddingQuality(lg, g, q2v, rng), len(chains))
+		return
+	}
+
+	// Screen for vertex-level conflicts, which the cycle analysis cannot
+	// see and which may exist even in a balanced graph.
+	if *fldConf && shard == nil && *coord == "" {
+		frustration.OutputFieldConflicts(w, g)
+	}
+
+	// Warn about couplers whose sign is an artifact of rounding error.
+	if cmd == "" && shard == nil && *coord == "" {
+		var n int
+		if *aggK > 0 || jsonOut {
+			n = len(g.CancelledCouplers(*cancelTol))
+		} else {
+			n = frustration.OutputCancellations(w, g, *cancelTol)
+		}
+		if n > 0 {
+			frustration.Warn("W002-cancelled-couplers", "", "%d coupler(s) are sums of terms that nearly cancel; their signs, and the frustration of cycles through them, are numerically meaningless", n)
+		}
+	}
+
+	// If the graph is a pure antiferromagnet or is balanced, report that
+	// and skip the heavyweight analysis.  Both tests presume the default
+	// definition of frustration and report individual vertices as text.
+	balCheckOK := shard == nil && *coord == "" && *baseFile == "" && *saveRes == "" && *matOut == "" && *dotOut == "" && *ppOut == "" && *noise == 0 && *classifier == "sign-parity" && *aggK == 0 && !jsonOut && !*gaugeInv
+	if cmd == "" && balCheckOK && *afRestarts >= 0 {
+		if frustration.OutputAntiferromagnet(w, g, *afRestarts, rng) {
+			frustration.OutputWarnings(w)
+			return
+		}
+		notify.Print("The graph is not a pure antiferromagnet; performing the full analysis")
+	}
+	if cmd == "" && balCheckOK && *balCheck && frustration.OutputIfBalanced(w, g) {
+		frustration.OutputWarnings(w)
+		return
+	}
+
+	// Estimate frustration from a sample of cycles if requested.
+	if *nSamples > 0 && *coord != "" {
+		order := frustration.WorkOrder{Provenance: prov, Graph: g, Samples: *nSamples, Weighting: *sampleWt}
+		frustration.OutputMergedShards(w, frustration.Coordinate(*coord, *nWorkers, order), ropts.Rules)
+		return
+	}
+	if *nSamples > 0 && shard != nil {
+		n, srng := shard.SampleShare(*nSamples, *seed)
+		t := g.TallySamples(n, *sampleWt, srng)
+		frustration.ShardFile{Shard: *shard, Provenance: prov, Sample: &t}.Write(w)
+		return
+	}
+	if *nSamples > 0 {
+		frustration.OutputCycleSample(w, g, *nSamples, *sampleWt, rng)
+		return
+	}
+
+	// Count frustrated triangles instead of finding cycles if requested.
+	if *triangles {
+		frustration.OutputTriangles(w, g)
+		frustration.OutputWarnings(w)
+		return
+	}
+	if cmd == "" {
+		g.WarnIfDense()
+	}
+
+	// Assemble the analysis pipeline.  Unless a registered cycle finder
+	// was requested, find base cycles and from those, if requested,
+	// elementary cycles.  If specific edges were requested, search for
+	// cycles through those edges instead.
+	pl := frustration.Pipeline{
+		Preprocessors: frustration.LookupPreprocessors(*preprocs),
+		Classifier:    cls,
+		Threads:       *threads,
+	}
+	switch {
+	case *finder != "":
+		pl.CycleFinder = frustration.LookupCycleFinder(*finder)
+	case len(through) > 0:
+		pl.CycleFinder = frustration.ThroughEdgeFinder{Edges: through, All: *allCycs}
+	case *allCycs:
+		pl.CycleFinder = frustration.ElementaryFinder{Budget: *budget, Force: *force, Rule: combine, Minimum: *minBasis, MaxLen: *maxCycLen}
+	default:
+		pl.CycleFinder = frustration.BasisFinder{Minimum: *minBasis}
+	}
+	pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+		switch {
+		case len(through) > 0 && *finder == "":
+			fmt.Fprintf(w, "#TCS %d\n", len(a.Cycles))
+		case a.BaseCycles != nil && *allCycs:
+			fmt.Fprintf(w, "#BCS %d\n", len(a.BaseCycles))
+			fmt.Fprintf(w, "#ECS %d\n", len(a.Cycles))
+			fmt.Fprintf(w, "#DUP %d\n", a.NumDup)
+		case a.BaseCycles != nil:
+			fmt.Fprintf(w, "#BCS %d\n", len(a.BaseCycles))
+		}
+	}))
+	if *basisStats {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputBasisStats(w, a.BaseCycles)
+		}))
+	}
+	if combine.Restricted() && !*allCycs {
+		frustration.Abortf("--shrinking-only and --combine-max-len require --all-cycles")
+	}
+	switch {
+	case *maxCycLen == 0:
+	case *maxCycLen < 3:
+		frustration.Abortf("--max-cycle-length must be at least 3 but saw %d", *maxCycLen)
+	case !*allCycs:
+		frustration.Abortf("--max-cycle-length requires --all-cycles")
+	case combine.Restricted():
+		frustration.Abortf("--max-cycle-length cannot be combined with --shrinking-only or --combine-max-len")
+	case len(through) > 0 || *finder != "":
+		frustration.Abortf("--max-cycle-length cannot be combined with --through-edge or --cycle-finder")
+	}
+	if *exclTriv && *trivRatio <= 0 {
+		frustration.Abortf("--exclude-trivial requires a positive --trivial-ratio")
+	}
+	if *trivRatio > 0 {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(frustration.OutputTrivial))
+	}
+	pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+		if *aggK > 0 {
+			frustration.OutputAggregate(w, a.Graph, a.Paths, a.Frustrated, ropts.Rules, *byPrefix, *aggK)
+			return
+		}
+		frustration.OutputResults(w, a.Graph, a.Paths, a.Frustrated, ropts, a.Rng)
+	}))
+	if *byMacro {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputMacroBreakdown(w, a.Graph, a.Paths, a.Frustrated)
+		}))
+	}
+	if *byKind {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputEdgeKindBreakdown(w, a.Graph, a.Paths, a.Frustrated)
+		}))
+	}
+	if *byGadget {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputGadgetBreakdown(w, a.Graph, a.Paths, a.Frustrated)
+		}))
+	}
+	if *byPrefix != "" && *aggK == 0 {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputPrefixBreakdown(w, a.Graph, a.Paths, a.Frustrated, *byPrefix)
+		}))
+	}
+	if *clipH != "" || *clipJ != "" {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputClipChanges(w, orig, a.Graph, a.Paths, a.Frustrated, cls)
+		}))
+	}
+	if *subFile != "" {
+		f, err := os.Open(*subFile)
+		frustration.CheckError(err)
+		sub := frustration.ReadSubset(f)
+		frustration.CheckError(f.Close())
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputSubsetBreakdown(w, a.Graph, a.Paths, a.Frustrated, sub)
+		}))
+	}
+	if *softBetas != "" {
+		betas := frustration.ParseBetas(*softBetas)
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputSoftFrustration(w, a.Graph, a.Cycles, a.Frustrated, betas)
+		}))
+	}
+	if *noise > 0 {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputNoiseRobustness(w, a.Graph, a.Cycles, a.Paths, a.Frustrated, *noise, *noiseN, a.Rng)
+		}))
+	}
+	if *fiMode == "heuristic" && *restarts < 0 {
+		*restarts = frustration.DefaultRestarts
+	}
+	if *restarts >= 0 {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputSwitching(w, a.Graph, *restarts, *gaugeInv, a.Rng)
+		}))
+	}
+	if *gaugeInv {
+		n := *restarts
+		if n < 0 {
+			n = 0
+		}
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputGaugeInvariants(w, a.Graph, a.Paths, a.Frustrated, cls, n, a.Rng)
+		}))
+	}
+	switch *fiMode {
+	case "", "heuristic":
+	case "exact":
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputExactFrustrationIndex(w, a.Graph, *gaugeInv, a.Rng)
+		}))
+	default:
+		frustration.Abortf("Unrecognized frustration-index mode %q", *fiMode)
+	}
+	if *cutRestarts >= 0 {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputFrustratedCut(w, a.Graph, a.Paths, a.Frustrated, ropts.Rules.Edge, *cutRestarts, a.Rng)
+		}))
+	}
+	if *remediate {
+		kinds := frustration.ParseEditKinds(*remEdits)
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputRemediation(w, a.Graph, a.Paths, a.Frustrated, kinds)
+		}))
+	}
+	if *sbm {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputSBM(w, a.Graph, a.Rng)
+		}))
+	}
+	if *forest {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputSpanningForest(w, a.Graph, ropts.CoeffView, cls)
+		}))
+	}
+	if *anoms {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputAnomalies(w, a.Graph, a.Paths, a.Frustrated, *anomFactor, ropts.CoeffView)
+		}))
+	}
+	if *fcore {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputFrustrationCore(w, a.Graph)
+		}))
+	}
+	if *fprint {
+		pl.Reporters = append(pl.Reporters, frustration.ReporterFunc(func(w io.Writer, a *frustration.Analysis) {
+			frustration.OutputFingerprint(w, a.Graph)
+		}))
+	}
+	pl.Reporters = append(pl.Reporters, frustration.LookupReporters(*extraReps)...)
+
+	// Prepare to save the results, compare them against a baseline, or
+	// export matrices once the analysis is complete.
+	tols := frustration.ParseTolerances(*baseTols)
+	var baseline *frustration.Summary
+	if *baseFile != "" {
+		f, err := os.Open(*baseFile)
+		frustration.CheckError(err)
+		b := frustration.ReadBaseline(f)
+		frustration.CheckError(f.Close())
+		baseline = &b
+	}
+	finishResults := func(a *frustration.Analysis) {
+		if !jsonOut && *aggK == 0 {
+			frustration.OutputWarnings(w)
+		}
+		if *matOut != "" {
+			frustration.WriteMatrices(*matOut, a.Graph, a.Paths, &prov)
+		}
+		if *dotOut != "" {
+			f, err := os.Create(*dotOut)
+			frustration.CheckError(err)
+			frustration.WriteFrustrationDOT(f, a.Graph, a.Paths, a.Frustrated, ropts.Rules, wopts)
+			frustration.CheckError(f.Close())
+		}
+		if *saveRes == "" && baseline == nil && !jsonOut {
+			return
+		}
+		res := a.Graph.TallyResults(len(a.BaseCycles), a.Paths, a.Frustrated, *allCycs, ropts.Rules)
+		if jsonOut {
+			frustration.WriteResults(w, res, prov)
+		}
+		if *saveRes != "" {
+			f, err := os.Create(*saveRes)
+			frustration.CheckError(err)
+			frustration.WriteResults(f, res, prov)
+			frustration.CheckError(f.Close())
+		}
+		if baseline != nil && frustration.OutputBaselineComparison(w, *baseline, res.Summary, tols) > 0 {
+			frustration.Abortf("Frustration increased beyond the tolerances of baseline %s", *baseFile)
+		}
+	}
+
+	// Run the pipeline.
+	a := &frustration.Analysis{Graph: g, Rng: rng, Timer: timer}
+	pl.Preprocess(a)
+	if *ppOut != "" {
+		f, err := os.Create(*ppOut)
+		frustration.CheckError(err)
+		frustration.WriteGraph(*ppFmt, f, a.Graph, wopts)
+		frustration.CheckError(f.Close())
+	}
+	if *coord != "" {
+		// Distribute the cycles among workers and merge their tallies.
+		order := frustration.WorkOrder{
+			Provenance:     prov,
+			Graph:          a.Graph,
+			AllCycles:      *allCycs,
+			MinimumBasis:   *minBasis,
+			Budget:         *budget,
+			Force:          *force,
+			Combine:        combine,
+			MaxCycleLen:    *maxCycLen,
+			Classifier:     *classifier,
+			TrivialRatio:   *trivRatio,
+			ExcludeTrivial: *exclTriv,
+		}
+		frustration.OutputMergedShards(w, frustration.Coordinate(*coord, *nWorkers, order), ropts.Rules)
+		return
+	}
+	if shard != nil && cmd == "" {
+		// Tally only this shard's cycles and leave reporting to "merge".
+		res := pl.TallyShard(a, *shard, *allCycs, *trivRatio, *exclTriv)
+		frustration.ShardFile{Shard: *shard, Provenance: prov, Results: &res}.Write(w)
+		return
+	}
+	pl.FindCycles(a)
+	if len(a.Cycles) == 0 {
+		if len(through) > 0 && *finder == "" {
+			notify.Printf("No cycles pass through %s; it cannot be frustrated", through.String())
+		} else {
+			notify.Print("Graph is acyclic; no frustration can exist")
+		}
+		finishResults(a)
+		return
+	}
+	if cmd == "cycles" {
+		// Output only the cycles themselves.
+		if shard != nil {
+			a.Cycles = shard.FilterCycles(a.Cycles)
+		}
+		frustration.OutputCycleList(w, a.Graph, a.Cycles, *cycFmt)
+		return
+	}
+	pl.Classify(a)
+	if *trivRatio > 0 {
+		a.Timer.Time("classify", func() { a.FindTrivial(*trivRatio, *exclTriv) })
+	}
+
+	// Tell the user what we discovered.
+	if !jsonOut {
+		pl.Report(w, a)
+	}
+	if *timing {
+		timer.Output(w)
+	}
+	if *maxMem != "" && !jsonOut {
+		peak, limit := frustration.MemoryPeak()
+		fmt.Fprintf(w, "#MEM %d / %d = %f\n", peak, limit, float64(peak)/float64(limit))
+	}
+	finishResults(a)
+}
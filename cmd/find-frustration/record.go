@@ -0,0 +1,378 @@
+/* This file archives a run of find-frustration (its exact input, flags, seed,
+version, and output) in a single zip file and replays such a record, checking
+that the same analysis of the same input still produces the same output. */
+
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lanl/find-frustration/frustration"
+)
+
+// recordedFileFlags lists the flags that name auxiliary input files, which a
+// record archives along with the input itself.
+var recordedFileFlags = []string{"assert-baseline", "embedding", "logical", "sapi-h", "solutions", "subset"}
+
+// unreplayedFlags is the set of flags that a replay does not restore: those
+// that record or replay a run and those that name files to which the run
+// wrote output other than its main output, which a replay must not
+// overwrite.
+var unreplayedFlags = map[string]bool{
+	"frustration-dot":  true,
+	"matrices-out":     true,
+	"o":                true,
+	"output":           true,
+	"preprocessed-out": true,
+	"record":           true,
+	"replay":           true,
+	"save-graph":       true,
+	"save-results":     true,
+}
+
+// unrecordedCommands is the set of subcommands that cannot be recorded
+// because they do not analyze a single input.
+var unrecordedCommands = map[string]bool{
+	"generate": true,
+	"merge":    true,
+	"serve":    true,
+	"sweep":    true,
+}
+
+// networkFlags is the set of flags that cannot be recorded because they make
+// a run listen on or connect to the network.  A record that nevertheless
+// contains one has been edited by hand, so a replay refuses it.
+var networkFlags = map[string]bool{
+	"coordinator": true,
+	"listen":      true,
+	"worker":      true,
+	"workers":     true,
+}
+
+// checkRecordable aborts if the current run, with a given subcommand, cannot
+// be recorded.
+func checkRecordable(cmd string) {
+	if unrecordedCommands[cmd] {
+		frustration.Abortf("--record applies only to runs that analyze a single input")
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if networkFlags[f.Name] {
+			frustration.Abortf("--record cannot be combined with --%s, which uses the network", f.Name)
+		}
+	})
+}
+
+// volatileTags lists the tags of output lines that legitimately differ from
+// run to run and are therefore ignored when comparing a replay's output with
+// the recorded output.
+var volatileTags = []string{"#TIME ", "#MEM "}
+
+// These are the names of the members of a record.
+const (
+	recProvenance = "provenance.json" // Provenance of the recorded run
+	recInput      = "input"           // Exact input bytes
+	recOutput     = "output"          // Exact output bytes
+	recFiles      = "files/"          // Prefix of each auxiliary input file, named by its flag
+)
+
+// A sessionRecorder spools a run's input and output to temporary files and
+// archives them, with the run's provenance, when the run completes.
+type sessionRecorder struct {
+	Name   string                  // Name of the record file
+	Dir    string                  // Temporary directory holding the spooled input and output
+	Input  *os.File                // Spooled input
+	Output *os.File                // Spooled output
+	Prov   *frustration.Provenance // Provenance of the run (nil until known)
+}
+
+// newSessionRecorder prepares to record a run to a given file.
+func newSessionRecorder(name string) *sessionRecorder {
+	dir, err := ioutil.TempDir("", "find-frustration-record")
+	frustration.CheckError(err)
+	sr := &sessionRecorder{Name: name, Dir: dir}
+	sr.Input, err = os.Create(filepath.Join(dir, recInput))
+	frustration.CheckError(err)
+	sr.Output, err = os.Create(filepath.Join(dir, recOutput))
+	frustration.CheckError(err)
+	return sr
+}
+
+// TeeInput returns a reader that spools to the record everything read from r.
+func (sr *sessionRecorder) TeeInput(r io.Reader) io.Reader {
+	return io.TeeReader(r, sr.Input)
+}
+
+// TeeOutput returns a writer that spools to the record everything written to
+// w.
+func (sr *sessionRecorder) TeeOutput(w io.Writer) io.Writer {
+	return io.MultiWriter(w, sr.Output)
+}
+
+// addFile copies a file into a zip archive as a member with a given name.
+func addFile(zw *zip.Writer, member, fn string) {
+	f, err := os.Open(fn)
+	frustration.CheckError(err)
+	defer f.Close()
+	mw, err := zw.Create(member)
+	frustration.CheckError(err)
+	_, err = io.Copy(mw, f)
+	frustration.CheckError(err)
+}
+
+// Close writes the record if the run completed and discards the spooled
+// input and output.
+func (sr *sessionRecorder) Close(completed bool) {
+	defer os.RemoveAll(sr.Dir)
+	frustration.CheckError(sr.Input.Close())
+	frustration.CheckError(sr.Output.Close())
+	if !completed || sr.Prov == nil {
+		return
+	}
+
+	// Hash the input as spooled, which is complete even if the analysis
+	// did not consume all of it.
+	prov := *sr.Prov
+	f, err := os.Open(sr.Input.Name())
+	frustration.CheckError(err)
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	f.Close()
+	frustration.CheckError(err)
+	prov.InputSHA256 = hex.EncodeToString(h.Sum(nil))
+
+	// Archive the provenance, the input, the output, and every auxiliary
+	// input file.
+	rf, err := os.Create(sr.Name)
+	frustration.CheckError(err)
+	defer rf.Close()
+	zw := zip.NewWriter(rf)
+	mw, err := zw.Create(recProvenance)
+	frustration.CheckError(err)
+	pj, err := json.MarshalIndent(prov, "", "  ")
+	frustration.CheckError(err)
+	_, err = mw.Write(append(pj, '\n'))
+	frustration.CheckError(err)
+	addFile(zw, recInput, sr.Input.Name())
+	addFile(zw, recOutput, sr.Output.Name())
+	for _, name := range recordedFileFlags {
+		if fn, ok := prov.Flags[name]; ok && fn != "" {
+			addFile(zw, recFiles+name, fn)
+		}
+	}
+	frustration.CheckError(zw.Close())
+}
+
+// A sessionReplay is a recorded run that is being replayed.
+type sessionReplay struct {
+	Name   string                 // Name of the record file
+	Dir    string                 // Temporary directory holding the extracted files ("" until extracted)
+	Prov   frustration.Provenance // Provenance of the recorded run
+	Files  map[string][]byte      // Contents of the input and each auxiliary input file, by member name
+	Output []byte                 // Output of the recorded run
+	Got    bytes.Buffer           // Output of the replay
+}
+
+// openReplay reads a record and verifies that it is complete and that its
+// input matches the recorded hash.
+func openReplay(name string) *sessionReplay {
+	zr, err := zip.OpenReader(name)
+	frustration.CheckError(err)
+	defer zr.Close()
+	sp := &sessionReplay{Name: name, Files: make(map[string][]byte)}
+	found := make(map[string]bool)
+	for _, zf := range zr.File {
+		rc, err := zf.Open()
+		frustration.CheckError(err)
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		frustration.CheckError(err)
+		found[zf.Name] = true
+		switch {
+		case zf.Name == recProvenance:
+			if err := json.Unmarshal(data, &sp.Prov); err != nil {
+				frustration.Abortf("Failed to parse %s in %s (%v)", recProvenance, name, err)
+			}
+		case zf.Name == recOutput:
+			sp.Output = data
+		case zf.Name == recInput, strings.HasPrefix(zf.Name, recFiles):
+			sp.Files[zf.Name] = data
+		}
+	}
+	for _, m := range []string{recProvenance, recInput, recOutput} {
+		if !found[m] {
+			frustration.Abortf("%s is not a find-frustration record: it lacks a member named %s", name, m)
+		}
+	}
+	if sum := sha256.Sum256(sp.Files[recInput]); hex.EncodeToString(sum[:]) != sp.Prov.InputSHA256 {
+		frustration.Abortf("The input archived in %s does not match its recorded SHA-256 hash", name)
+	}
+	return sp
+}
+
+// extract writes the input and auxiliary input files to a temporary
+// directory and returns the name of the file to which a given member was
+// written.
+func (sp *sessionReplay) extract() func(member string) string {
+	dir, err := ioutil.TempDir("", "find-frustration-replay")
+	frustration.CheckError(err)
+	sp.Dir = dir
+	path := func(member string) string {
+		return filepath.Join(dir, strings.ReplaceAll(member, "/", "_"))
+	}
+	for m, data := range sp.Files {
+		frustration.CheckError(ioutil.WriteFile(path(m), data, 0600))
+	}
+	return path
+}
+
+// Command returns the recorded run's subcommand ("" if none).
+func (sp *sessionReplay) Command() string {
+	if sp.Prov.Command == "analyze" {
+		return ""
+	}
+	return sp.Prov.Command
+}
+
+// RestoreFlags sets the command-line flags and input file to those of the
+// recorded run, with auxiliary input files replaced by their archived copies.
+// Only --replay and --output may accompany --replay.  A record of a run that
+// --record would have refused, which can only have been edited by hand, is
+// rejected.
+func (sp *sessionReplay) RestoreFlags() {
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "replay" && f.Name != "output" && f.Name != "o" {
+			frustration.Abortf("--replay cannot be combined with --%s; the record determines every option", f.Name)
+		}
+	})
+	if flag.NArg() > 0 {
+		frustration.Abortf("--replay does not accept an input file; the record contains the input")
+	}
+	if unrecordedCommands[sp.Command()] {
+		frustration.Abortf("%s records the %s subcommand, which cannot be replayed", sp.Name, sp.Command())
+	}
+	names := make([]string, 0, len(sp.Prov.Flags))
+	for n := range sp.Prov.Flags {
+		if !unreplayedFlags[n] && n != "seed" {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if networkFlags[n] {
+			frustration.Abortf("%s was recorded with --%s, which cannot be replayed because it uses the network", sp.Name, n)
+		}
+		if flag.Lookup(n) == nil {
+			frustration.Abortf("%s was recorded with --%s, which this version does not support", sp.Name, n)
+		}
+	}
+	path := sp.extract()
+	var args []string
+	for _, n := range names {
+		f := flag.Lookup(n)
+		val := sp.Prov.Flags[n]
+		if val != "" && contains(recordedFileFlags, n) {
+			val = path(recFiles + n)
+		}
+		if _, ok := f.Value.(*edgeList); ok {
+			// A repeatable flag's value lists every repetition.
+			for _, e := range strings.Fields(val) {
+				args = append(args, "--"+n+"="+e)
+			}
+			continue
+		}
+		args = append(args, "--"+n+"="+val)
+	}
+	args = append(args, fmt.Sprintf("--seed=%d", sp.Prov.Seed), path(recInput))
+	frustration.CheckError(flag.CommandLine.Parse(args))
+}
+
+// contains says whether a list of strings contains a given string.
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Provenance returns the provenance of the recorded run, updated with the
+// version of find-frustration performing the replay, so that the replay's
+// output can be compared with the recorded output.
+func (sp *sessionReplay) Provenance(cur frustration.Provenance) frustration.Provenance {
+	if cur.Version != sp.Prov.Version {
+		notify.Printf("%s was recorded by find-frustration version %s, but this is version %s; the output may differ", sp.Name, sp.Prov.Version, cur.Version)
+	}
+	prov := sp.Prov
+	prov.Version = cur.Version
+	return prov
+}
+
+// TeeOutput returns a writer that also captures everything written to w for
+// comparison with the recorded output.
+func (sp *sessionReplay) TeeOutput(w io.Writer) io.Writer {
+	return io.MultiWriter(w, &sp.Got)
+}
+
+// sortedLines splits output into lines, omits those with a volatile tag, and
+// sorts the rest.
+func sortedLines(out []byte) []string {
+	var lines []string
+	scan := bufio.NewScanner(bytes.NewReader(out))
+	scan.Buffer(nil, len(out)+1)
+	for scan.Scan() {
+		ln := scan.Text()
+		volatile := false
+		for _, t := range volatileTags {
+			volatile = volatile || strings.HasPrefix(ln, t)
+		}
+		if !volatile {
+			lines = append(lines, ln)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// Discard removes the extracted files, if any.
+func (sp *sessionReplay) Discard() {
+	if sp.Dir != "" {
+		os.RemoveAll(sp.Dir)
+		sp.Dir = ""
+	}
+}
+
+// Close compares the replay's output with the recorded output, up to the
+// order of lines, discards the extracted files, and returns the number of
+// lines that appear in only one of the two.
+func (sp *sessionReplay) Close() int {
+	sp.Discard()
+	want, got := sortedLines(sp.Output), sortedLines(sp.Got.Bytes())
+	ndiff := 0
+	for len(want) > 0 || len(got) > 0 {
+		switch {
+		case len(got) == 0 || (len(want) > 0 && want[0] < got[0]):
+			want = want[1:]
+			ndiff++
+		case len(want) == 0 || got[0] < want[0]:
+			got = got[1:]
+			ndiff++
+		default:
+			want, got = want[1:], got[1:]
+		}
+	}
+	return ndiff
+}
@@ -0,0 +1,156 @@
+/* This file decouples the analysis from a slow output sink, such as a pipe
+or a file on a network filesystem.  Output is handed to a goroutine that
+writes it to the sink in chunks while the analysis proceeds.  The amount of
+pending output is bounded, so an analysis that outpaces its sink waits for it
+rather than accumulating an unbounded backlog in memory. */
+
+package main
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// streamChunks is the number of chunks into which a streamWriter divides its
+// buffer: one being filled, one being written, and the rest pending.
+const streamChunks = 4
+
+// errStreamClosed is returned by a write to a closed streamWriter.
+var errStreamClosed = errors.New("write to closed output stream")
+
+// A streamWriter is an io.Writer that writes to an underlying sink in the
+// background.
+type streamWriter struct {
+	sink    io.Writer     // Underlying writer
+	chunk   int           // Size of each chunk in bytes
+	mu      sync.Mutex    // Guards buf and closed
+	buf     []byte        // Chunk being filled
+	closed  bool          // true once Close has been called
+	pending chan []byte   // Chunks awaiting the sink
+	free    chan []byte   // Chunks available for reuse
+	stop    chan struct{} // Closed to stop periodic flushing
+	done    chan struct{} // Closed once every chunk has been written
+	err     error         // First error returned by the sink
+	once    sync.Once     // Ensures Close takes effect once
+}
+
+// output is the program's output stream if --output-buffer is specified so
+// that it can be flushed on any path by which the program exits.
+var output *streamWriter
+
+// newStreamWriter returns a streamWriter that buffers at most size bytes of
+// output for a sink.  If interval is positive, buffered output is also
+// flushed at that interval so that a reader of the sink sees steady
+// progress.
+func newStreamWriter(sink io.Writer, size int, interval time.Duration) *streamWriter {
+	chunk := size / streamChunks
+	if chunk < 1 {
+		chunk = 1
+	}
+	sw := &streamWriter{
+		sink:    sink,
+		chunk:   chunk,
+		buf:     make([]byte, 0, chunk),
+		pending: make(chan []byte, streamChunks-2),
+		free:    make(chan []byte, streamChunks),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go sw.drain()
+	if interval > 0 {
+		go sw.tick(interval)
+	}
+	return sw
+}
+
+// drain writes each pending chunk to the sink.  After the sink fails, later
+// chunks are discarded so that writers never wait on a broken sink.
+func (sw *streamWriter) drain() {
+	defer close(sw.done)
+	for b := range sw.pending {
+		if sw.err == nil {
+			_, sw.err = sw.sink.Write(b)
+		}
+		select {
+		case sw.free <- b[:0]:
+		default:
+		}
+	}
+}
+
+// tick periodically flushes buffered output until the stream is closed.
+func (sw *streamWriter) tick(d time.Duration) {
+	t := time.NewTicker(d)
+	defer t.Stop()
+	for {
+		select {
+		case <-sw.stop:
+			return
+		case <-t.C:
+			sw.mu.Lock()
+			if !sw.closed {
+				sw.flushLocked()
+			}
+			sw.mu.Unlock()
+		}
+	}
+}
+
+// flushLocked hands the chunk being filled to the sink, waiting if the sink
+// has fallen behind.  The caller must hold sw.mu.
+func (sw *streamWriter) flushLocked() {
+	if len(sw.buf) == 0 {
+		return
+	}
+	sw.pending <- sw.buf
+	select {
+	case sw.buf = <-sw.free:
+	default:
+		sw.buf = make([]byte, 0, sw.chunk)
+	}
+}
+
+// Write buffers data for the sink.  It blocks only while the buffer is full.
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.closed {
+		return 0, errStreamClosed
+	}
+	n := len(p)
+	for len(p) > 0 {
+		k := copy(sw.buf[len(sw.buf):cap(sw.buf)], p)
+		sw.buf = sw.buf[:len(sw.buf)+k]
+		p = p[k:]
+		if len(sw.buf) == cap(sw.buf) {
+			sw.flushLocked()
+		}
+	}
+	return n, nil
+}
+
+// Close writes all buffered output to the sink, waits for the sink to accept
+// it, and returns the first error the sink returned.  Only the first call has
+// any effect.
+func (sw *streamWriter) Close() error {
+	sw.once.Do(func() {
+		close(sw.stop)
+		sw.mu.Lock()
+		sw.flushLocked()
+		sw.closed = true
+		close(sw.pending)
+		sw.mu.Unlock()
+		<-sw.done
+	})
+	return sw.err
+}
+
+// flushOutput writes any buffered program output before the program exits.
+// Write errors are ignored because the program is exiting anyway.
+func flushOutput() {
+	if output != nil {
+		output.Close()
+	}
+}
@@ -0,0 +1,181 @@
+/* This file produces reports that can be shared outside the organization
+that owns a problem.  Such a report contains only aggregate statistics, and
+it names a family of variables only if the family is large enough that its
+statistics do not single out any one variable. */
+
+package frustration
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// aggregateFlags is the set of command-line flags that can accompany
+// --aggregate-only because they cannot cause individual vertices, edges, or
+// cycles to be reported.
+var aggregateFlags = map[string]bool{
+	"aggregate-only":   true,
+	"all-cycles":       true,
+	"balance-check":    true,
+	"by-prefix":        true,
+	"cancel-tolerance": true,
+	"classifier":       true,
+	"colors":           true,
+	"combine-max-len":  true,
+	"cycle-budget":     true,
+	"cycle-finder":     true,
+	"delimiter":        true,
+	"edge-rule":        true,
+	"fail-on-warning":  true,
+	"exact-arithmetic": true,
+	"f":                true,
+	"flush-interval":   true,
+	"force":            true,
+	"format":           true,
+	"frustration-def":  true,
+	"graphml-field":    true,
+	"graphml-weight":   true,
+	"max-line-bytes":   true,
+	"max-memory":       true,
+	"max-name-bytes":   true,
+	"o":                true,
+	"output":           true,
+	"output-buffer":    true,
+	"preprocessors":    true,
+	"record":           true,
+	"replay":           true,
+	"sapi-h":           true,
+	"seed":             true,
+	"shrinking-only":   true,
+	"suppress":         true,
+	"threads":          true,
+	"timing":           true,
+	"vertex-names":     true,
+	"vertex-rule":      true,
+}
+
+// CheckAggregateFlags aborts if any flag specified on the command line could
+// cause individual vertices, edges, or cycles to be reported.
+func CheckAggregateFlags() {
+	flag.Visit(func(f *flag.Flag) {
+		if !aggregateFlags[f.Name] {
+			Abortf("--aggregate-only cannot be combined with --%s, which may report individual variables", f.Name)
+		}
+	})
+}
+
+// OutputAggregate outputs only aggregate frustration statistics: the numbers
+// of frustrated vertices, edges, and cycles, deemed frustrated according to
+// the given rules, and the same statistics as OutputPrefixBreakdown for each
+// family of vertices (as determined by delims) with at least minGroup
+// members.  The vertices of smaller families are pooled into a single group
+// named "<other>", whose members are not identified.  It also reports how
+// many families were named.
+func OutputAggregate(w io.Writer, g Graph, ps [][]string, isFrust []bool, rules ClassRules, delims string, minGroup int) {
+	// Tally vertices and edges.
+	vts, ets := g.tallyMembers(ps, isFrust, rules.Weighted())
+	nfvs, nfes, nfcs := 0, 0, 0
+	for _, t := range vts {
+		if t.frustrated(rules.Vertex) {
+			nfvs++
+		}
+	}
+	for _, t := range ets {
+		if t.frustrated(rules.Edge) {
+			nfes++
+		}
+	}
+	for _, f := range isFrust {
+		if f {
+			nfcs++
+		}
+	}
+
+	// Assign each vertex to a family, pooling small families.
+	family := make(map[string]string, len(g.Vs))
+	named, nfams := 0, 0
+	if delims != "" {
+		size := make(map[string]int)
+		for v := range g.Vs {
+			family[v] = namePrefix(v, delims)
+			size[family[v]]++
+		}
+		for v, fam := range family {
+			if size[fam] < minGroup {
+				family[v] = "<other>"
+			}
+		}
+		for _, n := range size {
+			if n >= minGroup {
+				named++
+			}
+		}
+		nfams = len(size)
+	}
+
+	// Tally vertices and cycles by family.
+	type tally struct {
+		FV, V int // Frustrated and total vertices
+		FC, C int // Frustrated and total cycles
+	}
+	tallies := make(map[string]*tally)
+	get := func(v string) *tally {
+		k := family[v]
+		if _, ok := tallies[k]; !ok {
+			tallies[k] = &tally{}
+		}
+		return tallies[k]
+	}
+	if delims != "" {
+		for v := range g.Vs {
+			t := get(v)
+			t.V++
+			if vt := vts[v]; vt != nil && vt.frustrated(rules.Vertex) {
+				t.FV++
+			}
+		}
+		for i, p := range ps {
+			ts := make(map[*tally]Empty)
+			for _, v := range p {
+				ts[get(v)] = Empty{}
+			}
+			for t := range ts {
+				t.C++
+				if isFrust[i] {
+					t.FC++
+				}
+			}
+		}
+	}
+
+	// Output the families from most to least frustrated, with the pooled
+	// small families last.
+	ks := make([]string, 0, len(tallies))
+	for k := range tallies {
+		ks = append(ks, k)
+	}
+	sort.Slice(ks, func(i, j int) bool {
+		ti, tj := tallies[ks[i]], tallies[ks[j]]
+		switch {
+		case (ks[i] == "<other>") != (ks[j] == "<other>"):
+			return ks[j] == "<other>"
+		case ti.FC != tj.FC:
+			return ti.FC > tj.FC
+		}
+		return ks[i] < ks[j]
+	})
+	for _, k := range ks {
+		t := tallies[k]
+		fmt.Fprintf(w, "AGG  %d %d %d %d | %s\n", t.FC, t.C, t.FV, t.V, k)
+	}
+
+	// Output the summary statistics.
+	if delims != "" {
+		fmt.Fprintf(w, "#AGG %d / %d = %f\n", named, nfams, fraction(named, nfams))
+	}
+	fmt.Fprintf(w, "#FV  %d / %d = %f\n", nfvs, len(g.Vs), fraction(nfvs, len(g.Vs)))
+	fmt.Fprintf(w, "#FE  %d / %d = %f\n", nfes, len(g.Es), fraction(nfes, len(g.Es)))
+	fmt.Fprintf(w, "#FC  %d / %d = %f\n", nfcs, len(ps), fraction(nfcs, len(ps)))
+}
@@ -0,0 +1,103 @@
+/* This file flags couplers whose coefficients look out of place relative to
+the rest of the problem.  Many frustrated formulations turn out to contain a
+sign typo or a misplaced decimal point, and such errors tend to stand out
+statistically. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// minAnomalyNeighbors is the minimum number of other couplers that must
+// touch an edge's endpoints before the edge's sign can be judged anomalous.
+const minAnomalyNeighbors = 3
+
+// An anomaly is a coupler whose coefficient appears to be a mistake.
+type anomaly struct {
+	E      [2]string // Edge
+	Reason string    // "sign" or "magnitude"
+	Ref    float64   // Value against which the coupler was judged
+}
+
+// findAnomalies returns each coupler that lies in a frustrated cycle and
+// either (a) has the opposite sign from every other coupler incident on
+// either of its endpoints or (b) has a magnitude at least factor times
+// greater or smaller than the median coupler magnitude.  For (a), Ref is the
+// number of neighboring couplers; for (b), it is the median magnitude.
+// Coefficients are taken from the given view.
+func (g Graph) findAnomalies(ps [][]string, isFrust []bool, factor float64, view string) []anomaly {
+	// Compute the median nonzero coupler magnitude.
+	mags := make([]float64, 0, len(g.Es))
+	for e := range g.Es {
+		if wt := g.couplerIn(view, e); wt != 0 {
+			mags = append(mags, math.Abs(wt))
+		}
+	}
+	if len(mags) == 0 {
+		return nil
+	}
+	sort.Float64s(mags)
+	med := mags[len(mags)/2]
+	if len(mags)%2 == 0 {
+		med = (mags[len(mags)/2-1] + mags[len(mags)/2]) / 2
+	}
+
+	// Tally the signs of the couplers incident on each vertex.
+	pos := make(map[string]int, len(g.Vs))
+	neg := make(map[string]int, len(g.Vs))
+	for e := range g.Es {
+		switch wt := g.couplerIn(view, e); {
+		case wt > 0:
+			pos[e[0]]++
+			pos[e[1]]++
+		case wt < 0:
+			neg[e[0]]++
+			neg[e[1]]++
+		}
+	}
+
+	// Judge each coupler in a frustrated cycle.
+	fEdges, _ := tallyEdges(ps, isFrust)
+	var anoms []anomaly
+	for _, e := range g.sortedEdges() {
+		wt := g.couplerIn(view, e)
+		if fEdges[e] == 0 || wt == 0 {
+			continue
+		}
+		np, nn := pos[e[0]]+pos[e[1]], neg[e[0]]+neg[e[1]]
+		if wt > 0 {
+			np -= 2
+		} else {
+			nn -= 2
+		}
+		switch {
+		case wt > 0 && np == 0 && nn >= minAnomalyNeighbors:
+			anoms = append(anoms, anomaly{E: e, Reason: "sign", Ref: float64(nn)})
+		case wt < 0 && nn == 0 && np >= minAnomalyNeighbors:
+			anoms = append(anoms, anomaly{E: e, Reason: "sign", Ref: float64(np)})
+		}
+		if r := math.Abs(wt) / med; r >= factor || r <= 1/factor {
+			anoms = append(anoms, anomaly{E: e, Reason: "magnitude", Ref: med})
+		}
+	}
+	return anoms
+}
+
+// OutputAnomalies outputs each coupler that lies in a frustrated cycle and
+// whose coefficient is statistically anomalous, followed by the number of
+// such couplers as a fraction of the couplers in frustrated cycles.  Coupler
+// strengths are output in the given view.
+func OutputAnomalies(w io.Writer, g Graph, ps [][]string, isFrust []bool, factor float64, view string) {
+	anoms := g.findAnomalies(ps, isFrust, factor, view)
+	flagged := make(map[[2]string]Empty, len(anoms))
+	for _, a := range anoms {
+		fmt.Fprintf(w, "ANOM %s %v %v | %s %s\n", a.Reason, g.couplerIn(view, a.E), a.Ref, a.E[0], a.E[1])
+		flagged[a.E] = Empty{}
+	}
+	fEdges, _ := tallyEdges(ps, isFrust)
+	fmt.Fprintf(w, "#ANOM %d / %d = %f\n", len(flagged), len(fEdges), fraction(len(flagged), len(fEdges)))
+}
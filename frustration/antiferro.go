@@ -0,0 +1,154 @@
+/* This file provides a fast path for pure antiferromagnets, graphs in which
+every coupling is antiferromagnetic.  In such a graph a cycle is frustrated
+exactly when its length is odd, so detecting frustration reduces to testing
+bipartiteness, and the frustration index is the number of edges left uncut by
+a maximum cut. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+)
+
+// isAntiferromagnet says whether a signed graph has at least one edge and
+// every edge is antiferromagnetic.
+func (sg signedGraph) isAntiferromagnet() bool {
+	if len(sg.Edges) == 0 {
+		return false
+	}
+	for _, s := range sg.Signs {
+		if s > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// A bfsForest is a breadth-first spanning forest of a signedGraph.
+type bfsForest struct {
+	Parent []int // Map from a vertex index to its parent's (-1 for a root)
+	Depth  []int // Map from a vertex index to its distance from its root
+}
+
+// bfsForest constructs a breadth-first spanning forest of a signedGraph,
+// rooting each tree at its lowest-indexed vertex.
+func (sg signedGraph) bfsForest() bfsForest {
+	nv := len(sg.Names)
+	f := bfsForest{Parent: make([]int, nv), Depth: make([]int, nv)}
+	seen := make([]bool, nv)
+	for root := range seen {
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		f.Parent[root] = -1
+		queue := []int{root}
+		for len(queue) > 0 {
+			u := queue[0]
+			queue = queue[1:]
+			for _, a := range sg.Adj[u] {
+				if !seen[a.To] {
+					seen[a.To] = true
+					f.Parent[a.To] = u
+					f.Depth[a.To] = f.Depth[u] + 1
+					queue = append(queue, a.To)
+				}
+			}
+		}
+	}
+	return f
+}
+
+// isTreeEdge says whether an edge belongs to a spanning forest.
+func (f bfsForest) isTreeEdge(e [2]int) bool {
+	return f.Parent[e[0]] == e[1] || f.Parent[e[1]] == e[0]
+}
+
+// fundamentalCycle returns, as a list of vertex indices in cycle order, the
+// cycle that a non-tree edge forms with a spanning forest.
+func (f bfsForest) fundamentalCycle(e [2]int) []int {
+	// Climb from both endpoints to their lowest common ancestor.
+	var up, down []int
+	u, v := e[0], e[1]
+	for u != v {
+		if f.Depth[u] >= f.Depth[v] {
+			up = append(up, u)
+			u = f.Parent[u]
+		} else {
+			down = append(down, v)
+			v = f.Parent[v]
+		}
+	}
+	cyc := append(up, u)
+	for i := len(down) - 1; i >= 0; i-- {
+		cyc = append(cyc, down[i])
+	}
+	return cyc
+}
+
+// OutputAntiferromagnet determines if a graph is a pure antiferromagnet.  If
+// not, it outputs nothing and returns false.  Otherwise, it outputs the side
+// of a maximum cut (found exactly if the graph is bipartite and by local
+// search with the given number of random restarts if not) on which each
+// vertex lies, the shortest odd cycle in a breadth-first cycle basis, the
+// number of odd cycles in that basis, and the number of edges cut, and
+// returns true.
+func OutputAntiferromagnet(w io.Writer, g Graph, restarts int, rng *rand.Rand) bool {
+	sg := g.signedGraph()
+	if !sg.isAntiferromagnet() {
+		return false
+	}
+
+	// Find every odd fundamental cycle.  A non-tree edge closes an odd
+	// cycle precisely when it joins two vertices of equal depth parity.
+	f := sg.bfsForest()
+	var odd [][2]int
+	nBase := 0
+	for _, e := range sg.Edges {
+		if f.isTreeEdge(e) {
+			continue
+		}
+		nBase++
+		if (f.Depth[e[0]]+f.Depth[e[1]])%2 == 0 {
+			odd = append(odd, e)
+		}
+	}
+
+	// Cut the edges.  With no odd cycle, depth parity is a perfect cut.
+	var sw []int
+	if len(odd) == 0 {
+		sw = make([]int, len(sg.Names))
+		for v, d := range f.Depth {
+			sw[v] = 1 - 2*(d%2)
+		}
+	} else {
+		_, sw = sg.frustrationIndexHeuristic(restarts, rng)
+	}
+	for v, x := range sw {
+		grp := 0
+		if x < 0 {
+			grp = 1
+		}
+		fmt.Fprintf(w, "AFG %d | %s\n", grp, sg.Names[v])
+	}
+
+	// Output the shortest odd cycle as a witness of frustration.
+	var short []int
+	for _, e := range odd {
+		if c := f.fundamentalCycle(e); short == nil || len(c) < len(short) {
+			short = c
+		}
+	}
+	if short != nil {
+		fmt.Fprintf(w, "ODD  %s\n", strings.Join(sg.pathNames(short), " "))
+	}
+
+	// Output the summary statistics.
+	cut := len(sg.Edges) - sg.negativeEdges(sw)
+	fmt.Fprintf(w, "#ODD %d / %d = %f\n", len(odd), nBase, fraction(len(odd), nBase))
+	fmt.Fprintf(w, "#MAXCUT %d / %d = %f\n", cut, len(sg.Edges), fraction(cut, len(sg.Edges)))
+	return true
+}
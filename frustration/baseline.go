@@ -0,0 +1,106 @@
+/* This file compares the frustration in a problem against a baseline saved
+from an earlier analysis so that changes to a problem's formulation can be
+rejected automatically if they increase frustration. */
+
+package frustration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseTolerances parses a comma-separated list of the amounts by which the
+// vertex, edge, and cycle fractions may exceed their baseline values.
+func ParseTolerances(s string) [3]float64 {
+	var tols [3]float64
+	fs := strings.Split(s, ",")
+	if len(fs) != 3 {
+		Abortf("Expected three comma-separated baseline tolerances but saw %q", s)
+	}
+	for i, f := range fs {
+		tol, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		CheckError(err)
+		if tol < 0 {
+			Abortf("Baseline tolerances must be non-negative")
+		}
+		tols[i] = tol
+	}
+	return tols
+}
+
+// ReadBaseline reads the summary from previously saved JSON results.  The
+// input may be either a results object or any object, such as the output
+// of --save-results or of the "merge" subcommand, whose "results" field is
+// one.
+func ReadBaseline(r io.Reader) Summary {
+	var doc struct {
+		Summary *Summary `json:"summary"`
+		Results *struct {
+			Summary *Summary `json:"summary"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		Abortf("Baseline is not valid JSON results")
+	}
+	switch {
+	case doc.Summary != nil:
+		return *doc.Summary
+	case doc.Results != nil && doc.Results.Summary != nil:
+		return *doc.Results.Summary
+	}
+	Abortf("Baseline contains no results summary")
+	return Summary{}
+}
+
+// WriteResults writes a single problem's results, along with their
+// provenance and the unsuppressed warnings issued so far, in JSON format.
+func WriteResults(w io.Writer, res Results, prov Provenance) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	CheckError(enc.Encode(struct {
+		Results    Results    `json:"results"`
+		Provenance Provenance `json:"provenance"`
+		Warnings   []Warning  `json:"warnings"`
+	}{res, prov, append([]Warning{}, Warnings()...)}))
+}
+
+// OutputBaselineComparison compares the frustrated vertex, edge, and cycle
+// fractions against a baseline, outputting one line per statistic, and
+// returns the number of statistics that exceed their baseline value by more
+// than the corresponding tolerance.  It warns if the baseline deemed vertices
+// or edges frustrated by different rules, as the fractions are then not
+// comparable.  A baseline that records no rules used the majority rule.
+func OutputBaselineComparison(w io.Writer, base, cur Summary, tols [3]float64) int {
+	rule := func(s string) string {
+		if s == "" {
+			return RuleMajority
+		}
+		return s
+	}
+	if rule(base.VertexRule) != rule(cur.VertexRule) || rule(base.EdgeRule) != rule(cur.EdgeRule) {
+		Warn("W004-baseline-rules", "", "The baseline used vertex and edge rules %s and %s, not %s and %s",
+			rule(base.VertexRule), rule(base.EdgeRule), rule(cur.VertexRule), rule(cur.EdgeRule))
+	}
+	stats := [3]struct {
+		Name      string
+		Base, Cur float64
+	}{
+		{"vertex", base.VertexFraction, cur.VertexFraction},
+		{"edge", base.EdgeFraction, cur.EdgeFraction},
+		{"cycle", base.CycleFraction, cur.CycleFraction},
+	}
+	nReg := 0
+	for i, st := range stats {
+		verdict := "ok"
+		if st.Cur-st.Base > tols[i] {
+			verdict = "regressed"
+			nReg++
+		}
+		fmt.Fprintf(w, "BASE  %s %f %f %+f %f %s\n", st.Name, st.Base, st.Cur, st.Cur-st.Base, tols[i], verdict)
+	}
+	fmt.Fprintf(w, "#BASE %d / %d = %f\n", nReg, len(stats), fraction(nReg, len(stats)))
+	return nReg
+}
@@ -0,0 +1,48 @@
+/* This file screens for couplers whose weight is the nearly cancelling sum
+of much larger terms.  Such a weight is dominated by rounding error, so its
+sign, and with it the classification of every cycle through the coupler, is
+numerically meaningless. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// CancelledCouplers returns, in lexicographic order, each edge whose weight
+// has a magnitude no greater than tol times the total magnitude of the terms
+// summed into it.  Edges formed from a single term, and all edges of a graph
+// whose parser did not record the terms' magnitudes, are never cancelled.
+func (g Graph) CancelledCouplers(tol float64) [][2]string {
+	if g.EMags == nil || tol <= 0 {
+		return nil
+	}
+	var cs [][2]string
+	for _, e := range g.sortedEdges() {
+		m := g.EMags[e]
+		if m > 0 && math.Abs(g.Es[e]) <= tol*m {
+			cs = append(cs, e)
+		}
+	}
+	return cs
+}
+
+// OutputCancellations outputs each coupler whose weight was numerically
+// cancelled, with the total magnitude of the terms summed into it and the
+// ratio of the two, followed by the number of such couplers as a fraction of
+// all edges.  It outputs nothing if no coupler was cancelled.  It returns the
+// number of cancelled couplers.
+func OutputCancellations(w io.Writer, g Graph, tol float64) int {
+	cs := g.CancelledCouplers(tol)
+	if len(cs) == 0 {
+		return 0
+	}
+	for _, e := range cs {
+		j, m := g.Es[e], g.EMags[e]
+		fmt.Fprintf(w, "CANC %v %v %g | %s %s\n", j, m, math.Abs(j)/m, e[0], e[1])
+	}
+	fmt.Fprintf(w, "#CANC %d / %d = %f\n", len(cs), len(g.Es), fraction(len(cs), len(g.Es)))
+	return len(cs)
+}
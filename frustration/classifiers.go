@@ -0,0 +1,97 @@
+/* This file provides alternative definitions of frustration, selectable
+with --classifier, to the default rule that a cycle is frustrated if it has an
+odd number of antiferromagnetic couplings.  Some definitions take a numeric
+parameter, which is written after the name and a colon (e.g., "softened:2"). */
+
+package frustration
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A ClassifierFamily constructs a Classifier from a numeric parameter.
+type ClassifierFamily func(param float64) Classifier
+
+// signParity is the default Classifier.
+var signParity Classifier = ClassifierFunc(Graph.isFrustrated)
+
+// RegisterClassifierFamily makes a parameterized family of Classifiers
+// available to --classifier as name:param.
+func RegisterClassifierFamily(name string, f ClassifierFamily) {
+	register("classifier family", name, f)
+}
+
+// LookupClassifier returns the Classifier registered under a given name or,
+// for a name of the form family:param, the member of the named family with
+// the given parameter.
+func LookupClassifier(name string) Classifier {
+	i := strings.Index(name, ":")
+	if i < 0 {
+		return lookup("classifier", name).(Classifier)
+	}
+	fam := lookup("classifier family", name[:i]).(ClassifierFamily)
+	x, err := strconv.ParseFloat(name[i+1:], 64)
+	if err != nil {
+		Abortf("Failed to parse the parameter of classifier %q", name)
+	}
+	return fam(x)
+}
+
+// isCouplerFrustrated says whether a cycle has an odd number of
+// antiferromagnetic couplers, disregarding the external fields that can
+// override a coupler's sign.
+func (g Graph) isCouplerFrustrated(p []string) bool {
+	afm := 0
+	for _, e := range g.pathToEdges(p) {
+		switch {
+		case g.Exact != nil && g.Exact.Es[e] != nil:
+			if g.Exact.Es[e].Sign() > 0 {
+				afm++
+			}
+		case g.Es[e] > 0:
+			afm++
+		}
+	}
+	return afm%2 == 1
+}
+
+// minCouplingClassifier returns a Classifier that deems a cycle frustrated
+// if it has an odd number of antiferromagnetic couplings and its weakest
+// coupler has a magnitude of at least theta.  Resolving the frustration of
+// such a cycle costs at least 2*theta in energy.
+func minCouplingClassifier(theta float64) Classifier {
+	if theta < 0 {
+		Abortf("The min-coupling threshold must be nonnegative but saw %v", theta)
+	}
+	return ClassifierFunc(func(g Graph, p []string) bool {
+		if !g.isFrustrated(p) {
+			return false
+		}
+		for _, e := range g.pathToEdges(p) {
+			if math.Abs(g.Es[e]) < theta {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// softenedClassifier returns a Classifier that deems a cycle frustrated if
+// the product of its edges' thermal correlations at inverse temperature beta
+// (see softEdgeWeights) is less than -1/2, that is, if its frustration
+// persists with at least half its full strength at that temperature.
+func softenedClassifier(beta float64) Classifier {
+	if beta <= 0 {
+		Abortf("Inverse temperatures must be positive but saw %v", beta)
+	}
+	return ClassifierFunc(func(g Graph, p []string) bool {
+		prod := 1.0
+		for _, e := range g.pathToEdges(p) {
+			s, _ := g.couplingSign(e[0], e[1])
+			prod *= float64(s) * math.Tanh(beta*math.Abs(g.Es[e]))
+		}
+		return prod < -0.5
+	})
+}
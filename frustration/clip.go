@@ -0,0 +1,110 @@
+/* This file simulates the limited coefficient ranges of annealing hardware,
+which clips each external field and coupler strength to the range it
+supports.  Clipping can change which couplings are ferromagnetic—a field that
+dominated a coupler may no longer do so—and hence which cycles are
+frustrated. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A CoeffRange is a closed interval of supported coefficient values.
+type CoeffRange struct {
+	Lo, Hi float64 // Least and greatest supported values
+}
+
+// ParseCoeffRange parses a range of the form "lo,hi".
+func ParseCoeffRange(s string) CoeffRange {
+	fs := strings.Split(s, ",")
+	if len(fs) != 2 {
+		Abortf("Expected a coefficient range of the form lo,hi but saw %q", s)
+	}
+	var r CoeffRange
+	var err error
+	r.Lo, err = strconv.ParseFloat(strings.TrimSpace(fs[0]), 64)
+	CheckError(err)
+	r.Hi, err = strconv.ParseFloat(strings.TrimSpace(fs[1]), 64)
+	CheckError(err)
+	if r.Lo > r.Hi {
+		Abortf("Coefficient range %q is empty", s)
+	}
+	return r
+}
+
+// clip returns the value in the range nearest to x.
+func (r CoeffRange) clip(x float64) float64 {
+	switch {
+	case x < r.Lo:
+		return r.Lo
+	case x > r.Hi:
+		return r.Hi
+	}
+	return x
+}
+
+// Clipped returns a copy of a graph in which each external field is clipped
+// to the range h and each coupler strength to the range j.  A nil range
+// leaves the corresponding coefficients unchanged.  The copy retains no QUBO
+// coefficients, as these no longer correspond to the clipped Ising
+// coefficients.
+func (g Graph) Clipped(h, j *CoeffRange) Graph {
+	cg := g
+	cg.QVs, cg.QEs = nil, nil
+	if h != nil {
+		cg.Vs = make(map[string]float64, len(g.Vs))
+		for v, wt := range g.Vs {
+			cg.Vs[v] = h.clip(wt)
+		}
+	}
+	if j != nil {
+		cg.Es = make(map[[2]string]float64, len(g.Es))
+		for e, wt := range g.Es {
+			cg.Es[e] = j.clip(wt)
+		}
+	}
+	return cg
+}
+
+// OutputClipChanges reports the effect of clipping a graph's coefficients:
+// each coefficient that was clipped and each cycle whose frustration, as
+// judged by a given Classifier, differs between the original graph and the
+// clipped graph, in which the cycles were found and classified.
+func OutputClipChanges(w io.Writer, orig, clipped Graph, ps [][]string, isFrust []bool, c Classifier) {
+	// Output the clipped coefficients.
+	nh := 0
+	for _, v := range orig.sortedVertices() {
+		if orig.Vs[v] != clipped.Vs[v] {
+			fmt.Fprintf(w, "CLPH %v %v | %s\n", orig.Vs[v], clipped.Vs[v], v)
+			nh++
+		}
+	}
+	nj := 0
+	for _, e := range orig.sortedEdges() {
+		if orig.Es[e] != clipped.Es[e] {
+			fmt.Fprintf(w, "CLPJ %v %v | %s %s\n", orig.Es[e], clipped.Es[e], e[0], e[1])
+			nj++
+		}
+	}
+
+	// Output the cycles whose classification changed.
+	label := map[bool]string{true: "frustrated", false: "satisfied"}
+	nc := 0
+	for i, p := range ps {
+		was := c.Classify(orig, p)
+		if was == isFrust[i] {
+			continue
+		}
+		fmt.Fprintf(w, "CLPC %s %s | %s\n", label[was], label[isFrust[i]], strings.Join(p, " "))
+		nc++
+	}
+
+	// Output the summary statistics.
+	fmt.Fprintf(w, "#CLPH %d / %d = %f\n", nh, len(orig.Vs), fraction(nh, len(orig.Vs)))
+	fmt.Fprintf(w, "#CLPJ %d / %d = %f\n", nj, len(orig.Es), fraction(nj, len(orig.Es)))
+	fmt.Fprintf(w, "#CLPC %d / %d = %f\n", nc, len(ps), fraction(nc, len(ps)))
+}
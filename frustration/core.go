@@ -0,0 +1,57 @@
+/* This file extracts a graph's frustration core: the part of the graph that
+remains after peeling away every vertex that participates in no frustrated
+cycle. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+)
+
+// FrustrationCore returns the subgraph induced by the vertices that lie in
+// at least one frustrated cycle.  Peeling away the other vertices cannot
+// destroy a frustrated cycle, so every vertex of the core still lies in a
+// frustrated cycle within the core, and peeling again would remove nothing.
+// Cycles are not enumerated; a vertex lies in a frustrated cycle exactly
+// when it is incident on an edge of an unbalanced biconnected component.
+func (g Graph) FrustrationCore() Graph {
+	// Find the vertices incident on a frustrated edge.
+	sg := g.signedGraph()
+	keep := make(map[string]Empty)
+	for k, f := range sg.frustratedEdges() {
+		if f {
+			keep[sg.Names[sg.Edges[k][0]]] = Empty{}
+			keep[sg.Names[sg.Edges[k][1]]] = Empty{}
+		}
+	}
+
+	// Construct the subgraph they induce.
+	core := Graph{
+		Vs:     make(map[string]float64, len(keep)),
+		Es:     make(map[[2]string]float64),
+		Offset: g.Offset,
+	}
+	for v := range keep {
+		core.Vs[v] = g.Vs[v]
+	}
+	for e, wt := range g.Es {
+		_, ok0 := keep[e[0]]
+		_, ok1 := keep[e[1]]
+		if ok0 && ok1 {
+			core.Es[e] = wt
+		}
+	}
+	return core
+}
+
+// OutputFrustrationCore outputs the vertices of a graph's frustration core
+// followed by the core's size relative to the graph's.
+func OutputFrustrationCore(w io.Writer, g Graph) {
+	core := g.FrustrationCore()
+	for _, v := range core.sortedVertices() {
+		fmt.Fprintf(w, "CORE %v | %s\n", core.Vs[v], v)
+	}
+	fmt.Fprintf(w, "#CORV %d / %d = %f\n", len(core.Vs), len(g.Vs), fraction(len(core.Vs), len(g.Vs)))
+	fmt.Fprintf(w, "#CORE %d / %d = %f\n", len(core.Es), len(g.Es), fraction(len(core.Es), len(g.Es)))
+}
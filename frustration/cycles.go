@@ -0,0 +1,826 @@
+/* This file provides functions for finding cycles in a graph. */
+
+package frustration
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/deckarep/golang-set"
+)
+
+// spanningTree returns the indices of the edges in a random spanning forest
+// and the indices of the non-forest edges.
+func (sg signedGraph) spanningTree(rng *rand.Rand) ([]int, []int) {
+	order := make([]int, len(sg.Edges))
+	for i := range order {
+		order[i] = i
+	}
+	rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	return sg.kruskal(order)
+}
+
+// maxWeightSpanningForest returns the indices of the edges in a spanning
+// forest that maximizes the total coupler magnitude and the indices of the
+// remaining (non-forest) edges, each in decreasing order of magnitude.
+func (sg signedGraph) maxWeightSpanningForest() ([]int, []int) {
+	order := make([]int, len(sg.Edges))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return math.Abs(sg.Weights[order[i]]) > math.Abs(sg.Weights[order[j]])
+	})
+	return sg.kruskal(order)
+}
+
+// kruskal adds each edge, in the given order of edge indices, to either a
+// spanning forest or, if it would close a cycle, a list of non-forest edges.
+// It returns both lists.
+func (sg signedGraph) kruskal(order []int) ([]int, []int) {
+	// Place each vertex in its own set, represented by a parent pointer
+	// leading to the set's root.
+	parent := make([]int, len(sg.Names))
+	for v := range parent {
+		parent[v] = v
+	}
+	find := func(v int) int {
+		for parent[v] != v {
+			parent[v] = parent[parent[v]]
+			v = parent[v]
+		}
+		return v
+	}
+
+	// Add each edge to either the forest list or the non-forest list.
+	tEdges := make([]int, 0, len(sg.Names))
+	ntEdges := make([]int, 0, len(order))
+	for _, i := range order {
+		u, v := find(sg.Edges[i][0]), find(sg.Edges[i][1])
+		if u == v {
+			// Same set --> non-forest edge
+			ntEdges = append(ntEdges, i)
+		} else {
+			// Different sets --> forest edge (and merge the sets)
+			parent[u] = v
+			tEdges = append(tEdges, i)
+		}
+	}
+	return tEdges, ntEdges
+}
+
+// treeForest roots each tree of the spanning forest with the given edges at
+// its lowest-indexed vertex.
+func (sg signedGraph) treeForest(tEdges []int) bfsForest {
+	nv := len(sg.Names)
+	adj := make([][]int, nv)
+	for _, i := range tEdges {
+		u, v := sg.Edges[i][0], sg.Edges[i][1]
+		adj[u] = append(adj[u], v)
+		adj[v] = append(adj[v], u)
+	}
+	f := bfsForest{Parent: make([]int, nv), Depth: make([]int, nv)}
+	seen := make([]bool, nv)
+	for root := range seen {
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		f.Parent[root] = -1
+		queue := []int{root}
+		for len(queue) > 0 {
+			u := queue[0]
+			queue = queue[1:]
+			for _, v := range adj[u] {
+				if !seen[v] {
+					seen[v] = true
+					f.Parent[v] = u
+					f.Depth[v] = f.Depth[u] + 1
+					queue = append(queue, v)
+				}
+			}
+		}
+	}
+	return f
+}
+
+// baseCycles returns a base set of cycles, each as a list of vertex indices
+// in cycle order: the fundamental cycles of a random spanning forest.
+func (sg signedGraph) baseCycles(rng *rand.Rand) [][]int {
+	tEdges, ntEdges := sg.spanningTree(rng)
+	f := sg.treeForest(tEdges)
+	cycles := make([][]int, len(ntEdges))
+	for k, i := range ntEdges {
+		checkMemory()
+		sg.deadline.check()
+		cycles[k] = f.fundamentalCycle(sg.Edges[i])
+	}
+	return cycles
+}
+
+// baseCyclePaths returns a base set of cyclic paths that appear in the graph.
+func (g Graph) baseCyclePaths(rng *rand.Rand) [][]string {
+	sg := g.signedGraph()
+	bcs := sg.baseCycles(rng)
+	cycles := make([][]string, len(bcs))
+	for i, c := range bcs {
+		checkMemory()
+		g.deadline.check()
+		cycles[i] = sg.pathNames(c)
+	}
+	return cycles
+}
+
+// pathToEdges converts a cycle to a list of edges.  Edge order is
+// canonicalized.
+func (g Graph) pathToEdges(c []string) [][2]string {
+	nv := len(c)
+	edges := make([][2]string, nv)
+	for i, v1 := range c {
+		v2 := c[(i+1)%nv]
+		if v1 > v2 {
+			v1, v2 = v2, v1
+		}
+		edges[i] = [2]string{v1, v2}
+	}
+	return edges
+}
+
+// edgesToPath converts a list of edges to a path.
+func (g Graph) edgesToPath(es [][2]string) []string {
+	// Map each vertex to the two vertices it abuts.
+	near := make(map[string][]string, len(es))
+	minV := es[0][0]
+	for _, e := range es {
+		// Keep track of the minimum vertex name.
+		if e[0] < minV {
+			minV = e[0]
+		}
+		if e[1] < minV {
+			minV = e[1]
+		}
+
+		// Make e[1] a neighbor of e[0].
+		if abuts, ok := near[e[0]]; ok {
+			near[e[0]] = append(abuts, e[1])
+		} else {
+			abuts = make([]string, 1, 2)
+			abuts[0] = e[1]
+			near[e[0]] = abuts
+		}
+
+		// Make e[0] a neighbor of e[1].
+		if abuts, ok := near[e[1]]; ok {
+			near[e[1]] = append(abuts, e[0])
+		} else {
+			abuts = make([]string, 1, 2)
+			abuts[0] = e[0]
+			near[e[1]] = abuts
+		}
+	}
+
+	// Construct a chain of vertices, always choosing the unique neighbor
+	// that that was not already visited.  Starting from the minimum vertex
+	// and proceeding toward the smaller of its neighbors yields the
+	// lexicographically minimal rotation or reflection of the cycle.
+	if ab := near[minV]; ab[1] < ab[0] {
+		ab[0], ab[1] = ab[1], ab[0]
+	}
+	p := make([]string, 1, len(es))
+	p[0] = minV
+	for len(near) > 1 {
+		last := p[len(p)-1]
+		abuts := near[last]
+		delete(near, last)
+		if _, ok := near[abuts[0]]; ok {
+			p = append(p, abuts[0])
+		} else {
+			p = append(p, abuts[1])
+		}
+	}
+	return p
+}
+
+// A CombineRule restricts which combinations of base cycles combinedCycles
+// retains, yielding a tractable set of cycles between the cycle basis and
+// the complete set of elementary cycles.  The zero rule retains every
+// combination.
+type CombineRule struct {
+	MaxLen    int  // Maximum number of edges in a combination (0: unlimited)
+	Shrinking bool // Retain only combinations shorter than both of their parents
+}
+
+// Restricted says whether a rule discards any combinations.
+func (cr CombineRule) Restricted() bool {
+	return cr.MaxLen > 0 || cr.Shrinking
+}
+
+// allows says whether a rule retains a combination of a given length formed
+// from parents of given lengths.
+func (cr CombineRule) allows(n, p1, p2 int) bool {
+	switch {
+	case cr.MaxLen > 0 && n > cr.MaxLen:
+		return false
+	case cr.Shrinking && (n >= p1 || n >= p2):
+		return false
+	}
+	return true
+}
+
+// elementaryCycles returns the graph's elementary cycles.  With the zero
+// combination rule, it enumerates them directly by Johnson's algorithm.
+// Otherwise, it combines the given basic cycles, discarding combinations as
+// the rule directs.
+func (g Graph) elementaryCycles(bcs [][][2]string, rule CombineRule) [][][2]string {
+	if rule.Restricted() {
+		return g.combinedCycles(bcs, rule)
+	}
+	return g.johnsonCycles()
+}
+
+// johnsonCycles enumerates every elementary cycle using Johnson's algorithm
+// (D. B. Johnson, "Finding all the elementary circuits of a directed graph,"
+// SIAM J. Comput. 4(1), 1975), whose running time is linear in the number of
+// vertices and edges per cycle found.  The graph is treated as a directed
+// graph with an arc in each direction per edge.  Each cycle is then found in
+// both orientations, and each edge forms a two-arc circuit; only the
+// orientation in which the cycle's second vertex is less than its last is
+// retained, and two-arc circuits are discarded.  The search from each start
+// vertex is confined to the strongly connected component containing it of
+// the subgraph induced by it and the greater vertices.  If memory runs low,
+// it stops early and returns only the cycles found so far.
+func (g Graph) johnsonCycles() [][][2]string {
+	// Work with vertex indices, which are assigned in lexicographic order.
+	sg := g.signedGraph()
+	names := sg.Names
+
+	// Prepare the search state, which is reset for each start vertex.
+	var cs [][][2]string
+	inComp := make([]bool, len(names))
+	blocked := make([]bool, len(names))
+	bSets := make([]map[int]Empty, len(names))
+	var path []int
+	var unblock func(u int)
+	unblock = func(u int) {
+		blocked[u] = false
+		for w := range bSets[u] {
+			delete(bSets[u], w)
+			if blocked[w] {
+				unblock(w)
+			}
+		}
+	}
+	var circuit func(s, v int) bool
+	circuit = func(s, v int) bool {
+		found := false
+		path = append(path, v)
+		blocked[v] = true
+		for _, a := range sg.Adj[v] {
+			switch w := a.To; {
+			case !inComp[w]:
+			case w == s:
+				if len(path) >= 3 && path[1] < path[len(path)-1] {
+					cs = append(cs, sg.pathEdges(path))
+				}
+				found = true
+			case !blocked[w]:
+				if circuit(s, w) {
+					found = true
+				}
+			}
+		}
+		if found {
+			unblock(v)
+		} else {
+			for _, a := range sg.Adj[v] {
+				if inComp[a.To] {
+					bSets[a.To][v] = Empty{}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return found
+	}
+
+	// Search for the cycles whose least vertex is each vertex in turn.
+	for s := range names {
+		checkMemory()
+		g.deadline.check()
+		if memoryIsLow() {
+			Warn("W006-incomplete-cycles", "", "Memory is running low; stopping after searching from %d of %d vertices, so the elementary cycles reported are incomplete", s, len(names))
+			break
+		}
+
+		// Find s's strongly connected component among the vertices
+		// not less than s.  Because every edge is an arc in both
+		// directions, this is the set of vertices reachable from s.
+		comp := []int{s}
+		inComp[s] = true
+		for i := 0; i < len(comp); i++ {
+			for _, a := range sg.Adj[comp[i]] {
+				if w := a.To; w > s && !inComp[w] {
+					inComp[w] = true
+					comp = append(comp, w)
+				}
+			}
+		}
+		if len(comp) >= 3 {
+			for _, v := range comp {
+				blocked[v] = false
+				bSets[v] = make(map[int]Empty)
+			}
+			circuit(s, s)
+		}
+		for _, v := range comp {
+			inComp[v] = false
+			bSets[v] = nil
+		}
+	}
+	return cs
+}
+
+// combinedCycles takes a list of basic cycles and combines these to form all
+// elementary cycles using Gibb's algorithm
+// (cf. http://dspace.mit.edu/bitstream/handle/1721.1/68106/FTL_R_1982_07.pdf,
+// p. 14).  A combination rule may discard combinations as they are formed,
+// in which case neither they nor anything subsequently formed from them is
+// returned.  If memory runs low, it stops early and returns only the
+// elementary cycles formed from the basic cycles considered so far.
+func (g Graph) combinedCycles(bcs [][][2]string, rule CombineRule) [][][2]string {
+	// Convert the input list of lists of edges to a list of sets of edges.
+	phi := make([]mapset.Set, len(bcs))
+	for i, c := range bcs {
+		phi[i] = mapset.NewSet()
+		for _, e := range c {
+			phi[i].Add(e)
+		}
+	}
+
+	// Initialize our various data structures.
+	s := mapset.NewSet(phi[0])
+	q := mapset.NewSet(phi[0])
+	r := mapset.NewSet()
+	rs := mapset.NewSet()
+
+	// Consider each basic cycle in turn.
+	for i := 1; i < len(phi); i++ {
+		checkMemory()
+		g.deadline.check()
+		if memoryIsLow() {
+			Warn("W006-incomplete-cycles", "", "Memory is running low; stopping after combining %d of %d base cycles, so the elementary cycles reported are incomplete", i, len(phi))
+			break
+		}
+
+		// Add to either r or rs the symmetric difference of each cycle
+		// in q with the current phi.
+		for ti := range q.Iterator().C {
+			t := ti.(mapset.Set)
+			diff := t.SymmetricDifference(phi[i])
+			if !rule.allows(diff.Cardinality(), t.Cardinality(), phi[i].Cardinality()) {
+				continue
+			}
+			if t.Intersect(phi[i]).Cardinality() == 0 {
+				rs.Add(diff)
+			} else {
+				r.Add(diff)
+			}
+		}
+
+		// If any cycle in r is a proper superset of another cycle in
+		// r, move the superset from r to rs.
+		move := mapset.NewSet() // Elements to move from r to rs
+		var wg sync.WaitGroup
+		rSlice := r.ToSlice()
+		for _, ui := range rSlice {
+			wg.Add(1)
+			go func(u mapset.Set) {
+				defer wg.Done()
+				for _, vi := range rSlice {
+					v := vi.(mapset.Set)
+					if u.IsProperSubset(v) {
+						move.Add(v)
+					}
+				}
+			}(ui.(mapset.Set))
+		}
+		wg.Wait()
+		r = r.Difference(move)
+		rs = rs.Union(move)
+
+		// Copy r and phi into both s and q then additionally copy rs
+		// into q.
+		s = s.Union(r)
+		s.Add(phi[i])
+		q = q.Union(r).Union(rs)
+		q.Add(phi[i])
+		r.Clear()
+		rs.Clear()
+	}
+
+	// Convert from a set of sets back to a list of lists.
+	ecs := make([][][2]string, 0, s.Cardinality())
+	for ci := range s.Iterator().C {
+		c := ci.(mapset.Set)
+		cyc := make([][2]string, 0, c.Cardinality())
+		for ei := range c.Iterator().C {
+			e := ei.([2]string)
+			cyc = append(cyc, e)
+		}
+		ecs = append(ecs, cyc)
+	}
+	return ecs
+}
+
+// couplingSign returns +1 if the edge between vertices u and v acts
+// ferromagnetically and -1 if it acts antiferromagnetically.  It additionally
+// reports whether that determination was made by the external fields on u and
+// v rather than by the coupler strength.  Exact weights, if present, are used
+// in place of the floating-point weights.
+func (g Graph) couplingSign(u, v string) (int, bool) {
+	if g.Exact != nil {
+		return g.Exact.couplingSign(u, v)
+	}
+
+	// Determine the coupler strength of edge UV and the strength of the
+	// external field applied to each of vertices U and V.
+	if u > v {
+		u, v = v, u
+	}
+	return couplingSignOf(g.Es[[2]string{u, v}], g.Vs[u], g.Vs[v])
+}
+
+// couplingSignOf is couplingSign for an edge with coupler strength cs whose
+// endpoints have external fields hu and hv.
+func couplingSignOf(cs, hu, hv float64) (int, bool) {
+	// If both external fields are stronger than the coupler strength,
+	// they override the coupler value in determining if we have a
+	// ferromagnetic or antiferromagnetic coupling.
+	ef := [2]float64{hu, hv}
+	if math.Abs(ef[0]) > math.Abs(cs) && math.Abs(ef[1]) > math.Abs(cs) {
+		// External fields dominate.
+		switch {
+		case ef[0] > 0.0 && ef[1] < 0.0:
+			return -1, true
+		case ef[0] < 0.0 && ef[1] > 0.0:
+			return -1, true
+		}
+		return 1, true
+	}
+
+	// Coupler strength dominates.
+	if cs > 0 {
+		return -1, false
+	}
+	return 1, false
+}
+
+// classifyCycles converts each cycle from a list of edges to a path and
+// says, according to a Classifier, whether each cycle is frustrated.
+func (g Graph) classifyCycles(ecs [][][2]string, c Classifier) ([][]string, []bool) {
+	return g.classifyConcurrently(ecs, c, 0)
+}
+
+// classifyChunk is the number of consecutive cycles that a goroutine in
+// classifyConcurrently claims at a time.
+const classifyChunk = 1024
+
+// classifyConcurrently is classifyCycles using the given number of
+// goroutines, or GOMAXPROCS goroutines if threads is 0.  Goroutines claim
+// chunks of consecutive cycles as they finish the previous ones, so a few
+// long cycles do not leave the others idle, and the results are in the same
+// order as the cycles however the work was divided.
+func (g Graph) classifyConcurrently(ecs [][][2]string, c Classifier, threads int) ([][]string, []bool) {
+	ps := make([][]string, len(ecs))
+	isFrust := make([]bool, len(ecs))
+	if threads <= 0 {
+		threads = runtime.GOMAXPROCS(0)
+	}
+	if n := (len(ecs) + classifyChunk - 1) / classifyChunk; threads > n {
+		threads = n
+	}
+	var next int64 // Index of the next unclaimed chunk
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				lo := int(atomic.AddInt64(&next, 1)-1) * classifyChunk
+				if lo >= len(ecs) {
+					return
+				}
+				hi := lo + classifyChunk
+				if hi > len(ecs) {
+					hi = len(ecs)
+				}
+				for i := lo; i < hi; i++ {
+					ps[i] = g.edgesToPath(ecs[i])
+					isFrust[i] = c.Classify(g, ps[i])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	checkMemory()
+	g.deadline.check()
+	return ps, isFrust
+}
+
+// dedupCycles removes duplicate cycles from a list, where two cycles are
+// duplicates if their vertex sequences are rotations or reflections of each
+// other.  It returns the unique cycles and the number of duplicates removed.
+func (g Graph) dedupCycles(cs [][][2]string) ([][][2]string, int) {
+	seen := make(map[string]Empty, len(cs))
+	uniq := make([][][2]string, 0, len(cs))
+	for _, c := range cs {
+		checkMemory()
+		g.deadline.check()
+		key := strings.Join(g.edgesToPath(c), "\x00")
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = Empty{}
+		uniq = append(uniq, c)
+	}
+	return uniq, len(cs) - len(uniq)
+}
+
+// findCycles returns the graph's base cycles and the cycles to analyze: the
+// elementary cycles if all is true or the base cycles otherwise.  Each cycle
+// is expressed as a list of edges.  Duplicate elementary cycles are removed,
+// and their number is returned as well.  rng selects the spanning tree from
+// which the base cycles are derived.
+func (g Graph) findCycles(all bool, rng *rand.Rand) ([][][2]string, [][][2]string, int) {
+	bPath := g.baseCyclePaths(rng)
+	bcs := make([][][2]string, len(bPath))
+	for i, p := range bPath {
+		checkMemory()
+		g.deadline.check()
+		bcs[i] = g.pathToEdges(p)
+	}
+	if !all || len(bcs) == 0 {
+		return bcs, bcs, 0
+	}
+	ecs, ndup := g.dedupCycles(g.elementaryCycles(bcs, CombineRule{}))
+	return bcs, ecs, ndup
+}
+
+// estimateSamples is the number of random cycle-space elements examined when
+// estimating the number of elementary cycles.
+const estimateSamples = 10000
+
+// estimateElementaryCycles estimates the number of elementary cycles in a
+// graph with the given base cycles.  The base cycles span a cycle space
+// whose 2^d - 1 nonempty elements (d being the number of base cycles) are
+// exactly the graph's nonempty even subgraphs.  Each elementary cycle is one
+// of these, so the estimate is 2^d - 1 times the fraction of uniformly
+// random cycle-space elements that are a single cycle.  If no sample is a
+// single cycle, the estimate falls back to the "rule of three" upper
+// confidence bound on that fraction.
+func (g Graph) estimateElementaryCycles(bcs [][][2]string, rng *rand.Rand) float64 {
+	d := len(bcs)
+	total := math.Pow(2, float64(d)) - 1
+	if d <= 16 {
+		return total // Small enough to be cheap regardless.
+	}
+	hits := 0
+	for s := 0; s < estimateSamples; s++ {
+		checkMemory()
+		g.deadline.check()
+
+		// XOR together a random nonempty subset of the base cycles.
+		es := make(map[[2]string]Empty)
+		for len(es) == 0 {
+			for _, c := range bcs {
+				if rng.Intn(2) == 0 {
+					continue
+				}
+				for _, e := range c {
+					if _, ok := es[e]; ok {
+						delete(es, e)
+					} else {
+						es[e] = Empty{}
+					}
+				}
+			}
+		}
+
+		// The result is a single cycle if it is connected and every
+		// vertex has degree 2.
+		deg := make(map[string]int, len(es))
+		ns := make(map[string][]string, len(es))
+		for e := range es {
+			deg[e[0]]++
+			deg[e[1]]++
+			ns[e[0]] = append(ns[e[0]], e[1])
+			ns[e[1]] = append(ns[e[1]], e[0])
+		}
+		single := len(deg) == len(es)
+		for _, k := range deg {
+			if k != 2 {
+				single = false
+				break
+			}
+		}
+		if single {
+			// Walk the cycle from an arbitrary vertex.
+			var start string
+			for v := range deg {
+				start = v
+				break
+			}
+			n, prev, cur := 1, "", start
+			for {
+				next := ns[cur][0]
+				if next == prev {
+					next = ns[cur][1]
+				}
+				if next == start {
+					break
+				}
+				prev, cur = cur, next
+				n++
+			}
+			single = n == len(deg)
+		}
+		if single {
+			hits++
+		}
+	}
+	frac := float64(hits) / estimateSamples
+	if hits == 0 {
+		frac = 3.0 / estimateSamples
+	}
+	return total * frac
+}
+
+// isFrustrated says whether a cycle is frustrated (i.e., has an odd number of
+// antiferromagnetic couplings).
+func (g Graph) isFrustrated(p []string) bool {
+	afm := uint(0)
+	np := len(p)
+	for i, u := range p {
+		v := p[(i+1)%np]
+		if s, _ := g.couplingSign(u, v); s < 0 {
+			afm++
+		}
+	}
+	return afm&1 == 1
+}
+
+// sortedAdjacency returns a map from each vertex to a sorted list of its
+// neighbors.
+func (g Graph) sortedAdjacency() map[string][]string {
+	adj := make(map[string][]string, len(g.Vs))
+	for _, e := range g.sortedEdges() {
+		adj[e[0]] = append(adj[e[0]], e[1])
+		adj[e[1]] = append(adj[e[1]], e[0])
+	}
+	for _, ns := range adj {
+		sort.Strings(ns)
+	}
+	return adj
+}
+
+// shortestPathAvoiding returns a shortest path from s to d that does not
+// visit vertex x or nil if no such path exists.
+func shortestPathAvoiding(adj map[string][]string, s, d, x string) []string {
+	prev := map[string]string{s: s, x: x}
+	queue := []string{s}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		if u == d {
+			// Walk the predecessors back to the source.
+			p := []string{d}
+			for u != s {
+				u = prev[u]
+				p = append(p, u)
+			}
+			for i, j := 0, len(p)-1; i < j; i, j = i+1, j-1 {
+				p[i], p[j] = p[j], p[i]
+			}
+			return p
+		}
+		for _, v := range adj[u] {
+			if _, ok := prev[v]; !ok {
+				prev[v] = u
+				queue = append(queue, v)
+			}
+		}
+	}
+	return nil
+}
+
+// allPathsAvoiding invokes a function on every simple path from s to d that
+// does not visit vertex x.
+func allPathsAvoiding(adj map[string][]string, s, d, x string, f func(p []string)) {
+	onPath := map[string]Empty{x: {}, s: {}}
+	p := []string{s}
+	var dfs func(u string)
+	dfs = func(u string) {
+		if u == d {
+			f(p)
+			return
+		}
+		for _, v := range adj[u] {
+			if _, ok := onPath[v]; ok {
+				continue
+			}
+			onPath[v] = Empty{}
+			p = append(p, v)
+			dfs(v)
+			p = p[:len(p)-1]
+			delete(onPath, v)
+		}
+	}
+	dfs(s)
+}
+
+// cyclesThroughEdges returns cycles, each expressed as a list of edges, that
+// pass through at least one of the given edges.  Rather than computing a
+// full cycle basis, it searches directly for paths that close each edge into
+// a cycle.  If all is false, it returns, for each neighbor n of the edge's
+// first endpoint u, the cycle formed by u, n, and a shortest path from n to
+// the edge's second endpoint.  If all is true, it returns every elementary
+// cycle through the edge (extremely slow on dense graphs).
+func (g Graph) cyclesThroughEdges(targets [][2]string, all bool) [][][2]string {
+	adj := g.sortedAdjacency()
+	seen := make(map[string]Empty)
+	var cs [][][2]string
+	addCycle := func(p []string) {
+		es := g.pathToEdges(p)
+		key := strings.Join(g.edgesToPath(es), "\x00")
+		if _, ok := seen[key]; !ok {
+			seen[key] = Empty{}
+			cs = append(cs, es)
+		}
+	}
+	for _, e := range targets {
+		u, v := e[0], e[1]
+		if _, ok := g.Es[e]; !ok {
+			Abortf("Edge %s,%s does not appear in the graph", u, v)
+		}
+		if all {
+			allPathsAvoiding(adj, v, u, "", func(p []string) {
+				if len(p) > 2 {
+					addCycle(p)
+				}
+			})
+			continue
+		}
+		for _, n := range adj[u] {
+			if n == v {
+				continue
+			}
+			if p := shortestPathAvoiding(adj, n, v, u); p != nil {
+				addCycle(append([]string{u}, p...))
+			}
+		}
+	}
+	return cs
+}
+
+// shortCycles returns every elementary cycle of at most maxLen edges.  Each
+// cycle is found exactly once, by a depth-first search from its least vertex
+// that visits only greater vertices and that accepts a closed path only in
+// the orientation in which its second vertex is less than its last.  The
+// search is bounded by maxLen rather than by the number of cycles in the
+// graph, so it remains tractable where enumerating every elementary cycle
+// would not.
+func (g Graph) shortCycles(maxLen int) [][][2]string {
+	adj := g.sortedAdjacency()
+	var cs [][][2]string
+	var path []string
+	onPath := make(map[string]bool)
+	var dfs func(s, u string)
+	dfs = func(s, u string) {
+		for _, n := range adj[u] {
+			switch {
+			case n == s:
+				if len(path) >= 3 && path[1] < path[len(path)-1] {
+					cs = append(cs, g.pathToEdges(path))
+				}
+			case n < s || onPath[n] || len(path) == maxLen:
+			default:
+				path = append(path, n)
+				onPath[n] = true
+				dfs(s, n)
+				onPath[n] = false
+				path = path[:len(path)-1]
+			}
+		}
+	}
+	for _, s := range g.sortedVertices() {
+		path = append(path[:0], s)
+		onPath[s] = true
+		dfs(s, s)
+		onPath[s] = false
+	}
+	return cs
+}
@@ -0,0 +1,169 @@
+package frustration
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// A testGraph is a small graph on which an exact algorithm is checked
+// against brute force.
+type testGraph struct {
+	Name string // Description of the graph
+	G    Graph  // The graph itself
+}
+
+// completeEdges returns the edges of a complete graph on n vertices.
+func completeEdges(n int) [][2]int {
+	var es [][2]int
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			es = append(es, [2]int{i, j})
+		}
+	}
+	return es
+}
+
+// randomEdges returns the edges of an Erdős–Rényi graph on n vertices with
+// edge probability p.
+func randomEdges(n int, p float64, rng *rand.Rand) [][2]int {
+	var es [][2]int
+	for _, e := range completeEdges(n) {
+		if rng.Float64() < p {
+			es = append(es, e)
+		}
+	}
+	return es
+}
+
+// testGraphs returns rings, ladders, K4, K5, a 4×4 lattice, and a random
+// graph, each with ±1 couplers and with couplers of random magnitude, and
+// each with no fields, weak fields, and fields that dominate the couplers on
+// about half the vertices.
+func testGraphs(rng *rand.Rand) []testGraph {
+	lattice := func(dims []int, periodic bool) [][2]int {
+		_, es := latticeEdges(dims, periodic)
+		return es
+	}
+	shapes := []struct {
+		Name string
+		N    int
+		Es   [][2]int
+	}{
+		{"ring5", 5, lattice([]int{5}, true)},
+		{"ring8", 8, lattice([]int{8}, true)},
+		{"ladder4", 8, lattice([]int{2, 4}, false)},
+		{"ladder6", 12, lattice([]int{2, 6}, false)},
+		{"K4", 4, completeEdges(4)},
+		{"K5", 5, completeEdges(5)},
+		{"lattice4x4", 16, lattice([]int{4, 4}, false)},
+		{"random8", 8, randomEdges(8, 0.4, rng)},
+	}
+	var tgs []testGraph
+	for _, sh := range shapes {
+		for _, wts := range []string{"pm", "uniform"} {
+			for _, fields := range []string{"none", "weak", "dominating"} {
+				g := Graph{
+					Vs:    make(map[string]float64, sh.N),
+					Es:    make(map[[2]string]float64, len(sh.Es)),
+					EMags: make(map[[2]string]float64, len(sh.Es)),
+				}
+				name := func(i int) string { return fmt.Sprintf("v%02d", i) }
+				for i := 0; i < sh.N; i++ {
+					h := 0.0
+					switch {
+					case fields == "weak":
+						h = rng.Float64() - 0.5
+					case fields == "dominating" && rng.Intn(2) == 0:
+						h = float64(1-2*rng.Intn(2)) * (2 + rng.Float64())
+					}
+					g.Vs[name(i)] = h
+				}
+				for _, e := range sh.Es {
+					j := float64(1 - 2*rng.Intn(2))
+					if wts == "uniform" {
+						j *= 0.5 + rng.Float64()
+					}
+					key := [2]string{name(e[0]), name(e[1])}
+					g.Es[key] = j
+					g.EMags[key] = j
+					if j < 0 {
+						g.EMags[key] = -j
+					}
+				}
+				tgs = append(tgs, testGraph{Name: sh.Name + "/" + wts + "/" + fields, G: g})
+			}
+		}
+	}
+	return tgs
+}
+
+// cycleKey returns a canonical representation of a cycle given as a list of
+// edges.
+func cycleKey(c [][2]string) string {
+	es := make([]string, len(c))
+	for i, e := range c {
+		if e[0] > e[1] {
+			e[0], e[1] = e[1], e[0]
+		}
+		es[i] = e[0] + "-" + e[1]
+	}
+	sort.Strings(es)
+	return strings.Join(es, " ")
+}
+
+// bruteForceCycles enumerates every elementary cycle of a graph by
+// depth-first search for simple paths that return to their least vertex.
+func bruteForceCycles(g Graph) map[string]bool {
+	adj := g.sortedAdjacency()
+	cycs := make(map[string]bool)
+	var path []string
+	onPath := make(map[string]bool)
+	var extend func(s, v string)
+	extend = func(s, v string) {
+		for _, w := range adj[v] {
+			switch {
+			case w == s && len(path) >= 3:
+				cycs[cycleKey(g.pathToEdges(path))] = true
+			case w > s && !onPath[w]:
+				path = append(path, w)
+				onPath[w] = true
+				extend(s, w)
+				onPath[w] = false
+				path = path[:len(path)-1]
+			}
+		}
+	}
+	for _, s := range g.sortedVertices() {
+		path = []string{s}
+		extend(s, s)
+	}
+	return cycs
+}
+
+// TestJohnsonCycles checks that johnsonCycles finds every elementary cycle,
+// and nothing else, exactly once.
+func TestJohnsonCycles(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, tg := range testGraphs(rng) {
+		want := bruteForceCycles(tg.G)
+		got := make(map[string]bool)
+		for _, c := range tg.G.johnsonCycles() {
+			k := cycleKey(c)
+			if got[k] {
+				t.Fatalf("%s: cycle %s was found more than once", tg.Name, k)
+			}
+			got[k] = true
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s: found %d elementary cycles; brute force found %d", tg.Name, len(got), len(want))
+		}
+		for k := range want {
+			if !got[k] {
+				t.Fatalf("%s: missed cycle %s", tg.Name, k)
+			}
+		}
+	}
+}
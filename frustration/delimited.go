@@ -0,0 +1,106 @@
+/* This file reads edge lists in delimited text, such as CSV or TSV, so that
+data exported from spreadsheets and databases can be analyzed without
+conversion. */
+
+package frustration
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A DelimitedParser reads a graph as delimited rows of text.  A row u,v,J
+// specifies a coupler of strength J between vertices u and v, and a row v,h
+// specifies a field of strength h on vertex v.  Fields may be quoted as in
+// CSV, and rows beginning with "#" are comments.  A first row whose last
+// column is not numeric, such as "source,target,weight", is taken to be a
+// header and skipped.
+type DelimitedParser struct {
+	Delimiter rune // Character separating the columns of a row (0: ',')
+}
+
+// ParseDelimiter converts the argument of --delimiter to a single character.
+// "tab" and `\t` both denote a tab.
+func ParseDelimiter(s string) rune {
+	switch s {
+	case "tab", `\t`:
+		return '\t'
+	}
+	rs := []rune(s)
+	if len(rs) != 1 || rs[0] == '"' || rs[0] == '#' || rs[0] == '\r' || rs[0] == '\n' {
+		Abortf("A delimiter must be a single character other than a quotation mark, \"#\", or a newline, not %q", s)
+	}
+	return rs[0]
+}
+
+// Parse reads a graph in delimited form.
+func (dp DelimitedParser) Parse(r io.Reader) Graph {
+	cr := csv.NewReader(r)
+	cr.Comma = dp.Delimiter
+	if cr.Comma == 0 {
+		cr.Comma = ','
+	}
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = cr.Comma != ' ' && cr.Comma != '\t'
+	cr.ReuseRecord = true
+	nt := make(nameTable)
+	vs := make(map[string]float64)      // Map from a vertex to a weight
+	es := make(map[[2]string]float64)   // Map from an edge to a weight
+	mags := make(map[[2]string]float64) // Map from an edge to the magnitude of its terms
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			Abortf("Failed to parse delimited input (%v)", err)
+		}
+		line, _ := cr.FieldPos(0)
+		for i, f := range rec {
+			rec[i] = strings.TrimSpace(f)
+		}
+		if len(rec) != 2 && len(rec) != 3 {
+			Abortf("Row %d of delimited input has %d columns; expected 2 (vertex, field) or 3 (vertex, vertex, coupler)", line, len(rec))
+		}
+		wt, err := strconv.ParseFloat(rec[len(rec)-1], 64)
+		if err != nil {
+			if first {
+				continue // Header
+			}
+			Abortf("Row %d of delimited input has a non-numeric weight, %q", line, rec[len(rec)-1])
+		}
+		u := nt.intern(rec[0])
+		if u == "" {
+			Abortf("Row %d of delimited input has an empty vertex name", line)
+		}
+		if len(rec) == 2 {
+			// Vertex
+			vs[u] += wt
+			continue
+		}
+
+		// Edge
+		v := nt.intern(rec[1])
+		switch {
+		case v == "":
+			Abortf("Row %d of delimited input has an empty vertex name", line)
+		case u == v:
+			vs[u] += wt
+			continue
+		case u > v:
+			u, v = v, u
+		}
+		if _, dup := es[[2]string{u, v}]; dup {
+			Warn("W001-duplicate-coupler", u+" "+v, "Coupler %s %s appears more than once; its terms are summed", u, v)
+		}
+		es[[2]string{u, v}] += wt
+		mags[[2]string{u, v}] += math.Abs(wt)
+		vs[u] += 0.0
+		vs[v] += 0.0
+	}
+	return Graph{Vs: vs, Es: es, EMags: mags}
+}
@@ -0,0 +1,302 @@
+/* This file distributes an analysis over several machines without an
+external scheduler.  A coordinator reads the problem and hands each worker
+that connects to it one shard of the work, together with the graph itself, so
+workers need neither the input file nor a shared file system.  Each worker
+streams its partial results back as soon as it finishes, and the coordinator
+merges them exactly as the "merge" subcommand would. */
+
+package frustration
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"time"
+)
+
+// workMagic identifies a find-frustration coordinator to a worker.
+const workMagic = "find-frustration coordinator"
+
+// workVersion is the version of the coordinator-worker protocol that we
+// speak.
+const workVersion = 4
+
+// workerDialAttempts is the number of times, one second apart, a worker
+// tries to connect to a coordinator that may not have started yet.
+const workerDialAttempts = 60
+
+// workHeartbeat is the interval at which a busy worker assures the
+// coordinator that it is still alive.
+const workHeartbeat = 10 * time.Second
+
+// workIdleTimeout is the time after which the coordinator gives up on a
+// worker from which it has heard nothing, or which has accepted none of its
+// work order, and reassigns the worker's shard.
+const workIdleTimeout = 6 * workHeartbeat
+
+// A workHeader precedes each work order.
+type workHeader struct {
+	Magic   string // Always workMagic
+	Version int    // Protocol version
+}
+
+// A WorkOrder tells a worker which shard of which analysis to perform.  A
+// positive Samples requests a share of a cycle sample; otherwise, the worker
+// tallies its shard of the base cycles or, with AllCycles, of the elementary
+// cycles.
+type WorkOrder struct {
+	Shard          ShardSpec   // Shard to analyze
+	Provenance     Provenance  // Provenance of the coordinator's run
+	Graph          Graph       // Graph to analyze, already preprocessed
+	AllCycles      bool        // Analyze elementary cycles rather than base cycles
+	MinimumBasis   bool        // Use a minimum cycle basis as the base cycles
+	Budget         float64     // Maximum estimated number of elementary cycles
+	Force          bool        // Proceed even if the budget is exceeded
+	Combine        CombineRule // Restriction on which combinations of cycles to retain
+	MaxCycleLen    int         // If positive, analyze only elementary cycles of at most this many edges
+	Classifier     string      // Name of a registered classifier
+	TrivialRatio   float64     // Ratio for identifying trivially resolvable cycles (0: disabled)
+	ExcludeTrivial bool        // Exclude trivially resolvable cycles from the tallies
+	Samples        int         // Total number of cycles to sample (0: no sampling)
+	Weighting      string      // Edge weighting for sampling
+}
+
+// A workReply carries a worker's partial results back to the coordinator.
+// A busy worker periodically sends a heartbeat, a workReply with Alive set
+// and no results, before its final reply.
+type workReply struct {
+	Alive bool      // true if this is merely a heartbeat
+	Shard ShardFile // Partial results
+	Err   string    // Reason the worker failed ("" if it succeeded)
+}
+
+// Run performs the work an order describes.  The results' provenance is
+// the coordinator's, except that it names the worker's host.
+func (o WorkOrder) Run() ShardFile {
+	sf := ShardFile{Shard: o.Shard, Provenance: o.Provenance}
+	if host, err := os.Hostname(); err == nil {
+		sf.Provenance.Hostname = host
+	}
+	if o.Samples > 0 {
+		n, rng := o.Shard.SampleShare(o.Samples, o.Provenance.Seed)
+		t := o.Graph.TallySamples(n, o.Weighting, rng)
+		sf.Sample = &t
+		return sf
+	}
+	pl := Pipeline{
+		CycleFinder: BasisFinder{Minimum: o.MinimumBasis},
+		Classifier:  LookupClassifier(o.Classifier),
+	}
+	if o.AllCycles {
+		pl.CycleFinder = ElementaryFinder{Budget: o.Budget, Force: o.Force, Rule: o.Combine, Minimum: o.MinimumBasis, MaxLen: o.MaxCycleLen}
+	}
+	a := &Analysis{Graph: o.Graph, Rng: rand.New(rand.NewSource(o.Provenance.Seed))}
+	res := pl.TallyShard(a, o.Shard, o.AllCycles, o.TrivialRatio, o.ExcludeTrivial)
+	sf.Results = &res
+	return sf
+}
+
+// A workOutcome reports what became of one shard sent to one worker.
+type workOutcome struct {
+	Worker string    // Worker's network address
+	Shard  ShardSpec // Shard the worker was given
+	Reply  workReply // Worker's reply
+	Err    error     // Communication error (nil if the reply arrived)
+}
+
+// assignWork sends a work order to a connected worker and reports the
+// outcome once the worker replies, disconnects, or falls silent for longer
+// than workIdleTimeout.  A reply for a shard other than the one assigned is
+// reported as a communication error.
+func assignWork(conn net.Conn, order WorkOrder, done chan<- workOutcome) {
+	defer conn.Close()
+	out := workOutcome{Worker: conn.RemoteAddr().String(), Shard: order.Shard}
+	out.Err = func() error {
+		// Send the work order.
+		idle := &idleConn{Conn: conn, timeout: workIdleTimeout}
+		enc := gob.NewEncoder(idle)
+		if err := enc.Encode(workHeader{Magic: workMagic, Version: workVersion}); err != nil {
+			return err
+		}
+		if err := enc.Encode(order); err != nil {
+			return err
+		}
+
+		// Await the reply, skipping heartbeats.
+		dec := gob.NewDecoder(idle)
+		for {
+			out.Reply = workReply{}
+			if err := dec.Decode(&out.Reply); err != nil {
+				return err
+			}
+			if !out.Reply.Alive {
+				break
+			}
+		}
+		if out.Reply.Err == "" && out.Reply.Shard.Shard != order.Shard {
+			return fmt.Errorf("replied with shard %s rather than %s", out.Reply.Shard.Shard, order.Shard)
+		}
+		return nil
+	}()
+	done <- out
+}
+
+// An idleConn is a network connection on which each read or write fails if
+// it makes no progress for a given duration.
+type idleConn struct {
+	net.Conn
+	timeout time.Duration // Maximum duration of each read or write
+}
+
+// Read reads from the connection, failing if no data arrive in time.
+func (c *idleConn) Read(p []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(p)
+}
+
+// Write writes to the connection, failing if the data cannot be sent in
+// time.
+func (c *idleConn) Write(p []byte) (int, error) {
+	if err := c.Conn.SetWriteDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Write(p)
+}
+
+// Coordinate listens on a network address for workers, divides an analysis
+// into one shard per expected worker, and returns every shard's partial
+// results.  Workers may connect at any time.  A shard whose worker
+// disconnects, falls silent, or replies with the wrong shard is reassigned to
+// the next worker to connect, but a shard
+// whose worker reports an error aborts the analysis, as every worker would
+// fail the same way.
+func Coordinate(addr string, nWorkers int, order WorkOrder) []ShardFile {
+	if nWorkers < 1 {
+		Abortf("A coordinator requires at least one worker")
+	}
+	ln, err := net.Listen("tcp", addr)
+	CheckError(err)
+	defer ln.Close()
+	Notify.Printf("Waiting for %d workers on %s", nWorkers, ln.Addr())
+
+	// Accept workers in the background until every shard is done.
+	conns := make(chan net.Conn)
+	stop := make(chan Empty)
+	defer close(stop)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			select {
+			case conns <- conn:
+			case <-stop:
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	// Hand out shards to workers as they become available and collect the
+	// results.
+	pending := make([]int, nWorkers) // Shards not yet assigned
+	for i := range pending {
+		pending[i] = i
+	}
+	var idle []net.Conn // Workers without a shard
+	done := make(chan workOutcome)
+	sfs := make([]ShardFile, 0, nWorkers)
+	for len(sfs) < nWorkers {
+		for len(pending) > 0 && len(idle) > 0 {
+			o := order
+			o.Shard = ShardSpec{Index: pending[0], Count: nWorkers}
+			go assignWork(idle[0], o, done)
+			pending, idle = pending[1:], idle[1:]
+		}
+		select {
+		case conn := <-conns:
+			idle = append(idle, conn)
+		case out := <-done:
+			switch {
+			case out.Err != nil:
+				Notify.Printf("Lost worker %s (%v); reassigning shard %s", out.Worker, out.Err, out.Shard)
+				pending = append(pending, out.Shard.Index)
+			case out.Reply.Err != "":
+				Abortf("Worker %s failed on shard %s: %s", out.Worker, out.Shard, out.Reply.Err)
+			default:
+				sfs = append(sfs, out.Reply.Shard)
+				Notify.Printf("Received shard %s from %s (%d of %d done)", out.Shard, out.Worker, len(sfs), nWorkers)
+			}
+		}
+	}
+	for _, conn := range idle {
+		conn.Close()
+	}
+	return sfs
+}
+
+// RunWorker connects to a coordinator, performs the work it assigns, and
+// sends back the results, sending heartbeats while it works.  An error while
+// performing the work is reported to the coordinator as well as to the
+// caller.
+func RunWorker(addr string) {
+	// Connect to the coordinator, waiting for it to start if necessary.
+	var conn net.Conn
+	var err error
+	for attempt := 1; ; attempt++ {
+		conn, err = net.Dial("tcp", addr)
+		if err == nil || attempt == workerDialAttempts {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	CheckError(err)
+	defer conn.Close()
+
+	// Receive a work order.
+	dec := gob.NewDecoder(conn)
+	var hdr workHeader
+	if err := dec.Decode(&hdr); err != nil || hdr.Magic != workMagic {
+		Abortf("%s is not a find-frustration coordinator", addr)
+	}
+	if hdr.Version != workVersion {
+		Abortf("Coordinator protocol version %d differs from the supported version %d", hdr.Version, workVersion)
+	}
+	var order WorkOrder
+	CheckError(dec.Decode(&order))
+	Notify.Printf("Analyzing shard %s", order.Shard)
+
+	// Perform the work in the background, sending heartbeats until it
+	// completes, and report the outcome.
+	var rep workReply
+	finished := make(chan Empty)
+	go func() {
+		defer close(finished)
+		err = func() (err error) {
+			defer RecoverFatal(&err)
+			rep.Shard = order.Run()
+			return nil
+		}()
+	}()
+	enc := gob.NewEncoder(conn)
+	tick := time.NewTicker(workHeartbeat)
+	defer tick.Stop()
+	for working := true; working; {
+		select {
+		case <-tick.C:
+			CheckError(enc.Encode(workReply{Alive: true}))
+		case <-finished:
+			working = false
+		}
+	}
+	if err != nil {
+		rep.Err = err.Error()
+	}
+	CheckError(enc.Encode(rep))
+	CheckError(err)
+}
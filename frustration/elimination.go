@@ -0,0 +1,248 @@
+/* This file implements an exact solver that eliminates variables one at a
+time (bucket elimination, equivalently edge contraction or the transfer-matrix
+method).  Its cost grows exponentially in the elimination width rather than
+in the number of variables, so it solves large but sparse or quasi-one-
+dimensional problems that exhaustive search cannot. */
+
+package frustration
+
+import (
+	"math"
+	"sort"
+)
+
+// maxElimWidth is the largest elimination width (the number of neighbors a
+// variable has when it is eliminated) for which variable elimination is
+// considered feasible.
+const maxElimWidth = 20
+
+// An elimEntry is the minimum energy of a partial problem given an
+// assignment to a factor's scope and the number of assignments to the
+// eliminated variables that attain it.
+type elimEntry struct {
+	E float64 // Minimum energy
+	N uint64  // Number of minimizing assignments
+}
+
+// An elimFactor is a function of the spins of a few variables.  Bit k of an
+// index into Table is 1 if Scope[k] is +1 and 0 if it is -1.
+type elimFactor struct {
+	Scope []int       // Variable indices in increasing order
+	Table []elimEntry // One entry per assignment to Scope
+}
+
+// spinAt returns the spin that a table index assigns to the kth variable of
+// a factor's scope.
+func spinAt(idx, k int) int {
+	return 2*(idx>>uint(k)&1) - 1
+}
+
+// eliminationOrder returns a variable elimination order chosen greedily by
+// minimum degree, accounting for the fill-in each elimination creates, and
+// the resulting elimination width.  It gives up, returning nil, as soon as
+// the width exceeds maxWidth.
+func (im isingModel) eliminationOrder(maxWidth int) ([]int, int) {
+	n := len(im.Names)
+	nbrs := make([]map[int]Empty, n)
+	for i := range nbrs {
+		nbrs[i] = make(map[int]Empty, len(im.Adj[i]))
+		for _, c := range im.Adj[i] {
+			nbrs[i][c.To] = Empty{}
+		}
+	}
+	done := make([]bool, n)
+	order := make([]int, 0, n)
+	width := 0
+	for len(order) < n {
+		// Eliminate the variable with the fewest remaining neighbors.
+		best := -1
+		for i := 0; i < n; i++ {
+			if !done[i] && (best < 0 || len(nbrs[i]) < len(nbrs[best])) {
+				best = i
+			}
+		}
+		if d := len(nbrs[best]); d > width {
+			width = d
+			if width > maxWidth {
+				return nil, width
+			}
+		}
+		order = append(order, best)
+		done[best] = true
+
+		// Connect its neighbors to each other.
+		for u := range nbrs[best] {
+			delete(nbrs[u], best)
+			for v := range nbrs[best] {
+				if u != v {
+					nbrs[u][v] = Empty{}
+				}
+			}
+		}
+	}
+	return order, width
+}
+
+// combineFactors multiplies a set of factors, adding their energies and
+// multiplying their counts, into a single factor over the union of their
+// scopes.
+func combineFactors(fs []elimFactor) elimFactor {
+	// Determine the union of the scopes.
+	inScope := make(map[int]Empty)
+	for _, f := range fs {
+		for _, v := range f.Scope {
+			inScope[v] = Empty{}
+		}
+	}
+	scope := make([]int, 0, len(inScope))
+	for v := range inScope {
+		scope = append(scope, v)
+	}
+	sort.Ints(scope)
+	pos := make(map[int]int, len(scope))
+	for k, v := range scope {
+		pos[v] = k
+	}
+
+	// Sum the energies of every factor for every assignment.
+	table := make([]elimEntry, 1<<uint(len(scope)))
+	for idx := range table {
+		ent := elimEntry{N: 1}
+		for _, f := range fs {
+			fi := 0
+			for k, v := range f.Scope {
+				fi |= (idx >> uint(pos[v]) & 1) << uint(k)
+			}
+			ent.E += f.Table[fi].E
+			ent.N *= f.Table[fi].N
+		}
+		table[idx] = ent
+	}
+	return elimFactor{Scope: scope, Table: table}
+}
+
+// minimizeOut eliminates variable x from a factor, returning a factor over
+// the remaining variables and, for each entry, the spin of x that attains
+// its minimum.  Energies within tol of each other are considered equal.
+func minimizeOut(f elimFactor, x int, tol float64) (elimFactor, []int8) {
+	kx := sort.SearchInts(f.Scope, x)
+	scope := make([]int, 0, len(f.Scope)-1)
+	scope = append(scope, f.Scope[:kx]...)
+	scope = append(scope, f.Scope[kx+1:]...)
+	low := 1<<uint(kx) - 1 // Bits below x's position
+	table := make([]elimEntry, 1<<uint(len(scope)))
+	arg := make([]int8, len(table))
+	for idx := range table {
+		i0 := idx&low | (idx&^low)<<1 // x = -1
+		i1 := i0 | 1<<uint(kx)        // x = +1
+		e0, e1 := f.Table[i0], f.Table[i1]
+		switch {
+		case e0.E < e1.E-tol:
+			table[idx], arg[idx] = e0, -1
+		case e1.E < e0.E-tol:
+			table[idx], arg[idx] = e1, 1
+		default:
+			table[idx] = elimEntry{E: math.Min(e0.E, e1.E), N: e0.N + e1.N}
+			arg[idx] = -1
+			if e1.E < e0.E {
+				arg[idx] = 1
+			}
+		}
+	}
+	return elimFactor{Scope: scope, Table: table}, arg
+}
+
+// eliminate finds a minimum-energy spin assignment by eliminating variables
+// in a given order and counts the assignments that attain that energy.
+func (im isingModel) eliminate(order []int) ([]int, uint64) {
+	n := len(im.Names)
+	rank := make([]int, n) // Position of each variable in the order
+	for r, v := range order {
+		rank[v] = r
+	}
+
+	// Express the model as unary and pairwise factors.  A fixed spin is
+	// expressed as an infinite energy for the disallowed value.
+	var factors []elimFactor
+	for i, h := range im.H {
+		f := elimFactor{Scope: []int{i}, Table: []elimEntry{{E: -h, N: 1}, {E: h, N: 1}}}
+		if i == im.Fixed {
+			f.Table[0].E = math.Inf(1)
+		}
+		factors = append(factors, f)
+	}
+	for k, uv := range im.Edges {
+		u, v := uv[0], uv[1]
+		if u > v {
+			u, v = v, u
+		}
+		f := elimFactor{Scope: []int{u, v}, Table: make([]elimEntry, 4)}
+		for idx := range f.Table {
+			f.Table[idx] = elimEntry{E: im.J[k] * float64(spinAt(idx, 0)*spinAt(idx, 1)), N: 1}
+		}
+		factors = append(factors, f)
+	}
+
+	// Place each factor in the bucket of its first variable to be
+	// eliminated.
+	buckets := make([][]elimFactor, n)
+	var consts []elimFactor // Factors with empty scope
+	place := func(f elimFactor) {
+		if len(f.Scope) == 0 {
+			consts = append(consts, f)
+			return
+		}
+		first := f.Scope[0]
+		for _, v := range f.Scope[1:] {
+			if rank[v] < rank[first] {
+				first = v
+			}
+		}
+		buckets[first] = append(buckets[first], f)
+	}
+	for _, f := range factors {
+		place(f)
+	}
+
+	// Eliminate each variable in turn, remembering how to recover its
+	// optimal spin from the spins of its remaining neighbors.
+	tol := groundStateTolerance * im.maxCoefficient()
+	type choice struct {
+		Scope []int  // Variables on which the choice depends
+		Arg   []int8 // Optimal spin for each assignment to Scope
+	}
+	choices := make([]choice, n)
+	for _, x := range order {
+		f, arg := minimizeOut(combineFactors(buckets[x]), x, tol)
+		buckets[x] = nil
+		choices[x] = choice{Scope: f.Scope, Arg: arg}
+		place(f)
+	}
+	total := combineFactors(consts)
+
+	// Assign spins in the reverse order of elimination.
+	s := make([]int, n)
+	for r := n - 1; r >= 0; r-- {
+		x := order[r]
+		c := choices[x]
+		idx := 0
+		for k, v := range c.Scope {
+			if s[v] > 0 {
+				idx |= 1 << uint(k)
+			}
+		}
+		s[x] = int(c.Arg[idx])
+	}
+	return s, total.Table[0].N
+}
+
+// solveExact finds a minimum-energy spin assignment and the number of
+// assignments that attain that energy, by variable elimination if the
+// model's elimination width is small enough and by exhaustive search
+// otherwise.  It returns nil if neither method is feasible.
+func (im isingModel) solveExact() ([]int, uint64) {
+	if order, _ := im.eliminationOrder(maxElimWidth); order != nil {
+		return im.eliminate(order)
+	}
+	return im.exhaustive()
+}
@@ -0,0 +1,74 @@
+package frustration
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// bruteForceGroundStates returns the minimum energy of an Ising model and
+// the number of spin assignments that attain it, found by evaluating every
+// assignment that respects the model's fixed spin.
+func bruteForceGroundStates(im isingModel) (float64, uint64) {
+	n := len(im.Names)
+	tol := groundStateTolerance * im.maxCoefficient()
+	bestE := math.Inf(1)
+	count := uint64(0)
+	s := make([]int, n)
+	for k := 0; k < 1<<uint(n); k++ {
+		for i := range s {
+			s[i] = 2*(k>>uint(i)&1) - 1
+		}
+		if im.Fixed >= 0 && s[im.Fixed] != 1 {
+			continue
+		}
+		switch e := im.Energy(s); {
+		case e < bestE-tol:
+			bestE = e
+			count = 1
+		case e <= bestE+tol:
+			count++
+		}
+	}
+	return bestE, count
+}
+
+// TestEliminate checks that variable elimination finds a ground state and
+// counts the ground states exactly as brute force does, both with every
+// spin free and, for flip-symmetric models, with one spin fixed.
+func TestEliminate(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, tg := range testGraphs(rng) {
+		ims := []isingModel{tg.G.isingModel()}
+		if fixed, ok := ims[0].fixSpin(); ok {
+			ims = append(ims, fixed)
+		}
+		for _, im := range ims {
+			order, _ := im.eliminationOrder(maxElimWidth)
+			if order == nil {
+				t.Fatalf("%s: no elimination order of width at most %d", tg.Name, maxElimWidth)
+			}
+			wantE, wantN := bruteForceGroundStates(im)
+			tol := groundStateTolerance * im.maxCoefficient()
+			for _, method := range []string{"eliminate", "solveExact"} {
+				var s []int
+				var n uint64
+				switch method {
+				case "eliminate":
+					s, n = im.eliminate(order)
+				case "solveExact":
+					s, n = im.solveExact()
+				}
+				if im.Fixed >= 0 && s[im.Fixed] != 1 {
+					t.Fatalf("%s: %s flipped fixed spin %d", tg.Name, method, im.Fixed)
+				}
+				if e := im.Energy(s); math.Abs(e-wantE) > tol {
+					t.Fatalf("%s (fixed %d): %s found energy %g; brute force found %g", tg.Name, im.Fixed, method, e, wantE)
+				}
+				if n != wantN {
+					t.Fatalf("%s (fixed %d): %s counted %d ground states; brute force counted %d", tg.Name, im.Fixed, method, n, wantN)
+				}
+			}
+		}
+	}
+}
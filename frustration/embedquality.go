@@ -0,0 +1,118 @@
+/* This file judges how much frustration a minor embedding adds to a problem.
+Each cycle in the embedded (physical) problem projects onto a closed walk in
+the logical problem by contracting every chain to its logical variable.  A
+physical cycle is frustrated by the logical problem itself if its projection
+is frustrated; otherwise, its frustration was introduced by the embedding,
+typically by chain couplers that are too weak relative to the fields split
+across a chain's qubits.  Conversely, a physical cycle can hide the
+frustration of its projection.  Both kinds of discrepancy make the hardware
+problem differ from the logical one. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+)
+
+// An EmbeddingQuality summarizes the frustration an embedding adds to or
+// hides from a logical problem.
+type EmbeddingQuality struct {
+	Score      float64        // Fraction of physical base cycles whose frustration matches that of their projection
+	Cycles     int            // Number of physical base cycles
+	Inherited  int            // Frustrated cycles whose projection is frustrated
+	Introduced int            // Frustrated cycles whose projection is not frustrated
+	Masked     int            // Non-frustrated cycles whose projection is frustrated
+	ChainBad   map[string]int // Number of introduced or masked cycles through each chain
+	ChainFrust map[string]int // Number of inherited cycles through each chain
+}
+
+// ComputeEmbeddingQuality compares the frustration of the base cycles of an
+// embedded problem with that of their projections onto the logical problem.
+// q2v maps each qubit of the embedded problem to its logical variable.
+// Frustration is judged by sign parity, under which the frustration of a
+// closed walk is the parity of its antiferromagnetic couplings.
+func ComputeEmbeddingQuality(logical, physical Graph, q2v map[string]string, rng *rand.Rand) EmbeddingQuality {
+	eq := EmbeddingQuality{
+		ChainBad:   make(map[string]int),
+		ChainFrust: make(map[string]int),
+	}
+	_, cs, _ := physical.findCycles(false, rng)
+	ps, isFrust := physical.classifyCycles(cs, signParity)
+	eq.Cycles = len(ps)
+	for i, p := range ps {
+		// Project the cycle onto the logical problem, noting the
+		// chains it traverses.
+		afm := 0
+		chains := make(map[string]Empty)
+		for k, a := range p {
+			b := p[(k+1)%len(p)]
+			va, aOK := q2v[a]
+			vb, bOK := q2v[b]
+			switch {
+			case !aOK:
+				Abortf("Qubit %s does not appear in the embedding", a)
+			case !bOK:
+				Abortf("Qubit %s does not appear in the embedding", b)
+			case va == vb:
+				chains[va] = Empty{}
+				continue
+			}
+			e := [2]string{va, vb}
+			if va > vb {
+				e = [2]string{vb, va}
+			}
+			if _, ok := logical.Es[e]; !ok {
+				Abortf("Coupler %s %s joins logical variables %s and %s, which the logical problem does not couple", a, b, va, vb)
+			}
+			if s, _ := logical.couplingSign(va, vb); s < 0 {
+				afm++
+			}
+		}
+
+		// Compare the cycle's frustration with its projection's.
+		counts := eq.ChainBad
+		switch lf := afm%2 == 1; {
+		case isFrust[i] && lf:
+			eq.Inherited++
+			counts = eq.ChainFrust
+		case isFrust[i]:
+			eq.Introduced++
+		case lf:
+			eq.Masked++
+		default:
+			continue
+		}
+		for v := range chains {
+			counts[v]++
+		}
+	}
+	eq.Score = 1 - fraction(eq.Introduced+eq.Masked, eq.Cycles)
+	return eq
+}
+
+// OutputEmbeddingQuality outputs, for each chain that lies on a physical
+// cycle whose frustration differs from its projection's or that is
+// frustrated by the logical problem, the number of such cycles, followed by
+// summary statistics and the embedding-quality score.  nChains is the number
+// of chains in the embedding.
+func OutputEmbeddingQuality(w io.Writer, eq EmbeddingQuality, nChains int) {
+	vs := make([]string, 0, len(eq.ChainBad)+len(eq.ChainFrust))
+	for v := range eq.ChainBad {
+		vs = append(vs, v)
+	}
+	for v := range eq.ChainFrust {
+		if _, ok := eq.ChainBad[v]; !ok {
+			vs = append(vs, v)
+		}
+	}
+	sort.Strings(vs)
+	for _, v := range vs {
+		fmt.Fprintf(w, "EMBC %d %d | %s\n", eq.ChainBad[v], eq.ChainFrust[v], v)
+	}
+	fmt.Fprintf(w, "#EMBC %d / %d = %f\n", len(eq.ChainBad), nChains, fraction(len(eq.ChainBad), nChains))
+	fmt.Fprintf(w, "#EMBF %d %d %d %d\n", eq.Inherited, eq.Introduced, eq.Masked, eq.Cycles)
+	fmt.Fprintf(w, "#EMBQ %f\n", eq.Score)
+}
@@ -0,0 +1,167 @@
+/* This file reads domain problems -- max-cut and graph coloring -- and
+encodes them as QUBOs using the textbook formulations.  Analyzing the result
+shows how much frustration a formulation introduces before any solver or
+embedding is involved. */
+
+package frustration
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// readDomainLines calls a function on the whitespace-separated fields of each
+// nonblank line of a domain-problem file.  Text from a "#" to the end of a
+// line is a comment.
+func readDomainLines(r io.Reader, f func(ln string, fs []string)) {
+	sc := newLineScanner(r)
+	for sc.Scan() {
+		ln := sc.Text()
+		if i := strings.Index(ln, "#"); i >= 0 {
+			ln = ln[:i]
+		}
+		if fs := strings.Fields(ln); len(fs) > 0 {
+			f(strings.TrimSpace(ln), fs)
+		}
+	}
+	CheckError(sc.Err())
+}
+
+// ReadMaxCutFile reads a max-cut instance, one "u v" or "u v w" line per
+// edge, with w defaulting to 1, and returns the Ising Hamiltonian of the
+// QUBO that minimizes the negated weight of the cut:
+//
+//	min Σ_uv w_uv (2 x_u x_v − x_u − x_v)
+//
+// Each edge becomes an antiferromagnetic coupler of strength w/2.
+func ReadMaxCutFile(r io.Reader) Graph {
+	vs := make(map[string]float64)    // Map from a vertex to a QUBO weight
+	es := make(map[[2]string]float64) // Map from an edge to a QUBO weight
+	readDomainLines(r, func(ln string, fs []string) {
+		if len(fs) != 2 && len(fs) != 3 {
+			Abortf("Failed to parse max-cut line %q", ln)
+		}
+		u, v := fs[0], fs[1]
+		if u == v {
+			Abortf("Max-cut edge %q is a self-loop", ln)
+		}
+		wt := 1.0
+		if len(fs) == 3 {
+			var err error
+			wt, err = strconv.ParseFloat(fs[2], 64)
+			CheckError(err)
+		}
+		if u > v {
+			u, v = v, u
+		}
+		es[[2]string{u, v}] += 2 * wt
+		vs[u] -= wt
+		vs[v] -= wt
+	})
+	qvs, qes := copyQUBO(vs, es)
+	off := quboToIsing(vs, es)
+	return Graph{Vs: vs, Es: es, Offset: off, QVs: qvs, QEs: qes}
+}
+
+// A ColoringParser reads a graph-coloring instance in DIMACS format ("p edge
+// n m" followed by one "e u v" line per edge) and returns the Ising
+// Hamiltonian of the one-hot QUBO that asks for a proper coloring with a
+// given number of colors.  Boolean variable "v.c" says that vertex v has
+// color c.  The QUBO is
+//
+//	Σ_v (1 − Σ_c x_v.c)² + Σ_uv Σ_c x_u.c x_v.c
+//
+// whose minimum is 0 exactly when a proper coloring exists.  Couplers within
+// a vertex's one-hot encoding are labeled as encoding edges and couplers
+// between neighbors as logical edges.
+type ColoringParser struct {
+	Colors int // Number of colors (0: one more than the maximum degree)
+}
+
+// Parse reads a graph-coloring instance.
+func (cp ColoringParser) Parse(r io.Reader) Graph {
+	// Read the instance.
+	var names []string             // Vertex names in order of appearance
+	seen := make(map[string]Empty) // Set of vertex names
+	deg := make(map[string]int)    // Degree of each vertex
+	var edges [][2]string          // Edges in order of appearance
+	addVertex := func(v string) {
+		if _, ok := seen[v]; !ok {
+			seen[v] = Empty{}
+			names = append(names, v)
+		}
+	}
+	readDomainLines(r, func(ln string, fs []string) {
+		switch {
+		case fs[0] == "c":
+			// Comment
+		case fs[0] == "p" && len(fs) == 4:
+			n, err := strconv.Atoi(fs[2])
+			CheckError(err)
+			for v := 1; v <= n; v++ {
+				addVertex(strconv.Itoa(v))
+			}
+		case fs[0] == "e" && len(fs) == 3:
+			u, v := fs[1], fs[2]
+			if u == v {
+				Abortf("Coloring edge %q is a self-loop", ln)
+			}
+			addVertex(u)
+			addVertex(v)
+			deg[u]++
+			deg[v]++
+			edges = append(edges, [2]string{u, v})
+		default:
+			Abortf("Failed to parse coloring line %q", ln)
+		}
+	})
+	k := cp.Colors
+	if k == 0 {
+		for _, d := range deg {
+			if d+1 > k {
+				k = d + 1
+			}
+		}
+		if k == 0 {
+			k = 1
+		}
+	}
+	if k < 1 {
+		Abortf("Graph coloring requires at least one color")
+	}
+
+	// Encode each vertex's color in one-hot form.
+	vs := make(map[string]float64)    // Map from a vertex to a QUBO weight
+	es := make(map[[2]string]float64) // Map from an edge to a QUBO weight
+	kinds := make(map[[2]string]string)
+	name := func(v string, c int) string { return v + "." + strconv.Itoa(c) }
+	addEdge := func(u, v string, wt float64, kind string) {
+		if u > v {
+			u, v = v, u
+		}
+		es[[2]string{u, v}] += wt
+		kinds[[2]string{u, v}] = kind
+	}
+	for _, v := range names {
+		for c := 0; c < k; c++ {
+			vs[name(v, c)] -= 1
+			for c2 := c + 1; c2 < k; c2++ {
+				addEdge(name(v, c), name(v, c2), 2, EdgeEncoding)
+			}
+		}
+	}
+
+	// Penalize neighbors that share a color.
+	for _, e := range edges {
+		for c := 0; c < k; c++ {
+			addEdge(name(e[0], c), name(e[1], c), 1, EdgeLogical)
+		}
+	}
+
+	// Convert to Ising form.  The constant term of each one-hot penalty
+	// belongs in the offset.
+	qvs, qes := copyQUBO(vs, es)
+	off := quboToIsing(vs, es) + float64(len(names))
+	return Graph{Vs: vs, Es: es, EKind: kinds, Offset: off, QVs: qvs, QEs: qes}
+}
@@ -0,0 +1,181 @@
+/* This file provides exact rational arithmetic for a problem's weights.
+Coefficients are parsed from their decimal text, summed, and converted from
+QUBO to Ising form without rounding, and the sign of each coupling is then
+determined from the exact values.  Floating-point approximations of the exact
+weights are retained for everything else. */
+
+package frustration
+
+import (
+	"io"
+	"math/big"
+)
+
+// ExactWeights represents a graph's weights as exact rational numbers.
+type ExactWeights struct {
+	Vs map[string]*big.Rat    // Map from a vertex to a weight
+	Es map[[2]string]*big.Rat // Map from an edge to a weight
+}
+
+// exactParsers maps each input format that supports exact arithmetic to a
+// function that parses it exactly.
+var exactParsers = map[string]func(r io.Reader) Graph{
+	"qubist": ReadQubistFileExact,
+	"qubo":   ReadQUBOFileExact,
+}
+
+// LookupExactParser returns a Parser that reads the named input format with
+// exact arithmetic.
+func LookupExactParser(inFmt string) Parser {
+	p, ok := exactParsers[inFmt]
+	if !ok {
+		Abortf("Exact arithmetic is supported only for the qubist and qubo formats, not %q", inFmt)
+	}
+	return ParserFunc(p)
+}
+
+// parseRat parses a decimal or rational coefficient exactly.
+func parseRat(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		Abortf("Failed to parse %q as an exact number", s)
+	}
+	return r
+}
+
+// addRat adds a value to the rational number a map associates with a key,
+// treating a missing entry as zero.
+func addRat(m map[string]*big.Rat, k string, x *big.Rat) {
+	if m[k] == nil {
+		m[k] = new(big.Rat)
+	}
+	m[k].Add(m[k], x)
+}
+
+// newExactWeights returns an empty set of exact weights.
+func newExactWeights() *ExactWeights {
+	return &ExactWeights{
+		Vs: make(map[string]*big.Rat),
+		Es: make(map[[2]string]*big.Rat),
+	}
+}
+
+// addTerm adds a term read from an input file to a set of exact weights and
+// the total magnitude of the terms summed into each edge.
+func (xw *ExactWeights) addTerm(u, v, s string, mags map[[2]string]*big.Rat) {
+	wt := parseRat(s)
+	if u == v {
+		addRat(xw.Vs, u, wt)
+		return
+	}
+	if u > v {
+		u, v = v, u
+	}
+	e := [2]string{u, v}
+	if xw.Es[e] == nil {
+		xw.Es[e] = new(big.Rat)
+		mags[e] = new(big.Rat)
+	}
+	xw.Es[e].Add(xw.Es[e], wt)
+	mags[e].Add(mags[e], new(big.Rat).Abs(wt))
+	addRat(xw.Vs, u, new(big.Rat))
+	addRat(xw.Vs, v, new(big.Rat))
+}
+
+// graph returns a graph with the given exact weights, approximated in
+// floating point, and the given magnitudes of each edge's terms.
+func (xw *ExactWeights) graph(mags map[[2]string]*big.Rat) Graph {
+	g := Graph{
+		Vs:    make(map[string]float64, len(xw.Vs)),
+		Es:    make(map[[2]string]float64, len(xw.Es)),
+		EMags: make(map[[2]string]float64, len(mags)),
+		Exact: xw,
+	}
+	for v, wt := range xw.Vs {
+		g.Vs[v], _ = wt.Float64()
+	}
+	for e, wt := range xw.Es {
+		g.Es[e], _ = wt.Float64()
+	}
+	for e, m := range mags {
+		g.EMags[e], _ = m.Float64()
+	}
+	return g
+}
+
+// ReadQubistFileExact returns the Ising Hamiltonian represented by a Qubist
+// source file, computed with exact arithmetic.
+func ReadQubistFileExact(r io.Reader) Graph {
+	xw := newExactWeights()
+	mags := make(map[[2]string]*big.Rat)
+	nt := make(nameTable)
+	scanQubistTerms(r, func(u, v, s string) { xw.addTerm(nt.intern(u), nt.intern(v), s, mags) })
+	return xw.graph(mags)
+}
+
+// ReadQUBOFileExact returns the Ising Hamiltonian represented by a QUBO
+// source file, computed with exact arithmetic.
+func ReadQUBOFileExact(r io.Reader) Graph {
+	// Read the QUBO coefficients.
+	q := newExactWeights()
+	mags := make(map[[2]string]*big.Rat)
+	nt := make(nameTable)
+	scanQUBOTerms(r, func(u, v, s string) { q.addTerm(nt.intern(u), nt.intern(v), s, mags) })
+
+	// Convert from a QUBO problem to an Ising problem as in quboToIsing.
+	half, quarter := big.NewRat(1, 2), big.NewRat(1, 4)
+	xw := newExactWeights()
+	off := new(big.Rat)
+	for v, wt := range q.Vs {
+		h := new(big.Rat).Mul(wt, half)
+		addRat(xw.Vs, v, h)
+		off.Add(off, h)
+	}
+	for e, wt := range q.Es {
+		j := new(big.Rat).Mul(wt, quarter)
+		xw.Es[e] = j
+		addRat(xw.Vs, e[0], j)
+		addRat(xw.Vs, e[1], j)
+		off.Add(off, j)
+		mags[e].Mul(mags[e], quarter)
+	}
+
+	// Return the Ising problem, retaining the original coefficients.
+	g := xw.graph(mags)
+	g.Offset, _ = off.Float64()
+	qg := q.graph(nil)
+	g.QVs, g.QEs = qg.Vs, qg.Es
+	return g
+}
+
+// couplingSign is the exact counterpart of Graph.couplingSign.
+func (xw *ExactWeights) couplingSign(u, v string) (int, bool) {
+	if u > v {
+		u, v = v, u
+	}
+	zero := new(big.Rat)
+	rat := func(x *big.Rat) *big.Rat {
+		if x == nil {
+			return zero
+		}
+		return x
+	}
+	cs := rat(xw.Es[[2]string{u, v}])
+	ef := [2]*big.Rat{rat(xw.Vs[u]), rat(xw.Vs[v])}
+
+	// If both external fields are stronger than the coupler strength,
+	// they determine the sign of the coupling.
+	abs := func(x *big.Rat) *big.Rat { return new(big.Rat).Abs(x) }
+	if abs(ef[0]).Cmp(abs(cs)) > 0 && abs(ef[1]).Cmp(abs(cs)) > 0 {
+		if ef[0].Sign()*ef[1].Sign() < 0 {
+			return -1, true
+		}
+		return 1, true
+	}
+
+	// Otherwise, the coupler strength determines the sign.
+	if cs.Sign() > 0 {
+		return -1, false
+	}
+	return 1, false
+}
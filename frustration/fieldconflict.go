@@ -0,0 +1,57 @@
+/* This file screens for frustration at the level of individual vertices:
+a vertex whose external field pushes its spin one way while its neighbors,
+each following its own field, push it the other way.  Such conflicts involve
+no cycle, so the cycle analysis cannot see them. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// fieldSign returns the spin that a vertex's field alone favors: +1 for a
+// negative field, -1 for a positive field, and 0 for no field.
+func fieldSign(h float64) int {
+	switch {
+	case h < 0:
+		return 1
+	case h > 0:
+		return -1
+	}
+	return 0
+}
+
+// neighborPressure returns, for each vertex, the effective field Σ_j J_ij s_j
+// exerted by its couplers when every neighbor j takes the spin s_j that its
+// own field favors.  Neighbors with no field exert no pressure.
+func (g Graph) neighborPressure() map[string]float64 {
+	pr := make(map[string]float64, len(g.Vs))
+	for e, wt := range g.Es {
+		u, v := e[0], e[1]
+		pr[u] += wt * float64(fieldSign(g.Vs[v]))
+		pr[v] += wt * float64(fieldSign(g.Vs[u]))
+	}
+	return pr
+}
+
+// OutputFieldConflicts outputs each vertex whose field favors one spin while
+// the pressure from its neighbors favors the other, followed by the number of
+// such vertices as a fraction of all vertices.  The ratio reported for each
+// vertex is the smaller of the two opposing magnitudes divided by the larger,
+// so values near 1 indicate evenly matched, hard-to-resolve conflicts.
+func OutputFieldConflicts(w io.Writer, g Graph) {
+	pr := g.neighborPressure()
+	n := 0
+	for _, v := range g.sortedVertices() {
+		h, p := g.Vs[v], pr[v]
+		if h*p >= 0 {
+			continue // No conflict
+		}
+		ratio := math.Min(math.Abs(h), math.Abs(p)) / math.Max(math.Abs(h), math.Abs(p))
+		fmt.Fprintf(w, "FLD  %v %v %f | %s\n", h, p, ratio, v)
+		n++
+	}
+	fmt.Fprintf(w, "#FLD %d / %d = %f\n", n, len(g.Vs), fraction(n, len(g.Vs)))
+}
@@ -0,0 +1,166 @@
+/* This file computes a fingerprint of a graph's frustrated substructure that
+does not depend on vertex names, so that differently labeled instances with
+identical frustration structure can be recognized as duplicates. */
+
+package frustration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// frustratedEdges returns, for each edge of a signed graph, whether the edge
+// lies in some frustrated cycle.  An edge does so exactly when the
+// biconnected component containing it is unbalanced, so the result does not
+// depend on which cycles were analyzed.
+func (sg signedGraph) frustratedEdges() []bool {
+	// Index each edge by its endpoints.
+	eIdx := make(map[[2]int]int, len(sg.Edges))
+	for i, e := range sg.Edges {
+		eIdx[e] = i
+	}
+	edgeIndex := func(u, v int) int {
+		if u > v {
+			u, v = v, u
+		}
+		return eIdx[[2]int{u, v}]
+	}
+
+	// Partition the edges into biconnected components (Hopcroft-Tarjan).
+	n := len(sg.Names)
+	disc := make([]int, n) // Discovery time of each vertex, 1-based
+	low := make([]int, n)  // Earliest discovery time reachable from each vertex's subtree
+	var stack []int        // Edges of the components under construction
+	var blocks [][]int     // Edges of each completed component
+	clock := 0
+	var visit func(u, parent int)
+	visit = func(u, parent int) {
+		clock++
+		disc[u], low[u] = clock, clock
+		for _, a := range sg.Adj[u] {
+			v := a.To
+			switch {
+			case disc[v] == 0:
+				stack = append(stack, edgeIndex(u, v))
+				visit(v, u)
+				if low[v] < low[u] {
+					low[u] = low[v]
+				}
+				if low[v] >= disc[u] {
+					// The edges pushed since uv form a
+					// biconnected component.
+					k := edgeIndex(u, v)
+					var blk []int
+					for {
+						top := stack[len(stack)-1]
+						stack = stack[:len(stack)-1]
+						blk = append(blk, top)
+						if top == k {
+							break
+						}
+					}
+					blocks = append(blocks, blk)
+				}
+			case v != parent && disc[v] < disc[u]:
+				stack = append(stack, edgeIndex(u, v))
+				if disc[v] < low[u] {
+					low[u] = disc[v]
+				}
+			}
+		}
+	}
+	for u := 0; u < n; u++ {
+		if disc[u] == 0 {
+			visit(u, -1)
+		}
+	}
+
+	// Mark the edges of every unbalanced component, i.e., every component
+	// whose vertices cannot be switched to make all of its edges positive.
+	frust := make([]bool, len(sg.Edges))
+	for _, blk := range blocks {
+		adj := make(map[int][]signedArc)
+		for _, k := range blk {
+			u, v := sg.Edges[k][0], sg.Edges[k][1]
+			adj[u] = append(adj[u], signedArc{To: v, Sign: sg.Signs[k]})
+			adj[v] = append(adj[v], signedArc{To: u, Sign: sg.Signs[k]})
+		}
+		sw := make(map[int]int, len(adj))
+		root := sg.Edges[blk[0]][0]
+		sw[root] = 1
+		queue := []int{root}
+		balanced := true
+		for len(queue) > 0 && balanced {
+			u := queue[0]
+			queue = queue[1:]
+			for _, a := range adj[u] {
+				s, ok := sw[a.To]
+				switch {
+				case !ok:
+					sw[a.To] = sw[u] * a.Sign
+					queue = append(queue, a.To)
+				case s != sw[u]*a.Sign:
+					balanced = false
+				}
+			}
+		}
+		if !balanced {
+			for _, k := range blk {
+				frust[k] = true
+			}
+		}
+	}
+	return frust
+}
+
+// Fingerprint returns a hash of the graph's frustrated substructure: the
+// signed subgraph formed by the edges that lie in some frustrated cycle.
+// The hash accumulates every round of Weisfeiler-Lehman color refinement of
+// that subgraph, so isomorphic substructures always hash equally regardless
+// of vertex names.  (Non-isomorphic substructures that color refinement
+// cannot distinguish also hash equally, but such collisions are rare in
+// practice.)  It also returns the number of vertices and edges in the
+// substructure.
+func (g Graph) Fingerprint() (string, int, int) {
+	// Extract the frustrated substructure, retaining only edge signs.
+	sg := g.signedGraph()
+	fg := Graph{
+		Vs: make(map[string]float64),
+		Es: make(map[[2]string]float64),
+	}
+	for k, f := range sg.frustratedEdges() {
+		if !f {
+			continue
+		}
+		u, v := sg.Names[sg.Edges[k][0]], sg.Names[sg.Edges[k][1]]
+		fg.Vs[u] = 0
+		fg.Vs[v] = 0
+		fg.Es[[2]string{u, v}] = float64(sg.Signs[k])
+	}
+
+	// Hash the sorted multiset of vertex signatures from each round of
+	// color refinement.
+	h := sha256.New()
+	fmt.Fprintf(h, "%d %d\n", len(fg.Vs), len(fg.Es))
+	fg.refineColorsVisit(func(sigs map[string]string) {
+		ss := make([]string, 0, len(sigs))
+		for _, s := range sigs {
+			ss = append(ss, s)
+		}
+		sort.Strings(ss)
+		fmt.Fprintf(h, "%s\n", strings.Join(ss, " "))
+	})
+	return hex.EncodeToString(h.Sum(nil)), len(fg.Vs), len(fg.Es)
+}
+
+// OutputFingerprint outputs the fingerprint of a graph's frustrated
+// substructure and the number of vertices and edges that substructure
+// contains.
+func OutputFingerprint(w io.Writer, g Graph) {
+	fp, nv, ne := g.Fingerprint()
+	fmt.Fprintf(w, "#FPRINT %s %d %d\n", fp, nv, ne)
+}
@@ -0,0 +1,450 @@
+/* This file computes a graph's exact frustration index by a parallel
+branch-and-bound search over vertex switchings. */
+
+package frustration
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// boundReportInterval is the interval at which the branch-and-bound search
+// reports its progress.
+const boundReportInterval = 10 * time.Second
+
+// A bbNode is a node in the branch-and-bound search tree: a switching of the
+// first Depth vertices in search order.
+type bbNode struct {
+	Sw    []int8 // Switching of each vertex (0 if not yet assigned)
+	Depth int    // Number of vertices assigned
+	Neg   int    // Number of negative edges between assigned vertices
+	Bound int    // Lower bound on the number of negative edges in any completion
+}
+
+// A bbDeque is a double-ended queue of search nodes.  Its owner pushes and
+// pops nodes at the bottom, exploring depth-first, while idle workers steal
+// nodes from the top, which represent the largest unexplored subtrees.
+type bbDeque struct {
+	sync.Mutex
+	Nodes []bbNode
+}
+
+// An exactSearch holds the state shared by all branch-and-bound workers.
+type exactSearch struct {
+	sg      signedGraph
+	order   []int         // Order in which to assign vertices
+	isRoot  []bool        // Whether order[i] is the first vertex of its component
+	seed    int64         // Number of negative edges in the heuristic seed
+	floor   int           // Gauge-invariant lower bound on the frustration index
+	best    int64         // Number of negative edges in the incumbent (atomic)
+	bestSw  []int8        // Incumbent switching
+	bestMu  sync.Mutex    // Protects bestSw
+	deques  []*bbDeque    // One deque per worker
+	current []int64       // Bound of the node each worker is expanding (atomic)
+	pending int64         // Number of nodes created but not yet expanded (atomic)
+	nodes   int64         // Number of nodes expanded (atomic)
+	done    chan struct{} // Closed when the search completes
+}
+
+// searchOrder returns the vertices in breadth-first order from the
+// highest-degree vertex of each component so that each vertex after the
+// first in its component is adjacent to an earlier vertex.  It additionally
+// says which vertices begin a component.
+func (sg signedGraph) searchOrder() ([]int, []bool) {
+	nv := len(sg.Names)
+	byDeg := make([]int, nv)
+	for v := range byDeg {
+		byDeg[v] = v
+	}
+	sort.SliceStable(byDeg, func(i, j int) bool {
+		return len(sg.Adj[byDeg[i]]) > len(sg.Adj[byDeg[j]])
+	})
+	seen := make([]bool, nv)
+	order := make([]int, 0, nv)
+	isRoot := make([]bool, 0, nv)
+	for _, r := range byDeg {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		queue := []int{r}
+		for len(queue) > 0 {
+			u := queue[0]
+			queue = queue[1:]
+			order = append(order, u)
+			isRoot = append(isRoot, u == r)
+			for _, a := range sg.Adj[u] {
+				if !seen[a.To] {
+					seen[a.To] = true
+					queue = append(queue, a.To)
+				}
+			}
+		}
+	}
+	return order, isRoot
+}
+
+// cyclePackingBound returns a lower bound on a graph's frustration index
+// that holds in every gauge: the number of edge-disjoint frustrated cycles
+// found by greedily packing the fundamental cycles of a breadth-first
+// spanning forest, shortest first.  Each such cycle contains at least one
+// negative edge under every switching.
+func (sg signedGraph) cyclePackingBound() int {
+	// Construct a breadth-first spanning forest, recording each vertex's
+	// parent edge, depth, and sign product along the path from its root.
+	nv := len(sg.Names)
+	edgeIdx := make(map[[2]int]int, len(sg.Edges))
+	for i, e := range sg.Edges {
+		edgeIdx[e] = i
+		edgeIdx[[2]int{e[1], e[0]}] = i
+	}
+	parent := make([]int, nv)
+	pEdge := make([]int, nv)
+	depth := make([]int, nv)
+	sign := make([]int, nv)
+	inTree := make([]bool, len(sg.Edges))
+	for v := range parent {
+		parent[v] = -1
+	}
+	for r := 0; r < nv; r++ {
+		if sign[r] != 0 {
+			continue
+		}
+		sign[r] = 1
+		queue := []int{r}
+		for len(queue) > 0 {
+			u := queue[0]
+			queue = queue[1:]
+			for _, a := range sg.Adj[u] {
+				if sign[a.To] != 0 {
+					continue
+				}
+				i := edgeIdx[[2]int{u, a.To}]
+				parent[a.To], pEdge[a.To], depth[a.To] = u, i, depth[u]+1
+				sign[a.To] = sign[u] * a.Sign
+				inTree[i] = true
+				queue = append(queue, a.To)
+			}
+		}
+	}
+
+	// Each non-tree edge whose sign disagrees with the sign product of the
+	// tree path between its endpoints closes a frustrated cycle.
+	var cycles [][]int
+	for i, e := range sg.Edges {
+		u, v := e[0], e[1]
+		if inTree[i] || sg.Signs[i]*sign[u]*sign[v] > 0 {
+			continue
+		}
+		c := []int{i}
+		for u != v {
+			if depth[u] < depth[v] {
+				u, v = v, u
+			}
+			c = append(c, pEdge[u])
+			u = parent[u]
+		}
+		cycles = append(cycles, c)
+	}
+	sort.SliceStable(cycles, func(i, j int) bool { return len(cycles[i]) < len(cycles[j]) })
+
+	// Greedily pack edge-disjoint cycles.
+	used := make([]bool, len(sg.Edges))
+	n := 0
+	for _, c := range cycles {
+		free := true
+		for _, i := range c {
+			if used[i] {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+		for _, i := range c {
+			used[i] = true
+		}
+		n++
+	}
+	return n
+}
+
+// lowerBound returns a lower bound on the number of negative edges in any
+// completion of a partial switching: the negative edges among assigned
+// vertices plus, for each unassigned vertex, the smaller number of negative
+// edges to assigned neighbors it would incur under either assignment.
+func (es *exactSearch) lowerBound(sw []int8, neg int) int {
+	lb := neg
+	for v, x := range sw {
+		if x != 0 {
+			continue
+		}
+		pos, nneg := 0, 0 // Negative edges if v is assigned +1 or -1
+		for _, a := range es.sg.Adj[v] {
+			switch int(sw[a.To]) * a.Sign {
+			case 1:
+				nneg++
+			case -1:
+				pos++
+			}
+		}
+		if pos < nneg {
+			lb += pos
+		} else {
+			lb += nneg
+		}
+	}
+	return lb
+}
+
+// expand processes a single search node, either updating the incumbent or
+// pushing the node's children onto the given deque.
+func (es *exactSearch) expand(n bbNode, dq *bbDeque) {
+	atomic.AddInt64(&es.nodes, 1)
+	best := atomic.LoadInt64(&es.best)
+	if int64(n.Bound) >= best || best <= int64(es.floor) {
+		return // Pruned
+	}
+	if n.Depth == len(es.order) {
+		// Complete switching: update the incumbent if it's better.
+		es.bestMu.Lock()
+		if int64(n.Neg) < atomic.LoadInt64(&es.best) {
+			atomic.StoreInt64(&es.best, int64(n.Neg))
+			es.bestSw = n.Sw
+		}
+		es.bestMu.Unlock()
+		return
+	}
+
+	// Construct each child, retaining only those not yet pruned.
+	v := es.order[n.Depth]
+	xs := []int8{1, -1}
+	if es.isRoot[n.Depth] {
+		xs = xs[:1] // Switching an entire component is a no-op.
+	}
+	children := make([]bbNode, 0, 2)
+	for _, x := range xs {
+		sw := make([]int8, len(n.Sw))
+		copy(sw, n.Sw)
+		sw[v] = x
+		neg := n.Neg
+		for _, a := range es.sg.Adj[v] {
+			if int(sw[a.To])*a.Sign*int(x) < 0 {
+				neg++
+			}
+		}
+		c := bbNode{Sw: sw, Depth: n.Depth + 1, Neg: neg, Bound: es.lowerBound(sw, neg)}
+		if int64(c.Bound) < atomic.LoadInt64(&es.best) {
+			children = append(children, c)
+		}
+	}
+
+	// Push the less promising child first so the more promising child is
+	// explored first.
+	if len(children) == 2 && children[0].Bound < children[1].Bound {
+		children[0], children[1] = children[1], children[0]
+	}
+	atomic.AddInt64(&es.pending, int64(len(children)))
+	dq.Lock()
+	dq.Nodes = append(dq.Nodes, children...)
+	dq.Unlock()
+}
+
+// work repeatedly expands nodes from worker w's deque, stealing from other
+// workers' deques when its own is empty, until no nodes remain.
+func (es *exactSearch) work(w int, rng *rand.Rand) {
+	dq := es.deques[w]
+	for {
+		// Pop a node from the bottom of our own deque.
+		var n bbNode
+		ok := false
+		dq.Lock()
+		if k := len(dq.Nodes); k > 0 {
+			n, ok = dq.Nodes[k-1], true
+			dq.Nodes = dq.Nodes[:k-1]
+			atomic.StoreInt64(&es.current[w], int64(n.Bound))
+		}
+		dq.Unlock()
+
+		// Otherwise, steal a node from the top of another deque.
+		for i, off := 0, rng.Intn(len(es.deques)); !ok && i < len(es.deques); i++ {
+			vq := es.deques[(off+i)%len(es.deques)]
+			vq.Lock()
+			if len(vq.Nodes) > 0 {
+				n, ok = vq.Nodes[0], true
+				vq.Nodes = vq.Nodes[1:]
+				atomic.StoreInt64(&es.current[w], int64(n.Bound))
+			}
+			vq.Unlock()
+		}
+
+		// Expand the node or, if there was none, check for completion.
+		if !ok {
+			if atomic.LoadInt64(&es.pending) == 0 {
+				return
+			}
+			runtime.Gosched()
+			continue
+		}
+		es.expand(n, dq)
+		atomic.StoreInt64(&es.current[w], math.MaxInt64)
+		atomic.AddInt64(&es.pending, -1)
+	}
+}
+
+// globalBound returns a lower bound on the frustration index given the
+// search's current state: the smallest bound of any unexpanded node or of
+// any node being expanded, or the incumbent if that is smaller, but no less
+// than the cycle-packing bound.
+func (es *exactSearch) globalBound() int64 {
+	for _, dq := range es.deques {
+		dq.Lock()
+	}
+	lb := atomic.LoadInt64(&es.best)
+	for w, dq := range es.deques {
+		for _, n := range dq.Nodes {
+			if int64(n.Bound) < lb {
+				lb = int64(n.Bound)
+			}
+		}
+		if c := atomic.LoadInt64(&es.current[w]); c < lb {
+			lb = c
+		}
+	}
+	for _, dq := range es.deques {
+		dq.Unlock()
+	}
+	if lb < int64(es.floor) {
+		lb = int64(es.floor)
+	}
+	return lb
+}
+
+// reportProgress periodically reports the incumbent and the global lower
+// bound until the search completes.
+func (es *exactSearch) reportProgress() {
+	tick := time.NewTicker(boundReportInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-es.done:
+			return
+		case <-tick.C:
+			ub := atomic.LoadInt64(&es.best)
+			if es.seed < ub {
+				ub = es.seed
+			}
+			lb := es.globalBound()
+			if lb > ub {
+				lb = ub
+			}
+			Notify.Printf("Frustration index: %d <= index <= %d after %d nodes",
+				lb, ub, atomic.LoadInt64(&es.nodes))
+		}
+	}
+}
+
+// frustrationIndexExact computes the exact frustration index of a graph and
+// a switching that attains it (-1 for a switched vertex, +1 otherwise).  The
+// search is seeded with the best of many random-restart local searches and
+// then proceeds by branch and bound on one goroutine per CPU, with idle
+// goroutines stealing work from busy ones.  A gauge-invariant cycle-packing
+// bound ends the search as soon as the incumbent attains it.
+func (sg signedGraph) frustrationIndexExact(rng *rand.Rand) (int, []int) {
+	// Seed the incumbent with random-restart local search performed in
+	// parallel.
+	nw := runtime.NumCPU()
+	type seed struct {
+		Neg int
+		Sw  []int
+	}
+	seeds := make([]seed, nw)
+	var wg sync.WaitGroup
+	for w := range seeds {
+		wg.Add(1)
+		go func(w int, r *rand.Rand) {
+			defer wg.Done()
+			neg, sw := sg.frustrationIndexHeuristic(DefaultRestarts/nw+1, r)
+			seeds[w] = seed{neg, sw}
+		}(w, rand.New(rand.NewSource(rng.Int63())))
+	}
+	wg.Wait()
+	best := seeds[0]
+	for _, s := range seeds[1:] {
+		if s.Neg < best.Neg {
+			best = s
+		}
+	}
+	floor := sg.cyclePackingBound()
+	if best.Neg <= floor {
+		return best.Neg, best.Sw // The heuristic is already optimal.
+	}
+	return sg.branchAndBound(best.Neg, floor, rng)
+}
+
+// branchAndBound computes the exact frustration index of a graph and a
+// switching that attains it by branch and bound on one goroutine per CPU.
+// seed is the number of negative edges under some known switching, and the
+// search stops early if it finds a switching with only floor negative edges.
+func (sg signedGraph) branchAndBound(seed, floor int, rng *rand.Rand) (int, []int) {
+	// Prepare the shared search state.  The incumbent is one more than
+	// the seed so that the search rediscovers a switching at least that
+	// good.
+	nw := runtime.NumCPU()
+	order, isRoot := sg.searchOrder()
+	es := &exactSearch{
+		sg:      sg,
+		order:   order,
+		isRoot:  isRoot,
+		seed:    int64(seed),
+		floor:   floor,
+		best:    int64(seed) + 1,
+		deques:  make([]*bbDeque, nw),
+		current: make([]int64, nw),
+		pending: 1,
+		done:    make(chan struct{}),
+	}
+	for w := range es.deques {
+		es.deques[w] = &bbDeque{}
+		es.current[w] = math.MaxInt64
+	}
+	root := bbNode{Sw: make([]int8, len(sg.Names))}
+	root.Bound = es.lowerBound(root.Sw, 0)
+	es.deques[0].Nodes = []bbNode{root}
+
+	// Search in parallel.
+	go es.reportProgress()
+	var wg sync.WaitGroup
+	for w := 0; w < nw; w++ {
+		wg.Add(1)
+		go func(w int, r *rand.Rand) {
+			defer wg.Done()
+			es.work(w, r)
+		}(w, rand.New(rand.NewSource(rng.Int63())))
+	}
+	wg.Wait()
+	close(es.done)
+
+	// Convert the optimal switching to the same form as
+	// frustrationIndexHeuristic returns.
+	sw := make([]int, len(es.bestSw))
+	nsw := 0
+	for v, x := range es.bestSw {
+		sw[v] = int(x)
+		if x < 0 {
+			nsw++
+		}
+	}
+	if 2*nsw > len(sw) {
+		for v := range sw {
+			sw[v] = -sw[v]
+		}
+	}
+	return int(es.best), sw
+}
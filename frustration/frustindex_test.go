@@ -0,0 +1,57 @@
+package frustration
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// bruteForceFrustrationIndex returns the smallest number of negative edges
+// under any switching of a signed graph, found by trying every switching.
+func bruteForceFrustrationIndex(sg signedGraph) int {
+	best := len(sg.Edges)
+	for k := 0; k < 1<<uint(len(sg.Names)); k++ {
+		neg := 0
+		for i, e := range sg.Edges {
+			su := 1 - 2*(k>>uint(e[0])&1)
+			sv := 1 - 2*(k>>uint(e[1])&1)
+			if sg.Signs[i]*su*sv < 0 {
+				neg++
+			}
+		}
+		if neg < best {
+			best = neg
+		}
+	}
+	return best
+}
+
+// TestFrustrationIndexExact checks the exact frustration index against
+// brute force, both as computed in full and as computed by branch and bound
+// alone from a trivial seed, without the heuristic incumbent or the
+// cycle-packing bound.
+func TestFrustrationIndexExact(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, tg := range testGraphs(rng) {
+		sg := tg.G.signedGraph()
+		want := bruteForceFrustrationIndex(sg)
+		if floor := sg.cyclePackingBound(); floor > want {
+			t.Fatalf("%s: cycle-packing bound %d exceeds the frustration index %d", tg.Name, floor, want)
+		}
+		for _, method := range []string{"exact", "branch-and-bound"} {
+			var got int
+			var sw []int
+			switch method {
+			case "exact":
+				got, sw = sg.frustrationIndexExact(rng)
+			case "branch-and-bound":
+				got, sw = sg.branchAndBound(len(sg.Edges), 0, rng)
+			}
+			if got != want {
+				t.Fatalf("%s: %s frustration index is %d; brute force found %d", tg.Name, method, got, want)
+			}
+			if neg := sg.negativeEdges(sw); neg != got {
+				t.Fatalf("%s: %s switching leaves %d negative edges, not %d", tg.Name, method, neg, got)
+			}
+		}
+	}
+}
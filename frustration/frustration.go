@@ -0,0 +1,149 @@
+/*
+Package frustration reports various statistics on how much frustration
+exists in a graph when treated as an Ising or QUBO problem.  It implements
+the find-frustration program, whose command-line wrapper is in
+cmd/find-frustration, so that other Go programs can parse graphs, find their
+cycles, and analyze their frustration without running the program.
+
+Functions in this package abort on error by panicking with a FatalError.
+Analyze and the other entry points intended for library use recover from
+such panics and return an ordinary error instead.
+*/
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+// Notify is used to output warnings and error messages.  Programs may
+// replace it to redirect or silence them.
+var Notify = log.New(os.Stderr, "", 0)
+
+// Empty represents a zero-byte object.
+type Empty struct{}
+
+// A FatalError is an error that aborts the current operation.  The program
+// as a whole exits, but a server merely fails the current request.
+type FatalError struct {
+	error
+}
+
+// CheckError is a convenience function that aborts on error.
+func CheckError(e error) {
+	if e != nil {
+		panic(FatalError{e})
+	}
+}
+
+// Abortf aborts the current operation with a formatted error message.
+func Abortf(format string, a ...interface{}) {
+	panic(FatalError{fmt.Errorf(format, a...)})
+}
+
+// RecoverFatal converts a FatalError panic into an ordinary error, storing
+// it in *err.  Other panics are propagated.  It must be invoked directly by a
+// defer statement.
+func RecoverFatal(err *error) {
+	if r := recover(); r != nil {
+		fe, ok := r.(FatalError)
+		if !ok {
+			panic(r)
+		}
+		*err = fe.error
+	}
+}
+
+// An Origin identifies the QMASM macro instantiation that introduced a vertex
+// or an edge.
+type Origin struct {
+	Macro    string // Name of the macro ("" for top-level code)
+	Instance string // Fully qualified instance name ("" for top-level code)
+}
+
+// A Graph is a collection of named vertices and edges.  Both vertices and
+// edges have an associated weight.  Offset is the constant that converts the
+// graph's Ising energy back to the energy convention of the input file.
+// Graphs read from QMASM additionally record the origin of each vertex and
+// edge, graphs read from QUBO retain their original QUBO coefficients, and
+// graphs read from bqpjson retain each vertex's integer variable ID and any
+// variable names supplied by the input's metadata.
+type Graph struct {
+	Vs       map[string]float64    // Map from a vertex to a weight
+	Es       map[[2]string]float64 // Map from an edge to a weight
+	VOrigin  map[string]Origin     // Map from a vertex to its origin (nil if unknown)
+	EOrigin  map[[2]string]Origin  // Map from an edge to its origin (nil if unknown)
+	EKind    map[[2]string]string  // Map from an edge to its kind (nil if unknown)
+	EMags    map[[2]string]float64 // Map from an edge to the total magnitude of the terms summed into its weight (nil if unknown)
+	Exact    *ExactWeights         // Exact rational weights, which determine coupling signs (nil if not computed)
+	Offset   float64               // Original energy minus Ising energy
+	QVs      map[string]float64    // Map from a vertex to its QUBO coefficient (nil if not QUBO)
+	QEs      map[[2]string]float64 // Map from an edge to its QUBO coefficient (nil if not QUBO)
+	VarIDs   map[string]int        // Map from a vertex to its bqpjson variable ID (nil if not bqpjson)
+	VarNames map[int]string        // Map from a bqpjson variable ID to its metadata name (nil if none)
+	deadline deadline              // Time after which cycle computations abort (zero for none)
+}
+
+// Edge kinds distinguish the roles that couplers play in an embedded or
+// compiled problem.
+const (
+	EdgeChain    = "chain"    // Coupler that binds physical qubits into a logical variable
+	EdgeLogical  = "logical"  // Coupler that expresses the problem itself
+	EdgePenalty  = "penalty"  // Coupler that penalizes an invalid ancilla configuration
+	EdgeEncoding = "encoding" // Coupler within the unary encoding of an integer variable
+)
+
+// EmbeddingEdgeKinds labels each edge as a chain if both endpoints are
+// embedded in the same logical variable or as logical otherwise.  q2v maps
+// each qubit to its logical variable.
+func (g Graph) EmbeddingEdgeKinds(q2v map[string]string) map[[2]string]string {
+	kinds := make(map[[2]string]string, len(g.Es))
+	for e := range g.Es {
+		u, uOK := q2v[e[0]]
+		v, vOK := q2v[e[1]]
+		if uOK && vOK && u == v {
+			kinds[e] = EdgeChain
+		} else {
+			kinds[e] = EdgeLogical
+		}
+	}
+	return kinds
+}
+
+// sortedVertices returns the graph's vertex names in lexicographic order.
+func (g Graph) sortedVertices() []string {
+	vs := make([]string, 0, len(g.Vs))
+	for v := range g.Vs {
+		vs = append(vs, v)
+	}
+	sort.Strings(vs)
+	return vs
+}
+
+// sortedEdges returns the graph's edges in lexicographic order.
+func (g Graph) sortedEdges() [][2]string {
+	es := make([][2]string, 0, len(g.Es))
+	for e := range g.Es {
+		es = append(es, e)
+	}
+	sort.Slice(es, func(i, j int) bool {
+		if es[i][0] != es[j][0] {
+			return es[i][0] < es[j][0]
+		}
+		return es[i][1] < es[j][1]
+	})
+	return es
+}
+
+// Analyze finds a graph's base cycles and determines which are frustrated,
+// that is, which contain an odd number of antiferromagnetic couplings.  It is
+// equivalent to running find-frustration with no options.  Use a Pipeline
+// for any other analysis.
+func Analyze(g Graph) (*Analysis, error) {
+	pl := Pipeline{CycleFinder: BasisFinder{}, Classifier: signParity}
+	return pl.Run(io.Discard, g, rand.New(rand.NewSource(1)))
+}
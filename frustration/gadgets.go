@@ -0,0 +1,232 @@
+/* This file recognizes common constraint gadgets in a parsed problem, whatever
+its input format, so that frustration inside the gadgets (caused by the
+penalties) can be told apart from frustration among the remaining couplers
+(caused by the objective).  Recognition works on the coefficients alone and is
+necessarily heuristic: a problem coupler that happens to match a gadget's
+pattern is counted as part of the gadget. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// These are the kinds of edge that gadget recognition distinguishes.
+const (
+	GadgetOneHot       = "one-hot"      // Coupler within a one-hot penalty, (1 - Σ x)²
+	GadgetEquality     = "equality"     // Coupler within a chain of equality penalties, (x - y)²
+	GadgetIndependence = "independence" // Independent-set penalty, x y, that outweighs its endpoints' rewards
+	GadgetNone         = "problem"      // Coupler in no recognized gadget
+)
+
+// gadgetTolerance is the relative difference below which two coupler
+// strengths are considered equal when recognizing gadgets.
+const gadgetTolerance = 1e-9
+
+// A Gadgets labels each edge of a graph with the kind of gadget it belongs
+// to and counts the gadgets of each kind.
+type Gadgets struct {
+	Kind         map[[2]string]string // Map from an edge to its gadget kind
+	OneHot       int                  // Number of one-hot groups
+	Equality     int                  // Number of equality chains
+	Independence int                  // Number of independent-set penalties
+}
+
+// RecognizeGadgets labels each edge of a graph with the kind of constraint
+// gadget it belongs to.  Recognition proceeds in three passes, each of which
+// considers only edges that earlier passes left unlabeled:
+//
+// 1. A one-hot group is a maximal set of at least three vertices that are
+// pairwise joined by antiferromagnetic couplers of equal strength, 2 P x y in
+// QUBO form, and whose QUBO linear coefficients are at least -P, which is
+// the penalty's own contribution.  This is the form of the penalty
+// P (1 - Σ x)² plus nonnegative costs.  Groups are grown from each vertex in
+// lexicographic order, preferring stronger couplers, and no vertex belongs to
+// more than one group.
+//
+// 2. An equality chain is a connected set of ferromagnetic couplers, each
+// stronger than every antiferromagnetic coupler in the graph, which is how
+// penalties P (x - y)² and embedding chains are usually weighted.  Without
+// an antiferromagnetic coupler to compare against, no equality chains are
+// recognized.
+//
+// 3. An independent-set penalty is an antiferromagnetic coupler, P x y in
+// QUBO form, whose endpoints both have negative QUBO linear coefficients
+// (rewards) smaller in magnitude than P, as in the standard formulation of
+// maximum independent set.
+func (g Graph) RecognizeGadgets() Gadgets {
+	gs := Gadgets{Kind: make(map[[2]string]string, len(g.Es))}
+	for e := range g.Es {
+		gs.Kind[e] = GadgetNone
+	}
+	tol := gadgetTolerance * g.maxCouplerMagnitude()
+	lin := g.quboLinear()
+	gs.recognizeOneHot(g, lin, tol)
+	gs.recognizeEquality(g)
+	gs.recognizeIndependence(g, lin)
+	return gs
+}
+
+// quboLinear returns each vertex's linear coefficient in QUBO form, which is
+// 2 h - 2 Σ J, where the sum is over the vertex's couplers.  A coupler's QUBO
+// coefficient is 4 J.
+func (g Graph) quboLinear() map[string]float64 {
+	lin := make(map[string]float64, len(g.Vs))
+	for v, h := range g.Vs {
+		lin[v] += 2 * h
+	}
+	for e, wt := range g.Es {
+		lin[e[0]] -= 2 * wt
+		lin[e[1]] -= 2 * wt
+	}
+	return lin
+}
+
+// maxCouplerMagnitude returns the largest coupler magnitude in a graph.
+func (g Graph) maxCouplerMagnitude() float64 {
+	mx := 0.0
+	for _, wt := range g.Es {
+		mx = math.Max(mx, math.Abs(wt))
+	}
+	return mx
+}
+
+// edgeKey returns the canonical key of the edge joining two vertices.
+func edgeKey(u, v string) [2]string {
+	if u > v {
+		u, v = v, u
+	}
+	return [2]string{u, v}
+}
+
+// recognizeOneHot labels the couplers of each one-hot group.
+func (gs *Gadgets) recognizeOneHot(g Graph, lin map[string]float64, tol float64) {
+	// Index each vertex's antiferromagnetic neighbors.  A coupler of
+	// strength J within a one-hot group implies a penalty of P = 2 J, so
+	// neighbors whose linear coefficients are below -2 J cannot share a
+	// group.
+	adj := make(map[string][]string)
+	for e, wt := range g.Es {
+		if wt > 0 && lin[e[0]] >= -2*wt-tol && lin[e[1]] >= -2*wt-tol {
+			adj[e[0]] = append(adj[e[0]], e[1])
+			adj[e[1]] = append(adj[e[1]], e[0])
+		}
+	}
+	for _, ns := range adj {
+		sort.Strings(ns)
+	}
+
+	// Grow a group from each vertex not already in one.
+	claimed := make(map[string]bool)
+	for _, v := range g.sortedVertices() {
+		if claimed[v] || len(adj[v]) < 2 {
+			continue
+		}
+		strengths := make([]float64, 0, len(adj[v]))
+		for _, u := range adj[v] {
+			strengths = append(strengths, g.Es[edgeKey(u, v)])
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(strengths)))
+		for i, s := range strengths {
+			if i > 0 && strengths[i-1]-s <= tol {
+				continue // Strength already tried
+			}
+			members := []string{v}
+			for _, u := range adj[v] {
+				if claimed[u] {
+					continue
+				}
+				joined := true
+				for _, m := range members {
+					wt, ok := g.Es[edgeKey(u, m)]
+					if !ok || math.Abs(wt-s) > tol {
+						joined = false
+						break
+					}
+				}
+				if joined {
+					members = append(members, u)
+				}
+			}
+			if len(members) < 3 {
+				continue
+			}
+			for j, m := range members {
+				claimed[m] = true
+				for _, m2 := range members[:j] {
+					gs.Kind[edgeKey(m, m2)] = GadgetOneHot
+				}
+			}
+			gs.OneHot++
+			break
+		}
+	}
+}
+
+// recognizeEquality labels the couplers of each equality chain.
+func (gs *Gadgets) recognizeEquality(g Graph) {
+	// Find the strongest antiferromagnetic coupler.
+	maxAFM := 0.0
+	for _, wt := range g.Es {
+		maxAFM = math.Max(maxAFM, wt)
+	}
+	if maxAFM == 0 {
+		return
+	}
+
+	// Label every stronger ferromagnetic coupler, and count the connected
+	// components they form.
+	parent := make(map[string]string)
+	var find func(v string) string
+	find = func(v string) string {
+		if p, ok := parent[v]; ok && p != v {
+			parent[v] = find(p)
+			return parent[v]
+		}
+		parent[v] = v
+		return v
+	}
+	for _, e := range g.sortedEdges() {
+		if wt := g.Es[e]; wt >= 0 || -wt <= maxAFM || gs.Kind[e] != GadgetNone {
+			continue
+		}
+		gs.Kind[e] = GadgetEquality
+		r0, r1 := find(e[0]), find(e[1])
+		if r0 == r1 {
+			continue
+		}
+		parent[r0] = r1
+	}
+	for v := range parent {
+		if find(v) == v {
+			gs.Equality++
+		}
+	}
+}
+
+// recognizeIndependence labels each independent-set penalty.
+func (gs *Gadgets) recognizeIndependence(g Graph, lin map[string]float64) {
+	for e, wt := range g.Es {
+		if wt <= 0 || gs.Kind[e] != GadgetNone {
+			continue
+		}
+		a0, a1 := lin[e[0]], lin[e[1]]
+		if a0 < 0 && a1 < 0 && 4*wt > math.Max(-a0, -a1) {
+			gs.Kind[e] = GadgetIndependence
+			gs.Independence++
+		}
+	}
+}
+
+// OutputGadgetBreakdown breaks down frustration statistics by whether edges
+// belong to a recognized constraint gadget, and of which kind, or to the
+// problem proper, followed by the number of gadgets of each kind.  A cycle is
+// attributed to every kind of edge it contains.
+func OutputGadgetBreakdown(w io.Writer, g Graph, ps [][]string, isFrust []bool) {
+	gs := g.RecognizeGadgets()
+	outputEdgeGroups(w, g, "GAD", ps, isFrust, func(e [2]string) string { return gs.Kind[e] })
+	fmt.Fprintf(w, "#GAD %d %d %d\n", gs.OneHot, gs.Equality, gs.Independence)
+}
@@ -0,0 +1,170 @@
+/* This file reports quantities that are invariant under gauge
+transformations (spin switchings), which negate a vertex's field and the
+couplers incident to it.  Gauge-equivalent instances have the same
+frustrated cycles, and hence the same frustrated vertices and edges, but
+different coupler signs, so reports of the signs themselves can make
+equivalent instances look different. */
+
+package frustration
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+)
+
+// gaugeDependentFlags maps each command-line flag whose output depends on
+// the gauge to a description of that output.
+var gaugeDependentFlags = map[string]string{
+	"antiferromagnet":  "reports coupling signs",
+	"coeff-view":       "reports coefficients",
+	"explain":          "reports coupling signs",
+	"field-conflicts":  "reports fields",
+	"preprocessed-out": "writes coefficients",
+	"remediate":        "reports coupler strengths",
+	"save-graph":       "writes coefficients",
+	"vertex-fields":    "reports fields",
+}
+
+// CheckGaugeFlags aborts if any flag specified on the command line would
+// output a quantity that depends on the gauge.
+func CheckGaugeFlags() {
+	flag.Visit(func(f *flag.Flag) {
+		if why, ok := gaugeDependentFlags[f.Name]; ok {
+			Abortf("--gauge-invariant cannot be combined with --%s, which %s", f.Name, why)
+		}
+	})
+}
+
+// plaquettes returns every 4-cycle of a graph, each as a vertex path that
+// begins with its least vertex.
+func (g Graph) plaquettes() [][]string {
+	adj := g.sortedAdjacency()
+	var ps [][]string
+	for _, a := range g.sortedVertices() {
+		// Find each vertex c > a with two or more neighbors b > a in
+		// common with a.
+		common := make(map[string][]string)
+		for _, b := range adj[a] {
+			if b < a {
+				continue
+			}
+			for _, c := range adj[b] {
+				if c > a {
+					common[c] = append(common[c], b)
+				}
+			}
+		}
+		cs := make([]string, 0, len(common))
+		for c := range common {
+			cs = append(cs, c)
+		}
+		sort.Strings(cs)
+		for _, c := range cs {
+			bs := common[c]
+			for i := 0; i < len(bs); i++ {
+				for j := i + 1; j < len(bs); j++ {
+					ps = append(ps, []string{a, bs[i], c, bs[j]})
+				}
+			}
+		}
+	}
+	return ps
+}
+
+// disjointFrustratedCycles greedily packs edge-disjoint frustrated cycles,
+// shortest first, and returns the number packed.  Each such cycle needs at
+// least one of its own edges to change sign, so the result is a lower bound
+// on the frustration index.
+func (g Graph) disjointFrustratedCycles(ps [][]string, isFrust []bool) int {
+	var fps [][]string
+	for i, p := range ps {
+		if isFrust[i] {
+			fps = append(fps, p)
+		}
+	}
+	sort.SliceStable(fps, func(i, j int) bool { return len(fps[i]) < len(fps[j]) })
+	used := make(map[[2]string]bool)
+	n := 0
+	for _, p := range fps {
+		es := g.pathToEdges(p)
+		free := true
+		for _, e := range es {
+			if used[e] {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+		for _, e := range es {
+			used[e] = true
+		}
+		n++
+	}
+	return n
+}
+
+// canonicalGauge returns a copy of a signedGraph switched so that every edge
+// of a breadth-first spanning forest, rooted at the least vertex of each
+// component, is positive.  The forest depends only on the graph's structure,
+// so gauge-equivalent signed graphs have the same canonical gauge.
+func (sg signedGraph) canonicalGauge() signedGraph {
+	sw := make([]int, len(sg.Names))
+	for r := range sw {
+		if sw[r] != 0 {
+			continue
+		}
+		sw[r] = 1
+		queue := []int{r}
+		for len(queue) > 0 {
+			u := queue[0]
+			queue = queue[1:]
+			for _, a := range sg.Adj[u] {
+				if sw[a.To] == 0 {
+					sw[a.To] = sw[u] * a.Sign
+					queue = append(queue, a.To)
+				}
+			}
+		}
+	}
+	cg := signedGraph{
+		Names: sg.Names,
+		Index: sg.Index,
+		Adj:   make([][]signedArc, len(sg.Names)),
+		Edges: sg.Edges,
+		Signs: make([]int, len(sg.Signs)),
+	}
+	for i, e := range sg.Edges {
+		u, v := e[0], e[1]
+		s := sg.Signs[i] * sw[u] * sw[v]
+		cg.Signs[i] = s
+		cg.Adj[u] = append(cg.Adj[u], signedArc{To: v, Sign: s})
+		cg.Adj[v] = append(cg.Adj[v], signedArc{To: u, Sign: s})
+	}
+	return cg
+}
+
+// OutputGaugeInvariants outputs the number of frustrated plaquettes
+// (4-cycles), as judged by a given Classifier, and lower and upper bounds on
+// the frustration index.  The lower bound is the number of edge-disjoint
+// frustrated cycles found among the analyzed cycles, and the upper bound is
+// the number of antiferromagnetic couplings left by a heuristic switching
+// with the given number of random restarts, begun from the canonical gauge so
+// that gauge-equivalent graphs receive the same bound.
+func OutputGaugeInvariants(w io.Writer, g Graph, ps [][]string, isFrust []bool, c Classifier, restarts int, rng *rand.Rand) {
+	nf := 0
+	plqs := g.plaquettes()
+	for _, p := range plqs {
+		if c.Classify(g, p) {
+			nf++
+		}
+	}
+	fmt.Fprintf(w, "#PLQ %d / %d = %f\n", nf, len(plqs), fraction(nf, len(plqs)))
+	lo := g.disjointFrustratedCycles(ps, isFrust)
+	hi, _ := g.signedGraph().canonicalGauge().frustrationIndexHeuristic(restarts, rng)
+	fmt.Fprintf(w, "#FIB %d %d\n", lo, hi)
+}
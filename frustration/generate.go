@@ -0,0 +1,210 @@
+/* This file generates random Ising problems on standard benchmark topologies
+-- hypercubic lattices, hypercubes, random regular graphs, and small-world
+graphs -- with ±J or Gaussian disorder.  Every random choice is drawn in a
+fixed order from a single seeded generator, so a seed reproduces a problem
+exactly. */
+
+package frustration
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// maxRegularAttempts is the number of random pairings tried before giving up
+// on generating a simple random regular graph.
+const maxRegularAttempts = 1000
+
+// GeneratorOptions specifies a family of random problems.
+type GeneratorOptions struct {
+	Topology string  // "lattice", "hypercube", "regular", or "small-world"
+	Dims     string  // Lattice side lengths ("8x8x8") or hypercube dimension
+	Periodic bool    // Whether a lattice wraps around in each dimension
+	Vertices int     // Number of vertices in a regular or small-world graph
+	Degree   int     // Vertex degree of a regular or small-world graph
+	Rewire   float64 // Probability of rewiring each small-world edge
+	Disorder string  // "pm" (J = ±1) or "gaussian" (J ~ N(0, 1))
+}
+
+// parseDims parses a lattice's side lengths, separated by "x".
+func parseDims(s string) []int {
+	var dims []int
+	for _, f := range strings.Split(s, "x") {
+		d, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || d < 1 {
+			Abortf("Invalid lattice dimensions %q", s)
+		}
+		dims = append(dims, d)
+	}
+	return dims
+}
+
+// latticeEdges returns the edges of a hypercubic lattice with the given side
+// lengths.  Vertices are numbered in row-major order.  A periodic dimension
+// of length 2 contributes a single edge per pair, not a double edge.
+func latticeEdges(dims []int, periodic bool) (int, [][2]int) {
+	n := 1
+	for _, d := range dims {
+		n *= d
+	}
+	var edges [][2]int
+	for v := 0; v < n; v++ {
+		stride := n
+		for _, d := range dims {
+			stride /= d
+			c := v / stride % d // Coordinate of v in this dimension
+			switch {
+			case c+1 < d:
+				edges = append(edges, [2]int{v, v + stride})
+			case periodic && d > 2:
+				edges = append(edges, [2]int{v, v - c*stride})
+			}
+		}
+	}
+	return n, edges
+}
+
+// regularEdges returns the edges of a uniformly random simple d-regular
+// graph on n vertices, generated by the configuration model: vertex stubs
+// are paired at random, and pairings that produce a self-loop or a double
+// edge are rejected.
+func regularEdges(n, d int, rng *rand.Rand) [][2]int {
+	switch {
+	case d < 1 || d >= n:
+		Abortf("A random regular graph requires 1 <= degree < vertices")
+	case n*d%2 != 0:
+		Abortf("A random regular graph requires an even product of degree and vertices")
+	}
+	stubs := make([]int, n*d)
+	for attempt := 0; attempt < maxRegularAttempts; attempt++ {
+		for i := range stubs {
+			stubs[i] = i / d
+		}
+		rng.Shuffle(len(stubs), func(i, j int) { stubs[i], stubs[j] = stubs[j], stubs[i] })
+		seen := make(map[[2]int]Empty, len(stubs)/2)
+		edges := make([][2]int, 0, len(stubs)/2)
+		for i := 0; i < len(stubs); i += 2 {
+			u, v := stubs[i], stubs[i+1]
+			if u > v {
+				u, v = v, u
+			}
+			if _, dup := seen[[2]int{u, v}]; dup || u == v {
+				break
+			}
+			seen[[2]int{u, v}] = Empty{}
+			edges = append(edges, [2]int{u, v})
+		}
+		if len(edges) == len(stubs)/2 {
+			return edges
+		}
+	}
+	Abortf("Failed to generate a simple %d-regular graph in %d attempts", d, maxRegularAttempts)
+	return nil
+}
+
+// smallWorldEdges returns the edges of a Watts-Strogatz small-world graph: a
+// ring of n vertices, each joined to its k nearest neighbors, in which each
+// edge's far endpoint is rewired with probability p to a random vertex that
+// would not create a self-loop or a double edge.
+func smallWorldEdges(n, k int, p float64, rng *rand.Rand) [][2]int {
+	switch {
+	case k < 2 || k%2 != 0 || k >= n:
+		Abortf("A small-world graph requires an even degree with 2 <= degree < vertices")
+	case p < 0 || p > 1:
+		Abortf("The rewiring probability must lie in [0, 1]")
+	}
+	key := func(u, v int) [2]int {
+		if u > v {
+			u, v = v, u
+		}
+		return [2]int{u, v}
+	}
+	seen := make(map[[2]int]Empty, n*k/2)
+	src := make([]int, 0, n*k/2) // Endpoint from which each edge was drawn
+	edges := make([][2]int, 0, n*k/2)
+	for j := 1; j <= k/2; j++ {
+		for u := 0; u < n; u++ {
+			e := key(u, (u+j)%n)
+			seen[e] = Empty{}
+			src = append(src, u)
+			edges = append(edges, e)
+		}
+	}
+	deg := make([]int, n)
+	for i := range deg {
+		deg[i] = k
+	}
+	for i, e := range edges {
+		u := src[i]
+		if rng.Float64() >= p || deg[u] == n-1 {
+			continue
+		}
+		v := rng.Intn(n)
+		for _, dup := seen[key(u, v)]; dup || u == v; _, dup = seen[key(u, v)] {
+			v = rng.Intn(n)
+		}
+		old := e[0] + e[1] - u
+		deg[old]--
+		deg[v]++
+		delete(seen, e)
+		edges[i] = key(u, v)
+		seen[edges[i]] = Empty{}
+	}
+	return edges
+}
+
+// Generate returns a random problem from the family described by opts.
+// Vertices are named by consecutive integers, starting from 0, and have no
+// field.
+func Generate(opts GeneratorOptions, rng *rand.Rand) Graph {
+	// Construct the topology.
+	var n int
+	var edges [][2]int
+	switch opts.Topology {
+	case "lattice":
+		n, edges = latticeEdges(parseDims(opts.Dims), opts.Periodic)
+	case "hypercube":
+		d, err := strconv.Atoi(opts.Dims)
+		if err != nil || d < 1 {
+			Abortf("A hypercube requires a single positive dimension, not %q", opts.Dims)
+		}
+		dims := make([]int, d)
+		for i := range dims {
+			dims[i] = 2
+		}
+		n, edges = latticeEdges(dims, false)
+	case "regular":
+		n, edges = opts.Vertices, regularEdges(opts.Vertices, opts.Degree, rng)
+	case "small-world":
+		n, edges = opts.Vertices, smallWorldEdges(opts.Vertices, opts.Degree, opts.Rewire, rng)
+	default:
+		Abortf("Unrecognized topology %q", opts.Topology)
+	}
+
+	// Assign each coupler a random strength.
+	g := Graph{
+		Vs: make(map[string]float64, n),
+		Es: make(map[[2]string]float64, len(edges)),
+	}
+	for v := 0; v < n; v++ {
+		g.Vs[strconv.Itoa(v)] = 0
+	}
+	for _, e := range edges {
+		var wt float64
+		switch opts.Disorder {
+		case "pm":
+			wt = float64(2*rng.Intn(2) - 1)
+		case "gaussian":
+			wt = rng.NormFloat64()
+		default:
+			Abortf("Unrecognized disorder %q", opts.Disorder)
+		}
+		u, v := strconv.Itoa(e[0]), strconv.Itoa(e[1])
+		if u > v {
+			u, v = v, u
+		}
+		g.Es[[2]string{u, v}] = wt
+	}
+	return g
+}
@@ -0,0 +1,162 @@
+/* This file reads graphs in GraphML, the XML format that network-analysis
+tools such as Gephi and NetworkX export, so that signed, weighted networks can
+be analyzed without first converting them to another format. */
+
+package frustration
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A GraphMLParser reads a graph in GraphML format.  Each node becomes a
+// vertex, and each edge becomes a coupler whose strength is the value of a
+// given edge attribute.  Edges are treated as undirected regardless of the
+// graph's declared direction, so a pair of opposing directed edges becomes a
+// single coupler whose strength is their sum.
+type GraphMLParser struct {
+	Weight string // Edge attribute holding each coupler strength, with a leading "-" to negate it ("": "weight")
+	Field  string // Node attribute holding each vertex's field, if the graph declares it ("": "h")
+}
+
+// A graphMLKey declares an attribute of GraphML nodes or edges.
+type graphMLKey struct {
+	ID      string  `xml:"id,attr"`        // Identifier by which data elements refer to the key
+	For     string  `xml:"for,attr"`       // Kind of element the attribute applies to
+	Name    string  `xml:"attr.name,attr"` // Name of the attribute
+	Default *string `xml:"default"`        // Value of the attribute where absent (nil if none)
+}
+
+// A graphMLData gives the value of one attribute of a node or edge.
+type graphMLData struct {
+	Key   string `xml:"key,attr"`  // Identifier of the attribute's key
+	Value string `xml:",chardata"` // Value of the attribute
+}
+
+// A graphMLNode is a GraphML node and its attributes.
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+// A graphMLEdge is a GraphML edge and its attributes.
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+// A graphMLDoc is the subset of a GraphML document that GraphMLParser reads.
+type graphMLDoc struct {
+	Keys   []graphMLKey `xml:"key"`
+	Graphs []struct {
+		Nodes []graphMLNode `xml:"node"`
+		Edges []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+// findKey returns the key that declares a named attribute of a given kind of
+// element.
+func (doc graphMLDoc) findKey(kind, name string) (graphMLKey, bool) {
+	for _, k := range doc.Keys {
+		if k.Name == name && (k.For == kind || k.For == "all") {
+			return k, true
+		}
+	}
+	return graphMLKey{}, false
+}
+
+// value returns the numeric value of a key's attribute among an element's
+// data, falling back to the key's default, and says whether either was
+// present.  what describes the element for error messages.
+func (k graphMLKey) value(ds []graphMLData, what string) (float64, bool) {
+	s := k.Default
+	for _, d := range ds {
+		if d.Key == k.ID {
+			val := d.Value
+			s = &val
+		}
+	}
+	if s == nil {
+		return 0, false
+	}
+	x, err := strconv.ParseFloat(strings.TrimSpace(*s), 64)
+	if err != nil {
+		Abortf("GraphML %s has a non-numeric %q attribute, %q", what, k.Name, *s)
+	}
+	return x, true
+}
+
+// Parse reads a graph in GraphML format.
+func (gp GraphMLParser) Parse(r io.Reader) Graph {
+	// Read the document and locate the attributes of interest.
+	var doc graphMLDoc
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		Abortf("Failed to parse GraphML input (%v)", err)
+	}
+	if len(doc.Graphs) != 1 {
+		Abortf("GraphML input must contain exactly one graph but contains %d", len(doc.Graphs))
+	}
+	wName, sign := gp.Weight, 1.0
+	if wName == "" {
+		wName = "weight"
+	}
+	if strings.HasPrefix(wName, "-") {
+		wName, sign = wName[1:], -1.0
+	}
+	wKey, ok := doc.findKey("edge", wName)
+	if !ok {
+		var names []string
+		for _, k := range doc.Keys {
+			if k.For == "edge" || k.For == "all" {
+				names = append(names, strconv.Quote(k.Name))
+			}
+		}
+		sort.Strings(names)
+		Abortf("GraphML input declares no edge attribute named %q (available: %s)", wName, strings.Join(names, ", "))
+	}
+	fName := gp.Field
+	if fName == "" {
+		fName = "h"
+	}
+	fKey, haveFields := doc.findKey("node", fName)
+
+	// Convert nodes to vertices and edges to couplers.
+	vs := make(map[string]float64)      // Map from a vertex to a weight
+	es := make(map[[2]string]float64)   // Map from an edge to a weight
+	mags := make(map[[2]string]float64) // Map from an edge to the magnitude of its terms
+	gr := doc.Graphs[0]
+	for _, n := range gr.Nodes {
+		vs[n.ID] += 0.0
+		if haveFields {
+			h, _ := fKey.value(n.Data, "node "+n.ID)
+			vs[n.ID] += h
+		}
+	}
+	for _, e := range gr.Edges {
+		u, v := e.Source, e.Target
+		if u == v {
+			Abortf("GraphML edge %s %s is a self-loop", u, v)
+		}
+		wt, ok := wKey.value(e.Data, "edge "+u+" "+v)
+		if !ok {
+			Abortf("GraphML edge %s %s has no %q attribute, which declares no default", u, v, wName)
+		}
+		wt *= sign
+		if u > v {
+			u, v = v, u
+		}
+		if _, dup := es[[2]string{u, v}]; dup {
+			Warn("W001-duplicate-coupler", u+" "+v, "Coupler %s %s appears more than once; its terms are summed", u, v)
+		}
+		es[[2]string{u, v}] += wt
+		mags[[2]string{u, v}] += math.Abs(wt)
+		vs[u] += 0.0
+		vs[v] += 0.0
+	}
+	return Graph{Vs: vs, Es: es, EMags: mags}
+}
@@ -0,0 +1,178 @@
+/* This file guards the input readers against pathological input -- binary
+files, multi-megabyte lines, and enormous vertex names -- which would
+otherwise exhaust memory or produce baffling error messages.  This matters
+most when the tool runs as a server that accepts uploads from anyone. */
+
+package frustration
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// InputLimits bounds what the readers accept.
+type InputLimits struct {
+	MaxLineBytes int // Maximum length of a line of text (0: unlimited)
+	MaxNameBytes int // Maximum length of a vertex name (0: unlimited)
+}
+
+// guardedFormats maps each built-in text format to whether it is
+// line-oriented.  Text formats are checked for NUL bytes and invalid UTF-8,
+// and line-oriented formats additionally for overlong lines.  JSON and XML
+// formats are exempt from the line limit because minified JSON and XML are a
+// single line, as is SAPI because Python literals are often written on a
+// single line.
+// Binary and unknown formats are not checked and are therefore refused from
+// network clients.
+var guardedFormats = map[string]bool{
+	"qubist":   true,
+	"qubo":     true,
+	"qmasm":    true,
+	"maxcut":   true,
+	"coloring": true,
+	"mtx":      true,
+	"csv":      true,
+	"tsv":      true,
+	"bqpjson":  false,
+	"sapi":     false,
+	"graphml":  false,
+}
+
+// checkUntrustedFormat aborts unless input in a given format can safely be
+// accepted from an untrusted source such as a network client.  Only guarded
+// text formats are accepted.  Binary formats are refused because ffg, for
+// example, decodes arbitrary gob data, and formats not listed in
+// guardedFormats, including any registered by other packages, are refused
+// because nothing bounds what their readers accept.
+func checkUntrustedFormat(inFmt string) {
+	if _, ok := guardedFormats[inFmt]; ok {
+		return
+	}
+	names := make([]string, 0, len(guardedFormats))
+	for f := range guardedFormats {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+	Abortf("Input format %q is not accepted from network clients (accepted: %s)", inFmt, strings.Join(names, ", "))
+}
+
+// A guardedReader passes text through from an underlying reader but fails
+// on the first NUL byte, invalid UTF-8 sequence, or overlong line.
+type guardedReader struct {
+	r       io.Reader // Underlying reader
+	maxLine int       // Maximum line length in bytes (0: unlimited)
+	line    int       // Current line number
+	col     int       // Number of bytes read so far on the current line
+	pending []byte    // Incomplete UTF-8 sequence at the end of the last read
+	err     error     // First error encountered
+}
+
+// fail records and returns an error about the current line.
+func (gr *guardedReader) fail(format string, a ...interface{}) error {
+	gr.err = fmt.Errorf("Input line %d %s", gr.line, fmt.Sprintf(format, a...))
+	return gr.err
+}
+
+// Read reads from the underlying reader, returning only the bytes that
+// precede the first violation.
+func (gr *guardedReader) Read(p []byte) (int, error) {
+	if gr.err != nil {
+		return 0, gr.err
+	}
+	n, err := gr.r.Read(p)
+
+	// Validate the new bytes, prefixed by any incomplete UTF-8 sequence
+	// left over from the previous read.
+	np := len(gr.pending)
+	buf := append(gr.pending, p[:n]...)
+	gr.pending = nil
+	for i := 0; i < len(buf); {
+		switch c := buf[i]; {
+		case c == 0:
+			return max0(i - np), gr.fail("contains a NUL byte (is the input binary?)")
+		case c == '\n':
+			gr.line++
+			gr.col = 0
+			i++
+			continue
+		case c < utf8.RuneSelf:
+			i++
+			gr.col++
+		default:
+			r, sz := utf8.DecodeRune(buf[i:])
+			if r == utf8.RuneError && sz <= 1 {
+				if !utf8.FullRune(buf[i:]) && err == nil {
+					// Wait for the rest of the sequence.
+					gr.pending = append([]byte(nil), buf[i:]...)
+					return n, nil
+				}
+				return max0(i - np), gr.fail("contains invalid UTF-8")
+			}
+			i += sz
+			gr.col += sz
+		}
+		if gr.maxLine > 0 && gr.col > gr.maxLine {
+			return max0(i - np), gr.fail("exceeds the maximum length of %d bytes", gr.maxLine)
+		}
+	}
+	return n, err
+}
+
+// max0 returns the larger of x and 0.
+func max0(x int) int {
+	if x < 0 {
+		return 0
+	}
+	return x
+}
+
+// Guard wraps a reader of a given input format with the checks appropriate
+// to that format.
+func (lim InputLimits) Guard(inFmt string, r io.Reader) io.Reader {
+	lineOriented, ok := guardedFormats[inFmt]
+	if !ok {
+		return r
+	}
+	gr := &guardedReader{r: r, line: 1}
+	if lineOriented {
+		gr.maxLine = lim.MaxLineBytes
+	}
+	return gr
+}
+
+// checkNames aborts if any vertex name in a graph exceeds the name limit.
+func (lim InputLimits) checkNames(g Graph) {
+	if lim.MaxNameBytes <= 0 {
+		return
+	}
+	for v := range g.Vs {
+		if len(v) > lim.MaxNameBytes {
+			start := v
+			if len(start) > 32 {
+				start = start[:32]
+			}
+			Abortf("Vertex name %q... is %d bytes long, exceeding the maximum of %d", start, len(v), lim.MaxNameBytes)
+		}
+	}
+}
+
+// Parse reads a graph using a given parser for a given input format,
+// subject to the limits.
+func (lim InputLimits) Parse(p Parser, inFmt string, r io.Reader) Graph {
+	g := p.Parse(lim.Guard(inFmt, r))
+	lim.checkNames(g)
+	return g
+}
+
+// newLineScanner returns a Scanner that splits its input into lines of any
+// length, leaving the enforcement of line limits to a guardedReader.
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, math.MaxInt32)
+	return sc
+}
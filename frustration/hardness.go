@@ -0,0 +1,113 @@
+/* This file predicts how hard an instance is to solve from structural
+features that a frustration analysis already computes.  The prediction is a
+linear model of the logarithm of the time to solution, calibrated against the
+built-in simulated-annealing solver on generated benchmarks, and is meant for
+triage (deciding which instances merit expensive hardware) rather than as a
+substitute for running a solver. */
+
+package frustration
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// hardnessModel holds the coefficients of the hardness predictor: an
+// intercept followed by the weights of the frustrated-cycle fraction, the
+// mean frustrated-cycle length, the coupler-weight dispersion, and the base-10
+// logarithm of one more than the number of frustration-core vertices.
+//
+// The coefficients were fit by least squares to the base-10 logarithm of the
+// time to solution, in Monte Carlo sweeps, of SolveAnnealing on 720 instances
+// produced by Generate: 2-D and 3-D lattices, hypercubes, random regular
+// graphs, and small-world graphs of 8 to 125 vertices, with pm and gaussian
+// disorder, and with 0%, 50%, or 80% of the couplers then made
+// ferromagnetic to vary the amount of frustration.  The time to solution is
+// the number of sweeps needed to find a ground state with 99% probability,
+// minimized over runs of 10, 100, and 1000 sweeps, with ground states found
+// by solveExact or, where that is infeasible, by repeated parallel tempering.
+// The fit explains 68% of the variance (Spearman rank correlation 0.87), and
+// nearly all of its predictive power comes from the size of the frustration
+// core and the dispersion of the coupler weights.
+var hardnessModel = [5]float64{-1.124, 0.191, 0.010, 1.087, 1.881}
+
+// A Hardness is a predicted time to solution along with the features from
+// which it was predicted.
+type Hardness struct {
+	Score            float64 `json:"score"`             // Predicted base-10 logarithm of the annealing sweeps needed to find a ground state with 99% probability
+	CycleFraction    float64 `json:"cycle_fraction"`    // Fraction of base cycles that are frustrated
+	MeanCycleLength  float64 `json:"mean_cycle_length"` // Mean length of the frustrated base cycles (0 if none)
+	WeightDispersion float64 `json:"weight_dispersion"` // Coefficient of variation of the coupler magnitudes
+	CoreVertices     int     `json:"core_vertices"`     // Number of vertices in the frustration core
+	CoreFraction     float64 `json:"core_fraction"`     // Fraction of all vertices in the frustration core
+}
+
+// ComputeHardness computes a graph's hardness features and predicts from
+// them the base-10 logarithm of the number of simulated-annealing sweeps
+// needed to find a ground state with 99% probability.  Cycles are deemed
+// frustrated by a given Classifier.  Because the model was calibrated on
+// problems without external fields, predictions for problems with strong
+// fields are less reliable.
+func ComputeHardness(g Graph, c Classifier, rng *rand.Rand) Hardness {
+	var hd Hardness
+
+	// Measure the density and length of the frustrated base cycles.
+	_, cs, _ := g.findCycles(false, rng)
+	if len(cs) > 0 {
+		ps, isFrust := g.classifyCycles(cs, c)
+		nf, sumLen := 0, 0
+		for i, p := range ps {
+			if isFrust[i] {
+				nf++
+				sumLen += len(p)
+			}
+		}
+		hd.CycleFraction = fraction(nf, len(ps))
+		if nf > 0 {
+			hd.MeanCycleLength = float64(sumLen) / float64(nf)
+		}
+	}
+
+	// Measure the dispersion of the coupler magnitudes.
+	if len(g.Es) > 0 {
+		sum, sum2 := 0.0, 0.0
+		for _, wt := range g.Es {
+			sum += math.Abs(wt)
+			sum2 += wt * wt
+		}
+		n := float64(len(g.Es))
+		mean := sum / n
+		if mean > 0 {
+			hd.WeightDispersion = math.Sqrt(math.Max(sum2/n-mean*mean, 0)) / mean
+		}
+	}
+
+	// Measure the size of the frustration core.
+	hd.CoreVertices = len(g.FrustrationCore().Vs)
+	hd.CoreFraction = fraction(hd.CoreVertices, len(g.Vs))
+
+	// Apply the model.
+	x := hd.features()
+	hd.Score = hardnessModel[0]
+	for i, f := range x {
+		hd.Score += hardnessModel[i+1] * f
+	}
+	return hd
+}
+
+// features returns the hardness features in the order of hardnessModel's
+// weights.
+func (hd Hardness) features() [4]float64 {
+	return [4]float64{
+		hd.CycleFraction,
+		hd.MeanCycleLength,
+		hd.WeightDispersion,
+		math.Log10(1 + float64(hd.CoreVertices)),
+	}
+}
+
+// String formats a hardness prediction as a pair of tagged lines.
+func (hd Hardness) String() string {
+	return fmt.Sprintf("#HARD %f\n#HARDF %f %f %f %d %f\n", hd.Score, hd.CycleFraction, hd.MeanCycleLength, hd.WeightDispersion, hd.CoreVertices, hd.CoreFraction)
+}
@@ -0,0 +1,941 @@
+/* This file provides functions for reading and parsing input files in
+different formats. */
+
+package frustration
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// copyQUBO returns a copy of a QUBO problem's coefficients, which quboToIsing
+// would otherwise overwrite.
+func copyQUBO(vs map[string]float64, es map[[2]string]float64) (map[string]float64, map[[2]string]float64) {
+	qvs := make(map[string]float64, len(vs))
+	for v, wt := range vs {
+		qvs[v] = wt
+	}
+	qes := make(map[[2]string]float64, len(es))
+	for e, wt := range es {
+		qes[e] = wt
+	}
+	return qvs, qes
+}
+
+// quboToIsing converts a QUBO problem to an Ising problem.  It returns the
+// constant that must be added to the Ising energy of a spin assignment to
+// obtain the QUBO energy of the corresponding Boolean assignment.  Terms are
+// summed in lexicographic order so that the result does not depend on map
+// iteration order.
+func quboToIsing(vs map[string]float64, es map[[2]string]float64) float64 {
+	g := Graph{Vs: vs, Es: es}
+	c := 0.0
+	for _, i := range g.sortedVertices() {
+		wt := vs[i]
+		vs[i] = wt / 2
+		c += wt / 2
+	}
+	for _, ij := range g.sortedEdges() {
+		i, j := ij[0], ij[1]
+		wt4 := es[ij] / 4
+		es[ij] = wt4
+		vs[i] += wt4
+		vs[j] += wt4
+		c += wt4
+	}
+	return c
+}
+
+// scaleMags multiplies the magnitudes of the terms summed into each edge's
+// weight by a constant, as when the weights themselves are scaled.
+func scaleMags(mags map[[2]string]float64, c float64) {
+	for e, m := range mags {
+		mags[e] = m * math.Abs(c)
+	}
+}
+
+// isAncilla says whether a QMASM variable name refers to an internal
+// (ancillary) variable, which by QMASM convention begins with "$".
+func isAncilla(v string) bool {
+	if dot := strings.LastIndex(v, "."); dot >= 0 {
+		v = v[dot+1:]
+	}
+	return strings.HasPrefix(v, "$")
+}
+
+// ReadQMASMFile returns the Ising Hamiltonian represented by a QMASM source
+// file.  Macros defined with !begin_macro/!end_macro are expanded at each
+// !use_macro, with the instance name prefixed to each variable name, and
+// the macro instantiation from which each vertex and edge arose is recorded
+// in the graph.  Each edge is additionally labeled as a chain (written with
+// "=" or "<->"), a penalty coupler (one involving an ancillary variable), or
+// a logical coupler (any other).  Other directives are ignored.
+func ReadQMASMFile(r io.Reader) Graph {
+	g := Graph{
+		Vs:      make(map[string]float64),    // Map from a vertex to a weight
+		Es:      make(map[[2]string]float64), // Map from an edge to a weight
+		VOrigin: make(map[string]Origin),     // Map from a vertex to its origin
+		EOrigin: make(map[[2]string]Origin),  // Map from an edge to its origin
+		EKind:   make(map[[2]string]string),  // Map from an edge to its kind
+		EMags:   make(map[[2]string]float64), // Map from an edge to the magnitude of its terms
+	}
+	macros := make(map[string][]string) // Map from a macro name to its body
+
+	// addVertex records a vertex's origin the first time it is seen.
+	addVertex := func(v string, wt float64, org Origin) {
+		if _, ok := g.VOrigin[v]; !ok {
+			g.VOrigin[v] = org
+		}
+		g.Vs[v] += wt
+	}
+
+	// Define a function that processes a list of lines in the context of
+	// a given macro instantiation.
+	var process func(lns []string, org Origin)
+	process = func(lns []string, org Origin) {
+		// qualify prefixes a name with the current instance name.
+		qualify := func(v string) string {
+			if org.Instance == "" {
+				return v
+			}
+			return org.Instance + "." + v
+		}
+		mName := ""        // Name of the macro currently being defined
+		var mBody []string // Body of the macro currently being defined
+		for _, ln := range lns {
+			// Discard comments.
+			hIdx := strings.Index(ln, "#")
+			if hIdx >= 0 {
+				ln = ln[:hIdx]
+			}
+			fs := strings.Fields(ln)
+			if len(fs) == 0 {
+				continue
+			}
+
+			// Accumulate macro definitions.
+			if mName != "" {
+				if fs[0] == "!end_macro" {
+					macros[mName] = mBody
+					mName = ""
+				} else {
+					mBody = append(mBody, ln)
+				}
+				continue
+			}
+
+			// Handle directives.
+			switch fs[0] {
+			case "!begin_macro":
+				if len(fs) != 2 {
+					Abortf("Failed to parse QMASM line %q", strings.TrimSpace(ln))
+				}
+				mName, mBody = fs[1], nil
+				continue
+			case "!use_macro":
+				if len(fs) < 3 {
+					Abortf("Failed to parse QMASM line %q", strings.TrimSpace(ln))
+				}
+				body, ok := macros[fs[1]]
+				if !ok {
+					Abortf("Macro %q is used before being defined", fs[1])
+				}
+				for _, inst := range fs[2:] {
+					process(body, Origin{Macro: fs[1], Instance: qualify(inst)})
+				}
+				continue
+			}
+			if strings.HasPrefix(fs[0], "!") {
+				continue // Unsupported directive
+			}
+
+			// Parse the line.
+			switch len(fs) {
+			case 2:
+				// Vertex
+				wt, err := strconv.ParseFloat(fs[1], 64)
+				CheckError(err)
+				addVertex(qualify(fs[0]), wt, org)
+			case 3:
+				// Edge, chain, or alias
+				var u, v string
+				var wt float64
+				kind := EdgeLogical
+				if fs[1] == "=" || fs[1] == "<->" {
+					// Chain or alias
+					u, v = qualify(fs[0]), qualify(fs[2])
+					wt = -1.0
+					kind = EdgeChain
+				} else {
+					var err error
+					u, v = qualify(fs[0]), qualify(fs[1])
+					wt, err = strconv.ParseFloat(fs[2], 64)
+					CheckError(err)
+					if isAncilla(fs[0]) || isAncilla(fs[1]) {
+						kind = EdgePenalty
+					}
+				}
+				if u > v {
+					u, v = v, u
+				}
+				e := [2]string{u, v}
+				if _, ok := g.EOrigin[e]; !ok {
+					g.EOrigin[e] = org
+					g.EKind[e] = kind
+				}
+				g.Es[e] += wt
+				g.EMags[e] += math.Abs(wt)
+				addVertex(u, 0.0, org)
+				addVertex(v, 0.0, org)
+			}
+		}
+		if mName != "" {
+			Abortf("Macro %q is missing an !end_macro", mName)
+		}
+	}
+
+	// Read the entire file then process it as top-level code.
+	var lns []string
+	sc := newLineScanner(r)
+	for sc.Scan() {
+		lns = append(lns, sc.Text())
+	}
+	CheckError(sc.Err())
+	process(lns, Origin{})
+	return g
+}
+
+// A nameTable interns vertex names so that every term that mentions a
+// vertex shares a single copy of its name.  Without interning, each name
+// read from a line of text retains the entire line, and each name formatted
+// from an integer ID is a separate allocation, which on instances with many
+// couplers costs more memory than the graph itself.
+type nameTable map[string]string
+
+// intern returns the canonical copy of a name.
+func (nt nameTable) intern(s string) string {
+	if c, ok := nt[s]; ok {
+		return c
+	}
+	c := strings.Clone(s)
+	nt[c] = c
+	return c
+}
+
+// A denseTerms accumulates the terms of a problem whose vertex names are
+// integer IDs, as in files written for hardware with large, sparse qubit
+// indices.  IDs are mapped to dense indices in order of first appearance, so
+// terms are stored under small integer keys rather than strings, and each
+// vertex's name is formatted only once, when the graph is built.
+type denseTerms struct {
+	index map[int64]int32      // Map from a vertex ID to its dense index
+	ids   []int64              // Map from a dense index to its vertex ID
+	hs    []float64            // Map from a dense index to a field
+	js    map[[2]int32]float64 // Map from a pair of dense indices, smaller first, to a coupler strength
+	mags  map[[2]int32]float64 // Map from a pair of dense indices to the magnitude of its terms
+}
+
+// newDenseTerms returns an empty denseTerms.
+func newDenseTerms() *denseTerms {
+	return &denseTerms{
+		index: make(map[int64]int32),
+		js:    make(map[[2]int32]float64),
+		mags:  make(map[[2]int32]float64),
+	}
+}
+
+// parseVertexID returns the integer a vertex name denotes and whether the
+// name is the canonical decimal form of that integer.  Names such as "007"
+// and "+7" are not, as formatting their IDs would not reproduce them.
+func parseVertexID(s string) (int64, bool) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	switch {
+	case err != nil:
+		return 0, false
+	case s[0] == '+', s[0] == '0' && len(s) > 1, strings.HasPrefix(s, "-0"):
+		return 0, false
+	}
+	return id, true
+}
+
+// vertex returns the dense index of a vertex ID, assigning the next index to
+// an ID not seen before.
+func (dt *denseTerms) vertex(id int64) int32 {
+	if i, ok := dt.index[id]; ok {
+		return i
+	}
+	if len(dt.ids) == math.MaxInt32 {
+		Abortf("Input has more than %d vertices", math.MaxInt32)
+	}
+	i := int32(len(dt.ids))
+	dt.index[id] = i
+	dt.ids = append(dt.ids, id)
+	dt.hs = append(dt.hs, 0.0)
+	return i
+}
+
+// add adds a term between the vertices with two IDs, which is a field if the
+// IDs are equal and a coupler otherwise.
+func (dt *denseTerms) add(uID, vID int64, wt float64) {
+	u, v := dt.vertex(uID), dt.vertex(vID)
+	if u == v {
+		dt.hs[u] += wt
+		return
+	}
+	if u > v {
+		u, v = v, u
+	}
+	if _, dup := dt.js[[2]int32{u, v}]; dup {
+		n0, n1 := strconv.FormatInt(dt.ids[u], 10), strconv.FormatInt(dt.ids[v], 10)
+		if n0 > n1 {
+			n0, n1 = n1, n0
+		}
+		Warn("W001-duplicate-coupler", n0+" "+n1, "Coupler %s %s appears more than once; its terms are summed", n0, n1)
+	}
+	dt.js[[2]int32{u, v}] += wt
+	dt.mags[[2]int32{u, v}] += math.Abs(wt)
+}
+
+// graph returns the accumulated terms as a graph, interning vertex names in
+// nt.
+func (dt *denseTerms) graph(nt nameTable) Graph {
+	names := make([]string, len(dt.ids))
+	vs := make(map[string]float64, len(dt.ids)) // Map from a vertex to a weight
+	for i, id := range dt.ids {
+		names[i] = nt.intern(strconv.FormatInt(id, 10))
+		vs[names[i]] = dt.hs[i]
+	}
+	es := make(map[[2]string]float64, len(dt.js))     // Map from an edge to a weight
+	mags := make(map[[2]string]float64, len(dt.mags)) // Map from an edge to the magnitude of its terms
+	for e, wt := range dt.js {
+		u, v := names[e[0]], names[e[1]]
+		if u > v {
+			u, v = v, u
+		}
+		es[[2]string{u, v}] = wt
+		mags[[2]string{u, v}] = dt.mags[e]
+	}
+	return Graph{Vs: vs, Es: es, EMags: mags}
+}
+
+// readTerms returns the Ising Hamiltonian whose terms a scanner such as
+// scanQubistTerms produces.  Terms between integer vertex IDs are accumulated
+// in a denseTerms.  If a vertex with any other name appears, the terms read
+// so far are converted to a graph, to which the remaining terms are added.
+func readTerms(scan func(term func(u, v, wt string))) Graph {
+	dt := newDenseTerms()
+	nt := make(nameTable)
+	var g Graph // Graph being built once a non-integer name appears
+	scan(func(u, v, s string) {
+		wt, err := strconv.ParseFloat(s, 64)
+		CheckError(err)
+		if g.Vs == nil {
+			uID, uOK := parseVertexID(u)
+			vID, vOK := parseVertexID(v)
+			if uOK && vOK {
+				dt.add(uID, vID, wt)
+				return
+			}
+			g = dt.graph(nt)
+		}
+		u, v = nt.intern(u), nt.intern(v)
+		if u == v {
+			// Vertex
+			g.Vs[u] += wt
+			return
+		}
+
+		// Edge
+		if u > v {
+			u, v = v, u
+		}
+		if _, dup := g.Es[[2]string{u, v}]; dup {
+			Warn("W001-duplicate-coupler", u+" "+v, "Coupler %s %s appears more than once; its terms are summed", u, v)
+		}
+		g.Es[[2]string{u, v}] += wt
+		g.EMags[[2]string{u, v}] += math.Abs(wt)
+		g.Vs[u] += 0.0
+		g.Vs[v] += 0.0
+	})
+	if g.Vs == nil {
+		g = dt.graph(nt)
+	}
+	return g
+}
+
+// scanQubistTerms invokes a function on the two vertex names and the
+// textual weight of each term of a Qubist source file.  The names are
+// substrings of the line on which they appear.  Lines beginning with "#",
+// such as those WriteQubistFile writes to record provenance, are comments.
+func scanQubistTerms(r io.Reader, term func(u, v, wt string)) {
+	// Process all lines, discarding the first (header) line that is not
+	// a comment.
+	rb := bufio.NewReader(r)
+	sawHeader := false
+	for {
+		// Read one line.
+		ln, err := rb.ReadString('\n')
+		if err == io.EOF {
+			if !sawHeader {
+				CheckError(err)
+			}
+			break
+		}
+		CheckError(err)
+		if strings.HasPrefix(ln, "#") {
+			continue // Comment
+		}
+		if !sawHeader {
+			sawHeader = true
+			continue
+		}
+
+		// Parse the line.
+		fs := strings.Fields(ln)
+		if len(fs) != 3 {
+			Abortf("Failed to parse Qubist line %q", strings.TrimSpace(ln))
+		}
+		term(fs[0], fs[1], fs[2])
+	}
+}
+
+// ReadQubistFile returns the Ising Hamiltonian represented by a Qubist source
+// file.
+func ReadQubistFile(r io.Reader) Graph {
+	return readTerms(func(term func(u, v, wt string)) { scanQubistTerms(r, term) })
+}
+
+// scanQUBOTerms invokes a function on the two vertex names and the textual
+// weight of each term of a QUBO source file.  The names are substrings of
+// the line on which they appear.
+func scanQUBOTerms(r io.Reader, term func(u, v, wt string)) {
+	rb := bufio.NewReader(r)
+	for {
+		// Read one line.
+		ln, err := rb.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		CheckError(err)
+
+		// Parse the line.
+		fs := strings.Fields(ln)
+		if len(fs) == 0 {
+			continue // Blank line
+		}
+		switch fs[0] {
+		case "c":
+			continue // Comment
+		case "p":
+			if len(fs) != 6 || fs[1] != "qubo" {
+				Abortf("Failed to parse QUBO line %q", strings.TrimSpace(ln))
+			}
+			continue // Don't bother validating the problem size.
+		}
+		if len(fs) != 3 {
+			Abortf("Failed to parse QUBO line %q", strings.TrimSpace(ln))
+		}
+		term(fs[0], fs[1], fs[2])
+	}
+}
+
+// ReadQUBOFile returns the Ising Hamiltonian represented by a QUBO source file.
+func ReadQUBOFile(r io.Reader) Graph {
+	// Read a list of edges and vertices in QUBO format.
+	g := readTerms(func(term func(u, v, wt string)) { scanQUBOTerms(r, term) })
+	vs, es, mags := g.Vs, g.Es, g.EMags
+
+	// Convert from a QUBO problem to an Ising problem and return that,
+	// retaining the original coefficients.
+	qvs, qes := copyQUBO(vs, es)
+	off := quboToIsing(vs, es)
+	scaleMags(mags, 0.25)
+	return Graph{Vs: vs, Es: es, EMags: mags, Offset: off, QVs: qvs, QEs: qes}
+}
+
+// bqpjsonBatchSize is the number of bqpjson terms decoded before being
+// handed off to a worker goroutine.
+const bqpjsonBatchSize = 4096
+
+// A bqpjsonTerm is either a linear or a quadratic term in a bqpjson file.
+// Linear terms use only V.
+type bqpjsonTerm struct {
+	U      int     `json:"id_tail"` // First variable ID
+	V      int     `json:"id_head"` // Second variable ID
+	Weight float64 `json:"coeff"`   // Term weight
+}
+
+// bqpjsonLinearTerm is the JSON representation of a linear term.
+type bqpjsonLinearTerm struct {
+	V      int     `json:"id"`    // Variable ID
+	Weight float64 `json:"coeff"` // Variable weight
+}
+
+// A bqpjsonEncoding declares, in a bqpjson file's metadata, that a set of
+// variables together encode a single integer-valued variable.
+type bqpjsonEncoding struct {
+	Name      string `json:"name"`      // Name of the integer variable
+	Type      string `json:"type"`      // "one-hot" or "domain-wall"
+	Variables []int  `json:"variables"` // IDs of the encoding's variables
+}
+
+// encodingEdgeKinds labels each edge as an encoding coupler if both endpoints
+// belong to the same integer-variable encoding or as logical otherwise.
+func (g Graph) encodingEdgeKinds(encs []bqpjsonEncoding) map[[2]string]string {
+	v2enc := make(map[string]string)
+	for _, enc := range encs {
+		switch enc.Type {
+		case "one-hot", "domain-wall":
+		default:
+			Abortf("Unrecognized encoding type %q for integer variable %q (expected \"one-hot\" or \"domain-wall\")", enc.Type, enc.Name)
+		}
+		for _, id := range enc.Variables {
+			v := strconv.Itoa(id)
+			if _, ok := g.Vs[v]; !ok {
+				Abortf("Integer variable %q is encoded using nonexistent variable %s", enc.Name, v)
+			}
+			if other, ok := v2enc[v]; ok && other != enc.Name {
+				Abortf("Variable %s appears in the encodings of both %q and %q", v, other, enc.Name)
+			}
+			v2enc[v] = enc.Name
+		}
+	}
+	kinds := make(map[[2]string]string, len(g.Es))
+	for e := range g.Es {
+		u, uOK := v2enc[e[0]]
+		v, vOK := v2enc[e[1]]
+		if uOK && vOK && u == v {
+			kinds[e] = EdgeEncoding
+		} else {
+			kinds[e] = EdgeLogical
+		}
+	}
+	return kinds
+}
+
+// jsonDelim consumes the next token from a JSON stream and aborts if it is
+// not the given delimiter.
+func jsonDelim(dec *json.Decoder, d json.Delim) {
+	tok, err := dec.Token()
+	CheckError(err)
+	if tok != d {
+		Abortf("Expected %q but saw %v in bqpjson input", d, tok)
+	}
+}
+
+// skipJSONValue consumes the next value from a JSON stream without storing
+// it.
+func skipJSONValue(dec *json.Decoder) {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		CheckError(err)
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return
+		}
+	}
+}
+
+// worker returns which of nw workers accumulates a term: a function of the
+// term's variable or, for a quadratic term, its unordered pair of variables.
+func (t bqpjsonTerm) worker(linear bool, nw int) int {
+	if linear {
+		return int(uint(t.V) % uint(nw))
+	}
+	u, v := uint(t.U), uint(t.V)
+	if u > v {
+		u, v = v, u
+	}
+	return int((u*0x9e3779b9 ^ v) % uint(nw))
+}
+
+// ingestBqpjsonTerms streams a JSON array of linear (if linear is true) or
+// quadratic terms into the given vertex and edge maps, which are keyed by
+// variable ID, recording in mags the total magnitude of the terms summed into
+// each edge.  Decoding happens sequentially, but batches of decoded terms are
+// accumulated into per-worker maps in parallel and merged at the end.  Each
+// variable or pair of variables is assigned to a single worker, so repeated
+// terms are always summed in the order in which they appear.
+func ingestBqpjsonTerms(dec *json.Decoder, linear bool, vs map[int]float64, es, mags map[[2]int]float64) {
+	// Launch one worker per CPU, each with its own maps and its own queue
+	// of batches.
+	type partial struct {
+		vs   map[int]float64
+		es   map[[2]int]float64
+		mags map[[2]int]float64
+	}
+	nw := runtime.NumCPU()
+	queues := make([]chan []bqpjsonTerm, nw)
+	parts := make([]partial, nw)
+	var wg sync.WaitGroup
+	for i := range parts {
+		queues[i] = make(chan []bqpjsonTerm, 1)
+		parts[i] = partial{vs: make(map[int]float64), es: make(map[[2]int]float64), mags: make(map[[2]int]float64)}
+		wg.Add(1)
+		go func(p partial, batches <-chan []bqpjsonTerm) {
+			defer wg.Done()
+			for b := range batches {
+				for _, t := range b {
+					if linear {
+						p.vs[t.V] += t.Weight
+						continue
+					}
+					u, v := t.U, t.V
+					if u > v {
+						u, v = v, u
+					}
+					p.es[[2]int{u, v}] += t.Weight
+					p.mags[[2]int{u, v}] += math.Abs(t.Weight)
+					p.vs[u] += 0.0
+					p.vs[v] += 0.0
+				}
+			}
+		}(parts[i], queues[i])
+	}
+
+	// Decode the array one term at a time, handing each term to the
+	// worker responsible for its variables.  The workers are stopped even
+	// if decoding fails.
+	func() {
+		defer func() {
+			for _, q := range queues {
+				close(q)
+			}
+			wg.Wait()
+		}()
+		jsonDelim(dec, json.Delim('['))
+		bs := make([][]bqpjsonTerm, nw)
+		for dec.More() {
+			var t bqpjsonTerm
+			if linear {
+				var lt bqpjsonLinearTerm
+				CheckError(dec.Decode(&lt))
+				t = bqpjsonTerm{V: lt.V, Weight: lt.Weight}
+			} else {
+				CheckError(dec.Decode(&t))
+			}
+			w := t.worker(linear, nw)
+			bs[w] = append(bs[w], t)
+			if len(bs[w]) == bqpjsonBatchSize {
+				queues[w] <- bs[w]
+				bs[w] = nil
+			}
+		}
+		jsonDelim(dec, json.Delim(']'))
+		for w, b := range bs {
+			if len(b) > 0 {
+				queues[w] <- b
+			}
+		}
+	}()
+
+	// Merge the workers' maps.
+	for _, p := range parts {
+		for v, wt := range p.vs {
+			vs[v] += wt
+		}
+		for e, wt := range p.es {
+			es[e] += wt
+		}
+		for e, m := range p.mags {
+			mags[e] += m
+		}
+	}
+}
+
+// ReadBqpjsonFile returns the Ising Hamiltonian represented by a bqpjson
+// source file (cf. https://github.com/lanl-ansi/bqpjson).  The input is
+// walked token by token so that large term lists are never held in memory all
+// at once.  Integer variables declared in the metadata as one-hot or
+// domain-wall encodings of spin or Boolean variables cause the couplers
+// within each encoding to be labeled as such.
+func ReadBqpjsonFile(r io.Reader) Graph {
+	// Process only the parts of the bqpjson format in which we're
+	// interested.
+	var (
+		varDomain string                     // "spin" or "boolean"
+		encs      []bqpjsonEncoding          // Integer-variable encodings
+		names     map[int]string             // Map from a variable ID to its metadata name
+		scale     float64                    // Scale factor for all coefficients
+		offset    float64                    // Constant energy term
+		idVs      = make(map[int]float64)    // Map from a variable ID to a weight
+		idEs      = make(map[[2]int]float64) // Map from a pair of variable IDs to a weight
+		idMags    = make(map[[2]int]float64) // Map from a pair of variable IDs to the magnitude of its terms
+	)
+	dec := json.NewDecoder(r)
+	jsonDelim(dec, json.Delim('{'))
+	for dec.More() {
+		tok, err := dec.Token()
+		CheckError(err)
+		switch tok {
+		case "variable_domain":
+			CheckError(dec.Decode(&varDomain))
+		case "scale":
+			CheckError(dec.Decode(&scale))
+		case "offset":
+			CheckError(dec.Decode(&offset))
+		case "metadata":
+			var md struct {
+				Encodings []bqpjsonEncoding `json:"encodings"`
+				VarNames  map[string]string `json:"var_names"`
+			}
+			CheckError(dec.Decode(&md))
+			encs = md.Encodings
+			if md.VarNames != nil {
+				names = make(map[int]string, len(md.VarNames))
+				for id, n := range md.VarNames {
+					i, err := strconv.Atoi(id)
+					if err != nil {
+						Abortf("Metadata var_names key %q is not an integer variable ID", id)
+					}
+					names[i] = n
+				}
+			}
+		case "linear_terms":
+			ingestBqpjsonTerms(dec, true, idVs, idEs, idMags)
+		case "quadratic_terms":
+			ingestBqpjsonTerms(dec, false, idVs, idEs, idMags)
+		default:
+			skipJSONValue(dec)
+		}
+	}
+	jsonDelim(dec, json.Delim('}'))
+
+	// Name each vertex by its variable ID, formatting each ID only once.
+	vs := make(map[string]float64, len(idVs)) // Map from a vertex to a weight
+	ids := make(map[string]int, len(idVs))    // Map from a vertex to its variable ID
+	vNames := make(map[int]string, len(idVs)) // Map from a variable ID to its vertex
+	vName := func(id int) string {
+		v, ok := vNames[id]
+		if !ok {
+			v = strconv.Itoa(id)
+			vNames[id] = v
+			ids[v] = id
+			vs[v] += 0.0
+		}
+		return v
+	}
+	for id, wt := range idVs {
+		vs[vName(id)] += wt
+	}
+	es := make(map[[2]string]float64, len(idEs))     // Map from an edge to a weight
+	mags := make(map[[2]string]float64, len(idMags)) // Map from an edge to the magnitude of its terms
+	for e, wt := range idEs {
+		u, v := vName(e[0]), vName(e[1])
+		if u > v {
+			u, v = v, u
+		}
+		es[[2]string{u, v}] += wt
+		mags[[2]string{u, v}] += idMags[e]
+	}
+
+	// Multiply all weights by the scale parameter.  The offset parameter
+	// is a constant term, which is likewise scaled.
+	for v, wt := range vs {
+		vs[v] = wt * scale
+	}
+	for v, wt := range es {
+		es[v] = wt * scale
+	}
+	scaleMags(mags, scale)
+	off := offset * scale
+
+	// Convert from QUBO to Ising if the problem was specified as QUBO,
+	// retaining the original coefficients.
+	var qvs map[string]float64
+	var qes map[[2]string]float64
+	switch varDomain {
+	case "boolean":
+		qvs, qes = copyQUBO(vs, es)
+		off += quboToIsing(vs, es)
+		scaleMags(mags, 0.25)
+	case "spin":
+	case "":
+		Abortf("bqpjson input is missing a variable_domain")
+	default:
+		Abortf("Unsupported variable_domain %q; only \"spin\" and \"boolean\" are supported, but an integer variable can be expressed as a one-hot or domain-wall encoding of spin or Boolean variables declared in the metadata's \"encodings\" list", varDomain)
+	}
+
+	// Return the resulting graph, recording each vertex's variable ID and
+	// labeling the couplers within each integer-variable encoding.
+	g := Graph{Vs: vs, Es: es, EMags: mags, Offset: off, QVs: qvs, QEs: qes, VarIDs: ids, VarNames: names}
+	if len(encs) > 0 {
+		g.EKind = g.encodingEdgeKinds(encs)
+	}
+	return g
+}
+
+// WithVarNames returns a copy of a bqpjson graph in which each vertex is
+// renamed to the name its variable ID is given in the input's metadata.
+// Vertices whose variable has no name keep their ID as their name.
+func (g Graph) WithVarNames() Graph {
+	if g.VarIDs == nil {
+		Abortf("Only bqpjson input can supply variable names")
+	}
+	rename := make(map[string]string, len(g.Vs))
+	seen := make(map[string]string, len(g.Vs))
+	for v := range g.Vs {
+		n, ok := g.VarNames[g.VarIDs[v]]
+		if !ok {
+			n = v
+		}
+		if other, dup := seen[n]; dup {
+			Abortf("Variables %s and %s are both named %q", other, v, n)
+		}
+		seen[n] = v
+		rename[v] = n
+	}
+	edge := func(e [2]string) [2]string {
+		u, v := rename[e[0]], rename[e[1]]
+		if u > v {
+			u, v = v, u
+		}
+		return [2]string{u, v}
+	}
+	h := Graph{
+		Vs:       make(map[string]float64, len(g.Vs)),
+		Es:       make(map[[2]string]float64, len(g.Es)),
+		Offset:   g.Offset,
+		VarIDs:   make(map[string]int, len(g.VarIDs)),
+		VarNames: g.VarNames,
+	}
+	for v, wt := range g.Vs {
+		h.Vs[rename[v]] = wt
+		h.VarIDs[rename[v]] = g.VarIDs[v]
+	}
+	for e, wt := range g.Es {
+		h.Es[edge(e)] = wt
+	}
+	if g.EKind != nil {
+		h.EKind = make(map[[2]string]string, len(g.EKind))
+		for e, k := range g.EKind {
+			h.EKind[edge(e)] = k
+		}
+	}
+	if g.EMags != nil {
+		h.EMags = make(map[[2]string]float64, len(g.EMags))
+		for e, m := range g.EMags {
+			h.EMags[edge(e)] = m
+		}
+	}
+	if g.QVs != nil {
+		h.QVs = make(map[string]float64, len(g.QVs))
+		for v, wt := range g.QVs {
+			h.QVs[rename[v]] = wt
+		}
+		h.QEs = make(map[[2]string]float64, len(g.QEs))
+		for e, wt := range g.QEs {
+			h.QEs[edge(e)] = wt
+		}
+	}
+	return h
+}
+
+// ReadBqpjsonBatch reads a JSON array of problems and invokes a function on
+// each problem's ID and graph in turn.  Each array element is either a
+// bqpjson document, identified by its "id" field, or an object with an "id"
+// field and a "problem" field containing a bqpjson document.  Elements
+// lacking an ID are identified by their position in the array.
+func ReadBqpjsonBatch(r io.Reader, f func(id string, g Graph)) {
+	dec := json.NewDecoder(r)
+	jsonDelim(dec, json.Delim('['))
+	for i := 0; dec.More(); i++ {
+		// Read the next problem in its entirety.
+		var raw json.RawMessage
+		CheckError(dec.Decode(&raw))
+		var hdr struct {
+			ID      json.RawMessage `json:"id"`
+			Problem json.RawMessage `json:"problem"`
+		}
+		CheckError(json.Unmarshal(raw, &hdr))
+
+		// Determine the problem's ID.
+		id := strconv.Itoa(i)
+		if len(hdr.ID) > 0 {
+			if err := json.Unmarshal(hdr.ID, &id); err != nil {
+				id = string(hdr.ID) // Numeric ID
+			}
+		}
+
+		// Parse the problem.
+		if len(hdr.Problem) > 0 {
+			raw = hdr.Problem
+		}
+		f(id, ReadBqpjsonFile(bytes.NewReader(raw)))
+	}
+	jsonDelim(dec, json.Delim(']'))
+}
+
+// ReadFFGFile returns the graph stored in find-frustration's binary graph
+// format (cf. WriteFFGFile).
+func ReadFFGFile(r io.Reader) Graph {
+	dec := gob.NewDecoder(r)
+	var hdr ffgHeader
+	if err := dec.Decode(&hdr); err != nil || hdr.Magic != ffgMagic {
+		Abortf("Input is not a find-frustration graph file")
+	}
+	if hdr.Version > ffgVersion {
+		Abortf("Graph file version %d is newer than the supported version %d", hdr.Version, ffgVersion)
+	}
+	var g Graph
+	CheckError(dec.Decode(&g))
+	return g
+}
+
+// ReadEmbedding reads a minor embedding represented as a JSON object that
+// maps each logical variable to a list of physical qubits (as produced, for
+// example, by minorminer) and returns a map from each qubit to its logical
+// variable.
+func ReadEmbedding(r io.Reader) map[string]string {
+	var emb map[string][]json.Number
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	CheckError(dec.Decode(&emb))
+	q2v := make(map[string]string)
+	for v, qs := range emb {
+		for _, q := range qs {
+			if other, ok := q2v[q.String()]; ok && other != v {
+				Abortf("Qubit %s is embedded in both %q and %q", q, other, v)
+			}
+			q2v[q.String()] = v
+		}
+	}
+	return q2v
+}
+
+// ReadSubset reads a set of variable names separated by whitespace.  Text
+// from a "#" to the end of a line is a comment.
+func ReadSubset(r io.Reader) map[string]Empty {
+	sub := make(map[string]Empty)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		ln := sc.Text()
+		if i := strings.Index(ln, "#"); i >= 0 {
+			ln = ln[:i]
+		}
+		for _, v := range strings.Fields(ln) {
+			sub[v] = Empty{}
+		}
+	}
+	CheckError(sc.Err())
+	return sub
+}
+
+// ReadGraph reads a graph in the named format, subject to the given limits.
+func ReadGraph(inFmt string, r io.Reader, lim InputLimits) Graph {
+	return lim.Parse(LookupParser(inFmt), inFmt, r)
+}
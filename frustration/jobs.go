@@ -0,0 +1,383 @@
+/* This file implements an asynchronous job queue that lets server clients
+submit long-running analyses and poll for their results. */
+
+package frustration
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	mrand "math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job statuses
+const (
+	JobQueued  = "queued"  // Waiting for a free worker
+	JobRunning = "running" // Being analyzed
+	JobDone    = "done"    // Completed successfully
+	JobFailed  = "failed"  // Completed unsuccessfully
+)
+
+// A Job is a single analysis submitted to a JobQueue.
+type Job struct {
+	ID        string    `json:"id"`                // Unique job identifier
+	Status    string    `json:"status"`            // One of the Job* status constants
+	Format    string    `json:"format"`            // Input format
+	AllCycles bool      `json:"all_cycles"`        // true if elementary cycles are analyzed
+	Seed      int64     `json:"seed"`              // Seed of the job's pseudorandom choices
+	Submitted time.Time `json:"submitted"`         // Time at which the job was submitted
+	Finished  time.Time `json:"finished"`          // Time at which the job completed
+	Error     string    `json:"error,omitempty"`   // Reason for failure
+	Results   *Results  `json:"results,omitempty"` // Analysis results
+	Owner     string    `json:"owner,omitempty"`   // Hash of the submitter's access token
+}
+
+// tokenHash returns the hash by which an access token is recorded.
+func tokenHash(tok string) string {
+	if tok == "" {
+		return ""
+	}
+	h := sha256.Sum256([]byte(tok))
+	return hex.EncodeToString(h[:])
+}
+
+// A JobQueue runs submitted jobs in the background, a bounded number at a
+// time, and persists each job's status and results to a directory so they
+// survive a server restart.
+type JobQueue struct {
+	Dir         string        // Directory in which jobs are persisted
+	MaxInput    int64         // Maximum size in bytes of a job's input
+	CycleBudget float64       // Maximum estimated number of elementary cycles per job
+	Seed        int64         // Seed for every job's pseudorandom choices (0 for the clock)
+	Limits      InputLimits   // Bounds on what a job's input may contain
+	MaxTime     time.Duration // Maximum time a job's cycle computations may run (0 for no limit)
+	Retention   time.Duration // Time for which finished jobs are kept (0 for forever)
+	slots       chan struct{} // One token per concurrently running job
+	key         []byte        // Secret key from which redacted names are derived
+	mu          sync.Mutex    // Protects jobs
+	jobs        map[string]*Job
+}
+
+// NewJobQueue creates a job queue that persists jobs to a given directory
+// and runs at most maxJobs of them at once.  Any unfinished jobs found in the
+// directory are resumed, and finished jobs older than retention are deleted,
+// both now and periodically thereafter.
+func NewJobQueue(dir string, maxJobs int, maxInput int64, budget float64, seed int64, lim InputLimits, maxTime, retention time.Duration) *JobQueue {
+	if maxJobs < 1 {
+		Abortf("At least one concurrent job must be allowed")
+	}
+	CheckError(os.MkdirAll(dir, 0755))
+	q := &JobQueue{
+		Dir:         dir,
+		MaxInput:    maxInput,
+		CycleBudget: budget,
+		Seed:        seed,
+		Limits:      lim,
+		MaxTime:     maxTime,
+		Retention:   retention,
+		slots:       make(chan struct{}, maxJobs),
+		jobs:        make(map[string]*Job),
+		key:         loadRedactionKey(filepath.Join(dir, "redaction.key")),
+	}
+
+	// Reload previously submitted jobs, requeuing those that never
+	// finished.
+	fns, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	CheckError(err)
+	for _, fn := range fns {
+		data, err := ioutil.ReadFile(fn)
+		CheckError(err)
+		var j Job
+		if err := json.Unmarshal(data, &j); err != nil {
+			Warn("W010-unreadable-job", fn, "Ignoring unreadable job file %s (%v)", fn, err)
+			continue
+		}
+		q.jobs[j.ID] = &j
+		if j.Status == JobQueued || j.Status == JobRunning {
+			j.Status = JobQueued
+			go q.run(&j)
+		}
+	}
+	if retention > 0 {
+		q.expire(time.Now())
+		go func() {
+			for now := range time.Tick(jobSweepInterval) {
+				q.expire(now)
+			}
+		}()
+	}
+	return q
+}
+
+// jobSweepInterval is the interval at which expired jobs are deleted.
+const jobSweepInterval = time.Minute
+
+// expire deletes every job that finished more than q.Retention before a
+// given time, along with its files.  Because it runs in the background, it
+// logs rather than aborts on failure to delete a file.
+func (q *JobQueue) expire(now time.Time) {
+	var ids []string
+	q.mu.Lock()
+	for id, j := range q.jobs {
+		if (j.Status == JobDone || j.Status == JobFailed) && now.Sub(j.Finished) > q.Retention {
+			delete(q.jobs, id)
+			ids = append(ids, id)
+		}
+	}
+	q.mu.Unlock()
+	for _, id := range ids {
+		for _, ext := range []string{".json", ".input"} {
+			if err := os.Remove(q.path(id, ext)); err != nil && !os.IsNotExist(err) {
+				Notify.Printf("Failed to delete expired job %s (%v)", id, err)
+			}
+		}
+	}
+}
+
+// A deadline is the time after which a job's cycle computations abort.  The
+// zero deadline never passes.
+type deadline time.Time
+
+// check aborts the current operation if the deadline has passed.
+func (d deadline) check() {
+	if t := time.Time(d); !t.IsZero() && time.Now().After(t) {
+		Abortf("Aborting because the job exceeded its --job-timeout limit")
+	}
+}
+
+// loadRedactionKey reads the secret key used to derive redacted names from a
+// file, first creating the file with a random key if it does not exist.
+// Keeping the key across restarts keeps a job's redacted names stable.
+func loadRedactionKey(fn string) []byte {
+	key, err := ioutil.ReadFile(fn)
+	if err == nil {
+		return key
+	}
+	if !os.IsNotExist(err) {
+		CheckError(err)
+	}
+	key = make([]byte, 32)
+	_, err = rand.Read(key)
+	CheckError(err)
+	CheckError(ioutil.WriteFile(fn, key, 0600))
+	return key
+}
+
+// alias returns the redacted name of a vertex in a given job.  Aliases are
+// keyed by a server secret, so they cannot be inverted by guessing names,
+// and by the job ID, so they cannot be correlated across jobs.
+func (q *JobQueue) alias(id, v string) string {
+	mac := hmac.New(sha256.New, q.key)
+	mac.Write([]byte(id))
+	mac.Write([]byte{0})
+	mac.Write([]byte(v))
+	return "v" + hex.EncodeToString(mac.Sum(nil)[:6])
+}
+
+// path returns the name of a file associated with a job.
+func (q *JobQueue) path(id, ext string) string {
+	return filepath.Join(q.Dir, id+ext)
+}
+
+// save persists a job's current state.  The job file is replaced atomically
+// so that a crash never leaves it half written.
+func (q *JobQueue) save(j *Job) error {
+	q.mu.Lock()
+	data, err := json.Marshal(j)
+	q.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := q.path(j.ID, ".tmp")
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path(j.ID, ".json"))
+}
+
+// Submit stores a job's input, queues the job for analysis, and returns a
+// copy of the newly queued job.  owner is the submitter's access token, if
+// any.  Submit aborts if format is not one that may be accepted from network
+// clients.
+func (q *JobQueue) Submit(r io.Reader, format string, allCycs bool, owner string) Job {
+	checkUntrustedFormat(format)
+
+	// Assign the job a random ID.
+	var buf [16]byte
+	_, err := rand.Read(buf[:])
+	CheckError(err)
+	j := &Job{
+		ID:        hex.EncodeToString(buf[:]),
+		Status:    JobQueued,
+		Format:    format,
+		AllCycles: allCycs,
+		Submitted: time.Now(),
+		Seed:      q.Seed,
+		Owner:     tokenHash(owner),
+	}
+
+	// Store the input, refusing inputs that are too large.
+	f, err := os.Create(q.path(j.ID, ".input"))
+	CheckError(err)
+	n, err := io.Copy(f, io.LimitReader(r, q.MaxInput+1))
+	CheckError(f.Close())
+	if err != nil || n > q.MaxInput {
+		os.Remove(q.path(j.ID, ".input"))
+		CheckError(err)
+		Abortf("Job input exceeds the limit of %d bytes", q.MaxInput)
+	}
+
+	// Queue the job, recording its seed so its analysis can be
+	// reproduced.
+	if j.Seed == 0 {
+		j.Seed = time.Now().UnixNano()
+	}
+	q.mu.Lock()
+	q.jobs[j.ID] = j
+	q.mu.Unlock()
+	CheckError(q.save(j))
+	sub := *j
+	go q.run(j)
+	return sub
+}
+
+// Lookup returns a copy of the job with a given ID or false if there is no
+// such job.
+func (q *JobQueue) Lookup(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *j, true
+}
+
+// setStatus updates a job's status and persists the change.  Because it runs
+// in the background, it logs rather than aborts on failure to persist.
+func (q *JobQueue) setStatus(j *Job, status string, res *Results, err error) {
+	q.mu.Lock()
+	j.Status = status
+	j.Results = res
+	j.Error = ""
+	if err != nil {
+		j.Error = err.Error()
+	}
+	if status == JobDone || status == JobFailed {
+		j.Finished = time.Now()
+	}
+	q.mu.Unlock()
+	if err := q.save(j); err != nil {
+		Notify.Printf("Failed to save job %s (%v)", j.ID, err)
+	}
+}
+
+// run waits for a free worker slot then analyzes a job.
+func (q *JobQueue) run(j *Job) {
+	q.slots <- struct{}{}
+	defer func() { <-q.slots }()
+	q.setStatus(j, JobRunning, nil, nil)
+	var res Results
+	err := func() (err error) {
+		defer RecoverFatal(&err)
+		f, err := os.Open(q.path(j.ID, ".input"))
+		CheckError(err)
+		defer f.Close()
+		res = q.analyze(j, f)
+		return nil
+	}()
+	if err != nil {
+		q.setStatus(j, JobFailed, nil, err)
+		return
+	}
+	q.setStatus(j, JobDone, &res, nil)
+}
+
+// analyze performs a job's frustration analysis, refusing to combine base
+// cycles if doing so would likely exceed the queue's cycle budget and
+// abandoning cycle computations that run longer than q.MaxTime.
+func (q *JobQueue) analyze(j *Job, r io.Reader) Results {
+	checkUntrustedFormat(j.Format)
+	g := ReadGraph(j.Format, r, q.Limits)
+	if q.MaxTime > 0 {
+		g.deadline = deadline(time.Now().Add(q.MaxTime))
+	}
+	rng := mrand.New(mrand.NewSource(j.Seed))
+	bcs, ecs, _ := g.findCycles(false, rng)
+	if j.AllCycles && len(bcs) > 0 {
+		est := g.estimateElementaryCycles(bcs, rng)
+		if est > q.CycleBudget {
+			Abortf("An estimated %.3g elementary cycles (from %d base cycles) exceeds the per-job budget of %.3g", est, len(bcs), q.CycleBudget)
+		}
+		ecs, _ = g.dedupCycles(g.elementaryCycles(bcs, CombineRule{}))
+	}
+	return AnalyzeGraph(g, bcs, ecs, signParity, j.AllCycles, ClassRules{})
+}
+
+// handleSubmitJob queues the problem provided in the request body for
+// asynchronous analysis and responds with the new job.  The "format" query
+// parameter names the input format (default: "qubist"), and "all_cycles=true"
+// requests analysis of elementary cycles.
+func (s *Server) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s requires POST", r.URL.Path))
+		return
+	}
+	// Bound the body by the job queue's limit, too, so that an oversize
+	// job is reported as such rather than as a bad request.
+	var j Job
+	body := s.limitBody(w, r, s.Jobs.MaxInput)
+	err := func() (err error) {
+		defer RecoverFatal(&err)
+		q := r.URL.Query()
+		inFmt := q.Get("format")
+		if inFmt == "" {
+			inFmt = "qubist"
+		}
+		j = s.Jobs.Submit(body, inFmt, q.Get("all_cycles") == "true", bearerToken(r))
+		return nil
+	}()
+	if err != nil {
+		writeError(w, body.status(), err)
+		return
+	}
+	j.Owner = ""
+	w.Header().Set("Location", "/jobs/"+j.ID)
+	writeJSON(w, http.StatusAccepted, j)
+}
+
+// handleGetJob responds with the status and, once available, the results
+// of the job named in the request path.  If the server redacts names, only
+// the job's owner, identified by the access token with which the job was
+// submitted, sees the original vertex names; all other callers see aliases.
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s requires GET", r.URL.Path))
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	j, ok := s.Jobs.Lookup(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such job %q", id))
+		return
+	}
+	tok := tokenHash(bearerToken(r))
+	isOwner := j.Owner != "" && hmac.Equal([]byte(tok), []byte(j.Owner))
+	if s.Redact && !isOwner && j.Results != nil {
+		red := j.Results.Redacted(func(v string) string { return s.Jobs.alias(j.ID, v) })
+		j.Results = &red
+	}
+	j.Owner = ""
+	writeJSON(w, http.StatusOK, j)
+}
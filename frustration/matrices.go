@@ -0,0 +1,116 @@
+/* This file exports a graph's signed adjacency matrix and its cycles'
+cycle-edge incidence matrix in Matrix Market format so that users can perform
+their own linear-algebraic analyses of a problem, such as computing ranks,
+null spaces, or spectra in MATLAB or SciPy. */
+
+package frustration
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// writeMatrixMarketHeader writes the banner, a comment, the provenance (if
+// non-nil) as further comments, and the size line of a Matrix Market file in
+// coordinate format.
+func writeMatrixMarketHeader(w io.Writer, symmetry, comment string, prov *Provenance, rows, cols, nnz int) {
+	fmt.Fprintf(w, "%%%%MatrixMarket matrix coordinate integer %s\n", symmetry)
+	fmt.Fprintf(w, "%% %s\n", comment)
+	if prov != nil {
+		prov.WriteText(w, "%")
+	}
+	fmt.Fprintf(w, "%d %d %d\n", rows, cols, nnz)
+}
+
+// WriteSignedAdjacency writes a graph's signed adjacency matrix in Matrix
+// Market format.  Row and column i correspond to vertex vs[i].  Each entry is
+// +1 for a ferromagnetic coupling and -1 for an antiferromagnetic coupling,
+// as determined by the coupler or, if they dominate, by the external fields.
+// As the matrix is symmetric, only entries on or below the diagonal are
+// written.  prov, if non-nil, is recorded in the header's comments.
+func WriteSignedAdjacency(w io.Writer, g Graph, vs []string, prov *Provenance) {
+	idx := make(map[string]int, len(vs))
+	for i, v := range vs {
+		idx[v] = i + 1
+	}
+	es := g.sortedEdges()
+	writeMatrixMarketHeader(w, "symmetric", "Signed adjacency matrix written by find-frustration", prov, len(vs), len(vs), len(es))
+	for _, e := range es {
+		s, _ := g.couplingSign(e[0], e[1])
+		i, j := idx[e[0]], idx[e[1]]
+		if i < j {
+			i, j = j, i
+		}
+		fmt.Fprintf(w, "%d %d %d\n", i, j, s)
+	}
+}
+
+// WriteCycleIncidence writes the cycle-edge incidence matrix of a set of
+// cycles, given as vertex paths, in Matrix Market format.  Row i corresponds
+// to cycle ps[i] and column j to edge es[j].  Each entry is +1 if the cycle
+// traverses the edge from its first to its second vertex and -1 if it
+// traverses the edge in the opposite direction, so the rows lie in the
+// graph's cycle space over the reals.  prov, if non-nil, is recorded in the
+// header's comments.
+func WriteCycleIncidence(w io.Writer, es [][2]string, ps [][]string, prov *Provenance) {
+	idx := make(map[[2]string]int, len(es))
+	for j, e := range es {
+		idx[e] = j + 1
+	}
+	nnz := 0
+	for _, p := range ps {
+		nnz += len(p)
+	}
+	writeMatrixMarketHeader(w, "general", "Cycle-edge incidence matrix written by find-frustration", prov, len(ps), len(es), nnz)
+	for i, p := range ps {
+		for k, u := range p {
+			v := p[(k+1)%len(p)]
+			dir := 1
+			if u > v {
+				u, v = v, u
+				dir = -1
+			}
+			fmt.Fprintf(w, "%d %d %d\n", i+1, idx[[2]string{u, v}], dir)
+		}
+	}
+}
+
+// createMatrixFile creates a file, passes a buffered writer for it to a
+// function that writes its contents, and closes it.
+func createMatrixFile(name string, write func(w io.Writer)) {
+	f, err := os.Create(name)
+	CheckError(err)
+	bw := bufio.NewWriter(f)
+	write(bw)
+	CheckError(bw.Flush())
+	CheckError(f.Close())
+}
+
+// WriteMatrices writes a graph's signed adjacency matrix and its cycles'
+// incidence matrix to prefix-adjacency.mtx and prefix-incidence.mtx and the
+// vertex and edge names that label their rows and columns to
+// prefix-vertices.txt and prefix-edges.txt, one per line.  prov, if non-nil,
+// is recorded in the two matrix files' comments.  The name files carry no
+// provenance so that line i names row or column i.
+func WriteMatrices(prefix string, g Graph, ps [][]string, prov *Provenance) {
+	vs := g.sortedVertices()
+	es := g.sortedEdges()
+	createMatrixFile(prefix+"-adjacency.mtx", func(w io.Writer) {
+		WriteSignedAdjacency(w, g, vs, prov)
+	})
+	createMatrixFile(prefix+"-incidence.mtx", func(w io.Writer) {
+		WriteCycleIncidence(w, es, ps, prov)
+	})
+	createMatrixFile(prefix+"-vertices.txt", func(w io.Writer) {
+		for _, v := range vs {
+			fmt.Fprintln(w, v)
+		}
+	})
+	createMatrixFile(prefix+"-edges.txt", func(w io.Writer) {
+		for _, e := range es {
+			fmt.Fprintf(w, "%s %s\n", e[0], e[1])
+		}
+	})
+}
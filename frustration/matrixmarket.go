@@ -0,0 +1,144 @@
+/* This file reads symmetric sparse matrices in Matrix Market coordinate format,
+in which many spin-glass benchmark sets are distributed. */
+
+package frustration
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// mtxHeader is the banner that begins every Matrix Market file.
+const mtxHeader = "%%MatrixMarket"
+
+// ReadMatrixMarketFile returns the Ising Hamiltonian represented by a square
+// matrix in Matrix Market coordinate format.  Each diagonal entry is the
+// field on a spin and each off-diagonal entry the strength of a coupler.
+// Vertices are named by their 1-based row indices, and rows with no entries
+// are omitted.  The matrix must be symmetric: a "symmetric" matrix stores one
+// entry per coupler, and a "general" matrix may store both of a coupler's
+// entries provided that they are equal.  Entries of a "pattern" matrix have
+// value 1.
+func ReadMatrixMarketFile(r io.Reader) Graph {
+	// Parse the banner.
+	rb := bufio.NewReader(r)
+	ln, err := rb.ReadString('\n')
+	if err != nil && err != io.EOF {
+		CheckError(err)
+	}
+	fs := strings.Fields(strings.ToLower(ln))
+	if len(fs) != 5 || fs[0] != strings.ToLower(mtxHeader) || fs[1] != "matrix" {
+		Abortf("Failed to parse Matrix Market banner %q", strings.TrimSpace(ln))
+	}
+	if fs[2] != "coordinate" {
+		Abortf("Matrix Market input must be in coordinate format, not %q", fs[2])
+	}
+	field, symm := fs[3], fs[4]
+	switch field {
+	case "real", "double", "integer", "pattern":
+	default:
+		Abortf("Matrix Market input must have real, integer, or pattern entries, not %q", field)
+	}
+	switch symm {
+	case "symmetric", "general":
+	default:
+		Abortf("Matrix Market input must be symmetric or general, not %q", symm)
+	}
+
+	// Read the size line and then one entry per line.
+	vs := make(map[string]float64)        // Map from a vertex to a weight
+	es := make(map[[2]string]float64)     // Map from an edge to a weight
+	mags := make(map[[2]string]float64)   // Map from an edge to the magnitude of its terms
+	stored := make(map[[2]string]float64) // Map from a stored (row, column) pair to its value, for general matrices
+	nt := make(nameTable)
+	n, nnz, seen := -1, 0, 0
+	for lnum := 2; ; lnum++ {
+		ln, err := rb.ReadString('\n')
+		if err == io.EOF && ln == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			CheckError(err)
+		}
+		fs := strings.Fields(ln)
+		if len(fs) == 0 || strings.HasPrefix(fs[0], "%") {
+			continue // Blank line or comment
+		}
+		if n < 0 {
+			// Size line
+			var dims [3]int
+			if len(fs) != 3 {
+				Abortf("Failed to parse Matrix Market size line %q", strings.TrimSpace(ln))
+			}
+			for i, f := range fs {
+				dims[i], err = strconv.Atoi(f)
+				if err != nil || dims[i] < 0 {
+					Abortf("Failed to parse Matrix Market size line %q", strings.TrimSpace(ln))
+				}
+			}
+			if dims[0] != dims[1] {
+				Abortf("Matrix Market input must be square but is %d×%d", dims[0], dims[1])
+			}
+			n, nnz = dims[0], dims[2]
+			continue
+		}
+
+		// Entry line
+		if (field == "pattern" && len(fs) != 2) || (field != "pattern" && len(fs) != 3) {
+			Abortf("Failed to parse Matrix Market line %d, %q", lnum, strings.TrimSpace(ln))
+		}
+		var ij [2]int
+		for k := range ij {
+			ij[k], err = strconv.Atoi(fs[k])
+			if err != nil || ij[k] < 1 || ij[k] > n {
+				Abortf("Matrix Market line %d has an index outside [1, %d]: %q", lnum, n, strings.TrimSpace(ln))
+			}
+		}
+		wt := 1.0
+		if field != "pattern" {
+			wt, err = strconv.ParseFloat(fs[2], 64)
+			if err != nil {
+				Abortf("Failed to parse Matrix Market line %d, %q", lnum, strings.TrimSpace(ln))
+			}
+		}
+		seen++
+		u, v := nt.intern(strconv.Itoa(ij[0])), nt.intern(strconv.Itoa(ij[1]))
+		if u == v {
+			// Vertex
+			vs[u] += wt
+			continue
+		}
+
+		// Edge.  A general matrix may store a coupler as both (i, j)
+		// and (j, i).
+		if symm == "general" {
+			stored[[2]string{u, v}] += wt
+			if mirror, ok := stored[[2]string{v, u}]; ok {
+				if mirror != stored[[2]string{u, v}] {
+					Abortf("Matrix Market input is not symmetric: entries (%s, %s) and (%s, %s) differ", u, v, v, u)
+				}
+				continue
+			}
+		}
+		if u > v {
+			u, v = v, u
+		}
+		if _, dup := es[[2]string{u, v}]; dup {
+			Warn("W001-duplicate-coupler", u+" "+v, "Coupler %s %s appears more than once; its terms are summed", u, v)
+		}
+		es[[2]string{u, v}] += wt
+		mags[[2]string{u, v}] += math.Abs(wt)
+		vs[u] += 0.0
+		vs[v] += 0.0
+	}
+	if n < 0 {
+		Abortf("Matrix Market input lacks a size line")
+	}
+	if seen != nnz {
+		Abortf("Matrix Market input declares %d entries but contains %d", nnz, seen)
+	}
+	return Graph{Vs: vs, Es: es, EMags: mags}
+}
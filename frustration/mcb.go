@@ -0,0 +1,212 @@
+/* This file finds a minimum cycle basis: a cycle basis whose total length is
+as small as possible.  The fundamental cycles of a spanning tree can be long
+and wind through much of the graph, while a minimum basis of a lattice-like
+graph consists of its plaquettes and other short, local cycles.  The
+algorithm is Horton's: every cycle of some minimum basis consists of shortest
+paths from one of its vertices to the endpoints of one of its edges, so
+candidate cycles of that form are considered in order of length and kept if
+they are linearly independent of those kept already. */
+
+package frustration
+
+import (
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// A hortonCycle is a candidate cycle for a minimum cycle basis.
+type hortonCycle struct {
+	Len   int   // Number of edges in the cycle
+	Edges []int // Indices of the cycle's edges, sorted
+}
+
+// A bfsBall is a breadth-first search tree truncated at a given radius.
+type bfsBall struct {
+	Dist   map[int]int // Distance of each reached vertex from the root
+	Parent map[int]int // Parent of each reached vertex other than the root
+	PEdge  map[int]int // Index of the edge to each vertex's parent
+	Branch map[int]int // Root's child from which each vertex descends
+}
+
+// ball performs a breadth-first search from root to the given radius.
+func (sg signedGraph) ball(root, radius int, eIdx map[[2]int]int) bfsBall {
+	b := bfsBall{
+		Dist:   map[int]int{root: 0},
+		Parent: make(map[int]int),
+		PEdge:  make(map[int]int),
+		Branch: make(map[int]int),
+	}
+	queue := []int{root}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		if b.Dist[u] == radius {
+			continue
+		}
+		for _, a := range sg.Adj[u] {
+			if _, ok := b.Dist[a.To]; ok {
+				continue
+			}
+			b.Dist[a.To] = b.Dist[u] + 1
+			b.Parent[a.To] = u
+			b.PEdge[a.To] = eIdx[[2]int{u, a.To}]
+			if u == root {
+				b.Branch[a.To] = a.To
+			} else {
+				b.Branch[a.To] = b.Branch[u]
+			}
+			queue = append(queue, a.To)
+		}
+	}
+	return b
+}
+
+// hortonCycles returns each candidate cycle that consists of the tree paths
+// in b from its root to the endpoints of an edge plus the edge itself, has
+// length in (minLen, maxLen], and is simple.
+func (sg signedGraph) hortonCycles(root int, b bfsBall, minLen, maxLen int) []hortonCycle {
+	var hcs []hortonCycle
+	for i, e := range sg.Edges {
+		x, y := e[0], e[1]
+		dx, okx := b.Dist[x]
+		dy, oky := b.Dist[y]
+		if !okx || !oky || x == root || y == root {
+			continue
+		}
+		n := dx + dy + 1
+		if n <= minLen || n > maxLen || b.Branch[x] == b.Branch[y] {
+			continue // Out of range or not simple
+		}
+		hc := hortonCycle{Len: n, Edges: make([]int, 0, n)}
+		hc.Edges = append(hc.Edges, i)
+		for _, v := range [2]int{x, y} {
+			for ; v != root; v = b.Parent[v] {
+				hc.Edges = append(hc.Edges, b.PEdge[v])
+			}
+		}
+		sort.Ints(hc.Edges)
+		hcs = append(hcs, hc)
+	}
+	return hcs
+}
+
+// A gf2Basis incrementally tests vectors over GF(2) for linear independence
+// by Gaussian elimination.
+type gf2Basis struct {
+	Rows map[int][]uint64 // Reduced row with each pivot bit
+}
+
+// add reduces a vector against the basis and, if the result is nonzero,
+// adds it to the basis and returns true.  The vector is modified.
+func (gb *gf2Basis) add(vec []uint64) bool {
+	for w := len(vec) - 1; w >= 0; w-- {
+		for vec[w] != 0 {
+			bit := w*64 + 63 - bits.LeadingZeros64(vec[w])
+			row, ok := gb.Rows[bit]
+			if !ok {
+				gb.Rows[bit] = vec
+				return true
+			}
+			for k := range row {
+				vec[k] ^= row[k]
+			}
+		}
+	}
+	return false
+}
+
+// minimumCycleBasis returns a minimum cycle basis of the graph, each cycle as
+// a list of edges.  Vertices and edges are considered in lexicographic order
+// of their names, so the result is deterministic.
+func (g Graph) minimumCycleBasis() [][][2]string {
+	// Index the edges and assign each edge outside a breadth-first
+	// spanning forest a coordinate.  A cycle is identified by its
+	// non-forest edges, so these coordinates represent the cycle space.
+	sg := g.signedGraph()
+	eIdx := make(map[[2]int]int, 2*len(sg.Edges))
+	for i, e := range sg.Edges {
+		eIdx[e] = i
+		eIdx[[2]int{e[1], e[0]}] = i
+	}
+	coord := make([]int, len(sg.Edges))
+	for i := range coord {
+		coord[i] = -1
+	}
+	seen := make([]bool, len(sg.Names))
+	inForest := make([]bool, len(sg.Edges))
+	for r := range sg.Names {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		queue := []int{r}
+		for len(queue) > 0 {
+			u := queue[0]
+			queue = queue[1:]
+			for _, a := range sg.Adj[u] {
+				if !seen[a.To] {
+					seen[a.To] = true
+					inForest[eIdx[[2]int{u, a.To}]] = true
+					queue = append(queue, a.To)
+				}
+			}
+		}
+	}
+	dim := 0
+	for i, in := range inForest {
+		if !in {
+			coord[i] = dim
+			dim++
+		}
+	}
+	if dim == 0 {
+		return nil
+	}
+	words := (dim + 63) / 64
+
+	// Consider candidate cycles in order of increasing length, doubling
+	// the search radius each time the candidates run out, until the basis
+	// is complete.  A cycle of length at most 2r+1 through a vertex is
+	// formed from paths of length at most r from that vertex.
+	gb := gf2Basis{Rows: make(map[int][]uint64, dim)}
+	tried := make(map[string]Empty)
+	key := make([]byte, 0, 64)
+	var bcs [][][2]string
+	for r, done := 1, 0; len(bcs) < dim && done < len(sg.Names); r, done = 2*r, 2*r+1 {
+		var hcs []hortonCycle
+		for v := range sg.Names {
+			hcs = append(hcs, sg.hortonCycles(v, sg.ball(v, r, eIdx), done, 2*r+1)...)
+		}
+		sort.SliceStable(hcs, func(i, j int) bool { return hcs[i].Len < hcs[j].Len })
+		for _, hc := range hcs {
+			key = key[:0]
+			for _, i := range hc.Edges {
+				key = strconv.AppendInt(key, int64(i), 10)
+				key = append(key, ' ')
+			}
+			if _, ok := tried[string(key)]; ok {
+				continue
+			}
+			tried[string(key)] = Empty{}
+			vec := make([]uint64, words)
+			for _, i := range hc.Edges {
+				if c := coord[i]; c >= 0 {
+					vec[c/64] ^= 1 << uint(c%64)
+				}
+			}
+			if !gb.add(vec) {
+				continue
+			}
+			cyc := make([][2]string, len(hc.Edges))
+			for k, i := range hc.Edges {
+				cyc[k] = sg.edgeName(i)
+			}
+			bcs = append(bcs, cyc)
+			if len(bcs) == dim {
+				break
+			}
+		}
+	}
+	return bcs
+}
@@ -0,0 +1,170 @@
+/* This file defines the rules that decide, from the frustrated and
+non-frustrated cycles through a vertex or edge, whether that vertex or edge
+is itself deemed frustrated. */
+
+package frustration
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Kinds of membership rule
+const (
+	RuleMajority = "majority" // More frustrated than non-frustrated cycles
+	RuleAny      = "any"      // At least one frustrated cycle
+	RuleRatio    = "ratio"    // At least a given fraction of frustrated cycles
+	RuleWeighted = "weighted" // More frustrated than non-frustrated cycles, each weighted by its weakest coupler
+)
+
+// A MembershipRule decides whether a vertex or edge is frustrated given the
+// cycles through it.  The zero value is the majority rule.
+type MembershipRule struct {
+	Kind  string  // RuleMajority (or ""), RuleAny, RuleRatio, or RuleWeighted
+	Ratio float64 // Minimum fraction of frustrated cycles for RuleRatio
+}
+
+// ParseMembershipRule parses a membership rule of the form "majority",
+// "any", "ratio:R", or "weighted".
+func ParseMembershipRule(s string) MembershipRule {
+	switch {
+	case s == RuleMajority, s == RuleAny, s == RuleWeighted:
+		return MembershipRule{Kind: s}
+	case strings.HasPrefix(s, RuleRatio+":"):
+		r, err := strconv.ParseFloat(strings.TrimPrefix(s, RuleRatio+":"), 64)
+		if err != nil || r <= 0 || r > 1 {
+			Abortf("The ratio in membership rule %q must lie in (0, 1]", s)
+		}
+		return MembershipRule{Kind: RuleRatio, Ratio: r}
+	}
+	Abortf("Unrecognized membership rule %q", s)
+	return MembershipRule{}
+}
+
+// String formats a membership rule as ParseMembershipRule expects it.
+func (r MembershipRule) String() string {
+	switch r.Kind {
+	case "":
+		return RuleMajority
+	case RuleRatio:
+		return fmt.Sprintf("%s:%v", RuleRatio, r.Ratio)
+	}
+	return r.Kind
+}
+
+// IsDefault says whether a membership rule is the majority rule.
+func (r MembershipRule) IsDefault() bool {
+	return r.Kind == "" || r.Kind == RuleMajority
+}
+
+// frustrated applies a membership rule to the number of frustrated and
+// non-frustrated cycles through a vertex or edge and, for the weighted rule,
+// to the total weights of those cycles.  A vertex or edge through which no
+// frustrated cycle passes is never frustrated.
+func (r MembershipRule) frustrated(f, nf int, wf, wnf float64) bool {
+	switch r.Kind {
+	case RuleAny:
+		return f > 0
+	case RuleRatio:
+		return f > 0 && float64(f) >= r.Ratio*float64(f+nf)
+	case RuleWeighted:
+		return f > 0 && wf > wnf
+	}
+	return f > nf
+}
+
+// ClassRules specifies the membership rules for vertices and edges.
+type ClassRules struct {
+	Vertex MembershipRule // Rule for deeming a vertex frustrated
+	Edge   MembershipRule // Rule for deeming an edge frustrated
+}
+
+// IsDefault says whether both membership rules are the majority rule.
+func (cr ClassRules) IsDefault() bool {
+	return cr.Vertex.IsDefault() && cr.Edge.IsDefault()
+}
+
+// Weighted says whether either membership rule requires cycle weights.
+func (cr ClassRules) Weighted() bool {
+	return cr.Vertex.Kind == RuleWeighted || cr.Edge.Kind == RuleWeighted
+}
+
+// cycleWeight returns the weight the weighted rule assigns to a cycle: the
+// magnitude of its weakest coupler, which bounds the energy at stake in
+// satisfying the cycle.
+func (g Graph) cycleWeight(p []string) float64 {
+	w := math.Inf(1)
+	for i, u := range p {
+		v := p[(i+1)%len(p)]
+		if u > v {
+			u, v = v, u
+		}
+		w = math.Min(w, math.Abs(g.Es[[2]string{u, v}]))
+	}
+	return w
+}
+
+// A memberTally records the cycles through one vertex or edge.
+type memberTally struct {
+	F, NF   int     // Number of frustrated and non-frustrated cycles
+	WF, WNF float64 // Total weight of frustrated and non-frustrated cycles
+}
+
+// tallyMembers tallies the frustrated and non-frustrated cycles through each
+// vertex and each edge.  Cycle weights are computed only if weighted is
+// true.
+func (g Graph) tallyMembers(ps [][]string, isFrust []bool, weighted bool) (map[string]*memberTally, map[[2]string]*memberTally) {
+	vts := make(map[string]*memberTally)
+	ets := make(map[[2]string]*memberTally)
+	for i, p := range ps {
+		w := 0.0
+		if weighted {
+			w = g.cycleWeight(p)
+		}
+		add := func(t *memberTally) {
+			if isFrust[i] {
+				t.F++
+				t.WF += w
+			} else {
+				t.NF++
+				t.WNF += w
+			}
+		}
+		for j, u := range p {
+			if vts[u] == nil {
+				vts[u] = &memberTally{}
+			}
+			add(vts[u])
+			v := p[(j+1)%len(p)]
+			if u > v {
+				u, v = v, u
+			}
+			e := [2]string{u, v}
+			if ets[e] == nil {
+				ets[e] = &memberTally{}
+			}
+			add(ets[e])
+		}
+	}
+	return vts, ets
+}
+
+// frustrated applies a membership rule to a tally.
+func (t *memberTally) frustrated(r MembershipRule) bool {
+	return r.frustrated(t.F, t.NF, t.WF, t.WNF)
+}
+
+// frustratedEdges returns the set of edges that a membership rule deems
+// frustrated.
+func (g Graph) frustratedEdges(ps [][]string, isFrust []bool, r MembershipRule) map[[2]string]bool {
+	_, ets := g.tallyMembers(ps, isFrust, r.Kind == RuleWeighted)
+	fes := make(map[[2]string]bool, len(ets))
+	for e, t := range ets {
+		if t.frustrated(r) {
+			fes[e] = true
+		}
+	}
+	return fes
+}
@@ -0,0 +1,132 @@
+/* This file monitors the program's memory usage so that runs that would
+exceed a user-specified limit stop gracefully instead of being killed. */
+
+package frustration
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// memPollInterval is the interval at which memory usage is checked.
+const memPollInterval = 100 * time.Millisecond
+
+// memSoftFraction is the fraction of the memory limit above which memory is
+// considered low and long-running computations should wind down.
+const memSoftFraction = 0.9
+
+// Memory-usage state shared by the watchdog and the computations it
+// monitors.  All fields are accessed atomically.
+var (
+	memLimit uint64 // Hard memory limit in bytes (0 for no limit)
+	memPeak  uint64 // Peak memory usage observed in bytes
+	memLow   int32  // 1 if usage has exceeded the soft limit
+	memOver  uint64 // Usage in bytes when last observed at or above the hard limit (0 if below it)
+)
+
+// ParseSize parses a size in bytes with an optional K, M, G, or T suffix
+// (powers of 1024).
+func ParseSize(s string) uint64 {
+	mult := uint64(1)
+	t := strings.ToUpper(strings.TrimSpace(s))
+	t = strings.TrimSuffix(t, "B")
+	if n := len(t); n > 0 {
+		switch t[n-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		case 'T':
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			t = t[:n-1]
+		}
+	}
+	x, err := strconv.ParseFloat(t, 64)
+	if err != nil || x <= 0 {
+		Abortf("Failed to parse %q as a memory size", s)
+	}
+	return uint64(x * float64(mult))
+}
+
+// memoryInUse returns the number of bytes the Go runtime currently holds
+// from the operating system.
+func memoryInUse() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.Sys - ms.HeapReleased
+}
+
+// StartMemoryWatchdog enforces a memory limit.  It asks the garbage collector
+// to work harder as the limit approaches, flags memory as low once usage
+// exceeds memSoftFraction of the limit, and flags it as exhausted while usage
+// is at or above the limit itself.  The watchdog never stops the program;
+// rather, long-running computations call checkMemory, which aborts the
+// current operation once memory is exhausted.
+func StartMemoryWatchdog(limit uint64) {
+	atomic.StoreUint64(&memLimit, limit)
+	debug.SetMemoryLimit(int64(float64(limit) * memSoftFraction))
+	go func() {
+		for range time.Tick(memPollInterval) {
+			use := memoryInUse()
+			if use > atomic.LoadUint64(&memPeak) {
+				atomic.StoreUint64(&memPeak, use)
+			}
+			if float64(use) > float64(limit)*memSoftFraction {
+				atomic.StoreInt32(&memLow, 1)
+			}
+			over := uint64(0)
+			if use >= limit {
+				over = use
+			}
+			atomic.StoreUint64(&memOver, over)
+		}
+	}()
+}
+
+// memoryIsLow says whether memory usage has exceeded the soft limit.
+// Computations that can stop early with partial results should do so when
+// this returns true.
+func memoryIsLow() bool {
+	return atomic.LoadInt32(&memLow) != 0
+}
+
+// checkMemory aborts the current operation if memory usage has reached the
+// limit enforced by StartMemoryWatchdog.
+func checkMemory() {
+	if use := atomic.LoadUint64(&memOver); use != 0 {
+		Abortf("Aborting because memory usage (%s) reached the --max-memory limit (%s); output written so far is incomplete", formatSize(use), formatSize(atomic.LoadUint64(&memLimit)))
+	}
+}
+
+// MemoryPeak returns the peak memory usage observed by the watchdog and the
+// limit it enforces.
+func MemoryPeak() (uint64, uint64) {
+	use := memoryInUse()
+	if use > atomic.LoadUint64(&memPeak) {
+		atomic.StoreUint64(&memPeak, use)
+	}
+	return atomic.LoadUint64(&memPeak), atomic.LoadUint64(&memLimit)
+}
+
+// formatSize formats a number of bytes in human-readable units.
+func formatSize(n uint64) string {
+	const units = "KMGT"
+	x := float64(n)
+	u := -1
+	for x >= 1024 && u < len(units)-1 {
+		x /= 1024
+		u++
+	}
+	if u < 0 {
+		return strconv.FormatUint(n, 10) + "B"
+	}
+	return strconv.FormatFloat(x, 'f', 1, 64) + string(units[u]) + "B"
+}
@@ -0,0 +1,146 @@
+/* This file estimates how robust each cycle's frustration is to noise in the
+coefficients, such as the control errors of annealing hardware, by
+classifying the cycles in many randomly perturbed realizations of a graph.
+Rather than classify one realization at a time, it computes every cycle's
+sign parity in a whole batch of realizations at once, as the product of the
+cycle-edge incidence matrix and an int8 matrix with one column of
+antiferromagnetic-edge indicators per realization. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+// noiseBatch is the number of realizations whose edge signs are held in
+// memory at once.
+const noiseBatch = 256
+
+// noiseTile is the number of realizations handled by the innermost loop of
+// the parity product, chosen so that each row segment it reads or writes is
+// a single 64-byte cache line.
+const noiseTile = 64
+
+// noisyAntiferromagnets fills afm, a row-major matrix with one row per edge
+// and width columns, with 1 for each edge that acts antiferromagnetically in
+// each of width realizations and 0 otherwise.  Each realization adds
+// independent Gaussian noise with standard deviation sigma to every field h
+// and coupler strength j.
+func (sg signedGraph) noisyAntiferromagnets(afm []int8, width int, h, j []float64, sigma float64, rng *rand.Rand) {
+	hn := make([]float64, len(h))
+	for r := 0; r < width; r++ {
+		for v, x := range h {
+			hn[v] = x + sigma*rng.NormFloat64()
+		}
+		for i, e := range sg.Edges {
+			s, _ := couplingSignOf(j[i]+sigma*rng.NormFloat64(), hn[e[0]], hn[e[1]])
+			afm[i*width+r] = int8((1 - s) / 2)
+		}
+	}
+}
+
+// parityProduct computes par = incid × afm, where incid is a cycle-edge
+// incidence matrix in compressed-row form (the edge indices of each cycle)
+// and par and afm are row-major with width columns.  The low bit of each
+// element of par is then the parity of the number of antiferromagnetic edges
+// in a cycle in one realization.  Sums accumulate in int8, whose wraparound
+// preserves parity.  The realizations are processed in tiles of noiseTile
+// columns so that the rows of afm used by a run of cycles stay in cache.
+func parityProduct(par []int8, incid [][]int, afm []int8, width int) {
+	for r0 := 0; r0 < width; r0 += noiseTile {
+		r1 := r0 + noiseTile
+		if r1 > width {
+			r1 = width
+		}
+		for c, es := range incid {
+			acc := par[c*width+r0 : c*width+r1]
+			for k := range acc {
+				acc[k] = 0
+			}
+			for _, e := range es {
+				row := afm[e*width+r0 : e*width+r1]
+				row = row[:len(acc)]
+				for k, x := range row {
+					acc[k] += x
+				}
+			}
+		}
+	}
+}
+
+// OutputNoiseRobustness classifies the given cycles in n realizations of a
+// graph, each of which adds independent Gaussian noise with standard
+// deviation sigma to every field and coupler strength.  It reports the
+// fraction of realizations in which each cycle is frustrated, for each cycle
+// whose frustration is not the same in every realization as in the
+// noiseless graph, and the mean and standard deviation over realizations of
+// the fraction of cycles that are frustrated.  Frustration is judged by sign
+// parity.
+func OutputNoiseRobustness(w io.Writer, g Graph, ecs [][][2]string, ps [][]string, isFrust []bool, sigma float64, n int, rng *rand.Rand) {
+	// Gather the coefficients and the cycle-edge incidence matrix.
+	sg := g.signedGraph()
+	h, j := sg.Fields, sg.Weights
+	eIdx := make(map[[2]string]int, len(sg.Edges))
+	for i := range sg.Edges {
+		eIdx[sg.edgeName(i)] = i
+	}
+	incid := make([][]int, len(ecs))
+	for c, ec := range ecs {
+		incid[c] = make([]int, len(ec))
+		for k, e := range ec {
+			incid[c][k] = eIdx[e]
+		}
+	}
+
+	// Classify every cycle in every realization, a batch of realizations
+	// at a time.
+	nFrust := make([]int, len(ecs)) // Realizations in which each cycle is frustrated
+	sum, sumSq := 0.0, 0.0          // Moments of the frustrated-cycle fraction
+	afm := make([]int8, len(sg.Edges)*noiseBatch)
+	par := make([]int8, len(ecs)*noiseBatch)
+	perReal := make([]int, noiseBatch)
+	for done := 0; done < n; done += noiseBatch {
+		width := n - done
+		if width > noiseBatch {
+			width = noiseBatch
+		}
+		sg.noisyAntiferromagnets(afm, width, h, j, sigma, rng)
+		parityProduct(par, incid, afm, width)
+		for r := range perReal {
+			perReal[r] = 0
+		}
+		for c := range ecs {
+			row := par[c*width : (c+1)*width]
+			for r, x := range row {
+				b := int(x & 1)
+				nFrust[c] += b
+				perReal[r] += b
+			}
+		}
+		for _, nf := range perReal[:width] {
+			f := fraction(nf, len(ecs))
+			sum += f
+			sumSq += f * f
+		}
+	}
+
+	// Output each cycle whose frustration is sensitive to noise.
+	ns := 0
+	for c, p := range ps {
+		if (isFrust[c] && nFrust[c] == n) || (!isFrust[c] && nFrust[c] == 0) {
+			continue
+		}
+		fmt.Fprintf(w, "NZC %f | %s\n", fraction(nFrust[c], n), strings.Join(p, " "))
+		ns++
+	}
+
+	// Output the summary statistics.
+	mean := sum / float64(n)
+	sd := math.Sqrt(math.Max(sumSq/float64(n)-mean*mean, 0))
+	fmt.Fprintf(w, "#NZC %d / %d = %f\n", ns, len(ps), fraction(ns, len(ps)))
+	fmt.Fprintf(w, "#NZF %f %f\n", mean, sd)
+}
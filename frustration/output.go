@@ -0,0 +1,1295 @@
+/* This file outputs various statistics about the frustration that appears in
+a graph. */
+
+package frustration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReportOptions specifies optional content for OutputResults to include.
+type ReportOptions struct {
+	Explain      bool       // Explain why each frustrated cycle is frustrated
+	VertexFields bool       // Output each frustrated vertex's field and incident coupling
+	Centrality   bool       // Output edges ranked by frustrated-cycle centrality
+	EnergyGaps   bool       // Output the energy penalty of each frustrated cycle
+	Symmetry     bool       // Collapse symmetric vertices and edges into classes
+	SignFlips    bool       // Output the effect of flipping each edge's sign
+	CoeffView    string     // Convention in which to output coefficients (ViewIsing or ViewQUBO)
+	Cycles       string     // Which cycles to output (CyclesAll, CyclesFrustrated, CyclesNone)
+	MinLen       int        // Minimum length of an output cycle
+	MaxLen       int        // Maximum length of an output cycle (0: unlimited)
+	Reservoir    int        // Maximum number of FC and of NFC lines to output (0: unlimited)
+	Histogram    string     // Style of cycle-length histogram to output ("": none, HistUnicode, or HistASCII)
+	Rules        ClassRules // Rules for deeming vertices and edges frustrated
+}
+
+// Cycle-length histogram styles
+const (
+	HistUnicode = "unicode" // Draw with Unicode block characters
+	HistASCII   = "ascii"   // Draw with ASCII characters
+)
+
+// histWidth is the width in characters of the longest histogram bar.
+const histWidth = 40
+
+// histGlyphs maps each histogram style to the characters that draw the
+// frustrated and non-frustrated portions of a bar and the characters, from
+// lowest to highest, that draw a sparkline.
+var histGlyphs = map[string]struct {
+	Frust, NonFrust string
+	Spark           []string
+}{
+	HistUnicode: {"█", "░", []string{"▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"}},
+	HistASCII:   {"#", ".", []string{"_", ".", "-", "=", "+", "*", "%", "@"}},
+}
+
+// Cycle-output filters
+const (
+	CyclesAll        = "all"             // Output every cycle
+	CyclesFrustrated = "frustrated-only" // Output only frustrated cycles
+	CyclesNone       = "none"            // Output no cycles, only the summary
+)
+
+// showCycle says whether the FC or NFC line for a cycle of a given length
+// and frustration status passes the output filters.
+func (opts ReportOptions) showCycle(n int, f bool) bool {
+	switch {
+	case opts.Cycles == CyclesNone:
+		return false
+	case opts.Cycles == CyclesFrustrated && !f:
+		return false
+	case n < opts.MinLen:
+		return false
+	case opts.MaxLen > 0 && n > opts.MaxLen:
+		return false
+	}
+	return true
+}
+
+// Coefficient views
+const (
+	ViewIsing = "ising" // Ising fields and coupler strengths
+	ViewQUBO  = "qubo"  // QUBO coefficients from which those were derived
+)
+
+// fieldIn returns a vertex's linear coefficient in a given view.  It falls
+// back to the Ising field if the graph has no QUBO coefficients.
+func (g Graph) fieldIn(view, v string) float64 {
+	if view == ViewQUBO && g.QVs != nil {
+		return g.QVs[v]
+	}
+	return g.Vs[v]
+}
+
+// couplerIn returns an edge's quadratic coefficient in a given view.  It
+// falls back to the Ising coupler strength if the graph has no QUBO
+// coefficients.
+func (g Graph) couplerIn(view string, e [2]string) float64 {
+	if view == ViewQUBO && g.QEs != nil {
+		return g.QEs[e]
+	}
+	return g.Es[e]
+}
+
+// incidentCoupling returns a map from each vertex to the sum of the
+// magnitudes of the couplers incident on it.
+func (g Graph) incidentCoupling() map[string]float64 {
+	tj := make(map[string]float64, len(g.Vs))
+	for e, wt := range g.Es {
+		tj[e[0]] += math.Abs(wt)
+		tj[e[1]] += math.Abs(wt)
+	}
+	return tj
+}
+
+// outputVertices outputs all vertices, categorized according to
+// opts.Rules.Vertex and tallied.  If opts.VertexFields is true, each
+// frustrated vertex additionally reports its external field, the total
+// magnitude of its incident couplers, and the ratio of the magnitude of the
+// former to the latter.  If color is non-nil,
+// identically tallied vertices of the same color are output as a single line.
+func outputVertices(w io.Writer, g Graph, ps [][]string, isFrust []bool, opts ReportOptions, color map[string]int) {
+	// Tally the number of times each vertex appears in a frustrated cycle
+	// and in a non-frustrated cycle.
+	rule := opts.Rules.Vertex
+	vts, _ := g.tallyMembers(ps, isFrust, rule.Kind == RuleWeighted)
+
+	// Output each vertex, categorized and tallied.  Keep track of the
+	// number of vertices deemed frustrated.
+	var cc *classCollapser
+	if color != nil {
+		cc = newClassCollapser()
+	}
+	emit := func(tag, text, v string) {
+		if cc == nil {
+			fmt.Fprintf(w, "%-4s %s | %s\n", tag, text, v)
+		} else {
+			cc.Add(tag+"C", text, fmt.Sprint(color[v]), v)
+		}
+	}
+	nfvs := 0 // Number of frustrated vertices
+	var tj map[string]float64
+	if opts.VertexFields {
+		tj = g.incidentCoupling()
+	}
+	for v, t := range vts {
+		switch {
+		case !t.frustrated(rule):
+			emit("NFV", fmt.Sprintf("%d %d", t.NF, t.NF-t.F), v)
+		case opts.VertexFields:
+			h := g.Vs[v]
+			emit("FV", fmt.Sprintf("%d %d %v %v %f", t.F, t.F-t.NF, g.fieldIn(opts.CoeffView, v), tj[v], math.Abs(h)/tj[v]), v)
+			nfvs++
+		default:
+			emit("FV", fmt.Sprintf("%d %d", t.F, t.F-t.NF), v)
+			nfvs++
+		}
+	}
+	if cc != nil {
+		cc.Output(w)
+	}
+
+	// Output some summary statistics.
+	fmt.Fprintf(w, "#FV  %d / %d = %f\n", nfvs, len(g.Vs), float64(nfvs)/float64(len(g.Vs)))
+}
+
+// tallyEdges returns the number of times each edge appears in a frustrated
+// cycle and in a non-frustrated cycle.
+func tallyEdges(ps [][]string, isFrust []bool) (map[[2]string]int, map[[2]string]int) {
+	fEdges := make(map[[2]string]int)
+	nfEdges := make(map[[2]string]int)
+	for i, p := range ps {
+		for j, v1 := range p {
+			v2 := p[(j+1)%len(p)]
+			if v1 > v2 {
+				v1, v2 = v2, v1
+			}
+			e := [2]string{v1, v2}
+			if isFrust[i] {
+				fEdges[e]++
+			} else {
+				nfEdges[e]++
+			}
+		}
+	}
+	return fEdges, nfEdges
+}
+
+// signFlipEffect returns the number of frustrated cycles that would become
+// unfrustrated and the number of unfrustrated cycles that would become
+// frustrated if an edge's coupler changed sign.  Flipping an edge's sign
+// toggles the frustration of every cycle through it unless the external
+// fields on its endpoints, rather than its coupler, determine its sign.
+func (g Graph) signFlipEffect(e [2]string, fEdges, nfEdges map[[2]string]int) (int, int) {
+	if _, byField := g.couplingSign(e[0], e[1]); byField {
+		return 0, 0
+	}
+	return fEdges[e], nfEdges[e]
+}
+
+// outputEdges outputs all edges, categorized according to opts.Rules.Edge
+// and tallied.  If opts.SignFlips is true, each edge additionally reports how
+// many cycles flipping its sign would fix and break.  If color is non-nil, identically tallied edges of the
+// same class are output as a single line.
+func outputEdges(w io.Writer, g Graph, ps [][]string, isFrust []bool, opts ReportOptions, color map[string]int) {
+	// Tally the number of times each edge appears in a frustrated cycle
+	// and in a non-frustrated cycle.
+	fEdges, nfEdges := tallyEdges(ps, isFrust)
+	rule := opts.Rules.Edge
+	_, ets := g.tallyMembers(ps, isFrust, rule.Kind == RuleWeighted)
+
+	// Output each edge, categorized and tallied.
+	var cc *classCollapser
+	if color != nil {
+		cc = newClassCollapser()
+	}
+	emit := func(tag, text string, e [2]string) {
+		if opts.SignFlips {
+			fix, brk := g.signFlipEffect(e, fEdges, nfEdges)
+			text += fmt.Sprintf(" %d %d", fix, brk)
+		}
+		if cc == nil {
+			fmt.Fprintf(w, "%-4s %s | %s %s\n", tag, text, e[0], e[1])
+		} else {
+			cc.Add(tag+"C", text, g.edgeClass(color, e), e[0]+" "+e[1])
+		}
+	}
+	nfes := 0 // Number of frustrated edges
+	for e, t := range ets {
+		if t.frustrated(rule) {
+			emit("FE", fmt.Sprintf("%d %d", t.F, t.F-t.NF), e)
+			nfes++
+		} else {
+			emit("NFE", fmt.Sprintf("%d %d", t.NF, t.NF-t.F), e)
+		}
+	}
+	if cc != nil {
+		cc.Output(w)
+	}
+
+	// Output some summary statistics.
+	fmt.Fprintf(w, "#FE  %d / %d = %f\n", nfes, len(g.Es), float64(nfes)/float64(len(g.Es)))
+}
+
+// explainCycle outputs a step-by-step derivation of why a cycle is frustrated:
+// the sign each edge contributes and the running product of those signs.  In
+// the QUBO view, each Ising coefficient is annotated with the QUBO
+// coefficient from which it was derived.
+func explainCycle(w io.Writer, g Graph, p []string, view string) {
+	coeff := func(x, q float64) string {
+		if view == ViewQUBO && g.QEs != nil {
+			return fmt.Sprintf("%v (Q = %v)", x, q)
+		}
+		return fmt.Sprint(x)
+	}
+	prod := 1
+	afm := 0 // Number of antiferromagnetic couplings
+	for i, u := range p {
+		// Describe the edge and what determined its sign.
+		v := p[(i+1)%len(p)]
+		s, byField := g.couplingSign(u, v)
+		kind := "ferromagnetic"
+		sc := '+'
+		if s < 0 {
+			kind = "antiferromagnetic"
+			sc = '-'
+			afm++
+		}
+		e := [2]string{u, v}
+		if u > v {
+			e[0], e[1] = v, u
+		}
+		var why string
+		if byField {
+			why = fmt.Sprintf("h = %s, %s outweigh J = %s", coeff(g.Vs[u], g.fieldIn(view, u)), coeff(g.Vs[v], g.fieldIn(view, v)), coeff(g.Es[e], g.couplerIn(view, e)))
+		} else {
+			why = fmt.Sprintf("J = %s", coeff(g.Es[e], g.couplerIn(view, e)))
+		}
+
+		// Update and report the running product, noting where it
+		// turns negative.
+		prev := prod
+		prod *= s
+		pc := '+'
+		if prod < 0 {
+			pc = '-'
+		}
+		note := ""
+		if prev > 0 && prod < 0 {
+			note = "  <-- product turns negative"
+		}
+		fmt.Fprintf(w, "EXP    %s -- %s: %s (%s), sign %c, product %c%s\n", u, v, why, kind, sc, pc, note)
+	}
+	fmt.Fprintf(w, "EXP    odd number (%d) of antiferromagnetic couplings, so no assignment of spins satisfies every edge in this cycle\n", afm)
+}
+
+// outputEdgeCentrality outputs each edge that lies on at least one cycle
+// along with the fraction of the cycles through it that are frustrated.
+// Edges are sorted from most to least central, with ties broken by the number
+// of frustrated cycles through the edge.
+func outputEdgeCentrality(w io.Writer, ps [][]string, isFrust []bool) {
+	// Compute each edge's centrality.
+	type centrality struct {
+		E     [2]string // Edge
+		F     int       // Number of frustrated cycles through the edge
+		T     int       // Total number of cycles through the edge
+		Score float64   // F divided by T
+	}
+	fEdges, nfEdges := tallyEdges(ps, isFrust)
+	cs := make([]centrality, 0, len(fEdges)+len(nfEdges))
+	for e, t := range nfEdges {
+		f := fEdges[e]
+		cs = append(cs, centrality{E: e, F: f, T: f + t, Score: float64(f) / float64(f+t)})
+	}
+	for e, f := range fEdges {
+		if _, ok := nfEdges[e]; !ok {
+			cs = append(cs, centrality{E: e, F: f, T: f, Score: 1.0})
+		}
+	}
+
+	// Rank the edges and output them in order.
+	sort.Slice(cs, func(i, j int) bool {
+		switch {
+		case cs[i].Score != cs[j].Score:
+			return cs[i].Score > cs[j].Score
+		case cs[i].F != cs[j].F:
+			return cs[i].F > cs[j].F
+		case cs[i].E[0] != cs[j].E[0]:
+			return cs[i].E[0] < cs[j].E[0]
+		default:
+			return cs[i].E[1] < cs[j].E[1]
+		}
+	})
+	for _, c := range cs {
+		fmt.Fprintf(w, "EC   %f %d %d | %s %s\n", c.Score, c.F, c.T, c.E[0], c.E[1])
+	}
+}
+
+// outputCycles outputs the cycles that pass the filters in opts, categorized,
+// and tallies all cycles.  If opts.Explain is true, each frustrated cycle is
+// followed by a derivation of why it is frustrated, with coefficients
+// presented in the view given by opts.CoeffView.  If opts.Reservoir is
+// positive, only a sample of that many frustrated and that many
+// non-frustrated cycles, stratified by length, is output.
+func outputCycles(w io.Writer, g Graph, ps [][]string, isFrust []bool, opts ReportOptions, rng *rand.Rand) {
+	// If requested, sample the cycles to output.
+	var keep []bool
+	if opts.Reservoir > 0 {
+		keep = make([]bool, len(ps))
+		rs := [2]*cycleReservoir{newCycleReservoir(opts.Reservoir, rng), newCycleReservoir(opts.Reservoir, rng)}
+		nElig := 0 // Number of cycles that pass the filters
+		for i, p := range ps {
+			if !opts.showCycle(len(p), isFrust[i]) {
+				continue
+			}
+			nElig++
+			if isFrust[i] {
+				rs[0].Add(i, len(p))
+			} else {
+				rs[1].Add(i, len(p))
+			}
+		}
+		nKeep := 0
+		for _, r := range rs {
+			for _, i := range r.Select() {
+				keep[i] = true
+				nKeep++
+			}
+		}
+		fmt.Fprintf(w, "#RSV %d / %d = %f\n", nKeep, nElig, fraction(nKeep, nElig))
+	}
+
+	// Output each cycle that passes the filters preceded by whether it is
+	// frustrated or not.  As we go along, tally the number of frustrated
+	// cycles encountered, whether output or not.
+	nfcs := 0 // Number of frustrated cycles
+	for i, p := range ps {
+		f := isFrust[i]
+		if f {
+			nfcs++
+		}
+		if !opts.showCycle(len(p), f) || (keep != nil && !keep[i]) {
+			continue
+		}
+		if f {
+			fmt.Fprintf(w, "FC  ")
+		} else {
+			fmt.Fprintf(w, "NFC ")
+		}
+		for _, v := range p {
+			fmt.Fprintf(w, " %s", v)
+		}
+		fmt.Fprintln(w, "")
+		if f && opts.Explain {
+			explainCycle(w, g, p, opts.CoeffView)
+		}
+	}
+
+	// Output some summary statistics.
+	fmt.Fprintf(w, "#FC  %d / %d = %f\n", nfcs, len(ps), float64(nfcs)/float64(len(ps)))
+}
+
+// outputEnergyGaps outputs, for each frustrated cycle, the energy penalty of
+// its cheapest resolution: breaking its weakest coupler, which costs twice
+// that coupler's magnitude.  It also outputs the sum of these penalties as an
+// estimate of the graph's total frustration energy.
+func outputEnergyGaps(w io.Writer, g Graph, ps [][]string, isFrust []bool) {
+	total := 0.0 // Sum of all energy gaps
+	nfcs := 0    // Number of frustrated cycles
+	for i, p := range ps {
+		if !isFrust[i] {
+			continue
+		}
+		minJ := math.Inf(1)
+		for _, e := range g.pathToEdges(p) {
+			minJ = math.Min(minJ, math.Abs(g.Es[e]))
+		}
+		gap := 2 * minJ
+		fmt.Fprintf(w, "FCE  %v |", gap)
+		for _, v := range p {
+			fmt.Fprintf(w, " %s", v)
+		}
+		fmt.Fprintln(w, "")
+		total += gap
+		nfcs++
+	}
+	mean := 0.0
+	if nfcs > 0 {
+		mean = total / float64(nfcs)
+	}
+	fmt.Fprintf(w, "#FCE %v %f\n", total, mean)
+}
+
+// OutputResults is the program's top-level output routine.  It outputs a
+// variety of information about frustration within a graph given its cycles,
+// expressed as paths, and whether each is frustrated.  rng is used only to
+// sample the cycles to output if opts.Reservoir is positive.
+func OutputResults(w io.Writer, g Graph, ps [][]string, isFrust []bool, opts ReportOptions, rng *rand.Rand) {
+	// Record any nonstandard rules for deeming vertices and edges
+	// frustrated.
+	if !opts.Rules.IsDefault() {
+		fmt.Fprintf(w, "#RULE %s %s\n", opts.Rules.Vertex, opts.Rules.Edge)
+	}
+
+	// Output information about the graph's vertices, edges, and cycles.
+	var color map[string]int
+	if opts.Symmetry {
+		color = g.refineColors()
+	}
+	outputVertices(w, g, ps, isFrust, opts, color)
+	outputEdges(w, g, ps, isFrust, opts, color)
+	if opts.Centrality {
+		outputEdgeCentrality(w, ps, isFrust)
+	}
+	outputCycles(w, g, ps, isFrust, opts, rng)
+	if opts.EnergyGaps {
+		outputEnergyGaps(w, g, ps, isFrust)
+	}
+	if opts.Histogram != "" {
+		outputCycleHistogram(w, ps, isFrust, opts.Histogram)
+	}
+}
+
+// outputCycleHistogram draws a histogram of frustrated and non-frustrated
+// cycles by length, one bar per length, scaled so that the most common
+// length fills histWidth characters.  It follows the histogram with a
+// sparkline of the fraction of frustrated cycles at each length from the
+// shortest to the longest, with a space for each length at which no cycle
+// occurs.
+func outputCycleHistogram(w io.Writer, ps [][]string, isFrust []bool, style string) {
+	if len(ps) == 0 {
+		return
+	}
+	glyphs := histGlyphs[style]
+
+	// Tally frustrated and non-frustrated cycles by length.
+	minLen, maxLen := len(ps[0]), len(ps[0])
+	nf := make(map[int]int)  // Map from a length to its # of frustrated cycles
+	nnf := make(map[int]int) // Map from a length to its # of non-frustrated cycles
+	for i, p := range ps {
+		n := len(p)
+		if isFrust[i] {
+			nf[n]++
+		} else {
+			nnf[n]++
+		}
+		if n < minLen {
+			minLen = n
+		}
+		if n > maxLen {
+			maxLen = n
+		}
+	}
+	most := 0
+	for n := minLen; n <= maxLen; n++ {
+		if nf[n]+nnf[n] > most {
+			most = nf[n] + nnf[n]
+		}
+	}
+
+	// Draw one bar per length that occurs.  Rounding the cumulative width
+	// keeps a bar's two portions from overstating its total.
+	var spark strings.Builder
+	for n := minLen; n <= maxLen; n++ {
+		tot := nf[n] + nnf[n]
+		if tot == 0 {
+			spark.WriteString(" ")
+			continue
+		}
+		fw := int(math.Round(float64(nf[n]*histWidth) / float64(most)))
+		tw := int(math.Round(float64(tot*histWidth) / float64(most)))
+		if tw == 0 {
+			tw = 1 // Show that the length occurs.
+		}
+		if nf[n] > 0 && fw == 0 {
+			fw = 1 // Show that the length has frustrated cycles.
+		}
+		if fw > tw {
+			tw = fw
+		}
+		fmt.Fprintf(w, "HIST %d %d %d %s%s\n", n, nf[n], nnf[n],
+			strings.Repeat(glyphs.Frust, fw), strings.Repeat(glyphs.NonFrust, tw-fw))
+		lvl := nf[n] * (len(glyphs.Spark) - 1) / tot
+		if nf[n] > 0 && lvl == 0 {
+			lvl = 1 // Distinguish some frustration from none.
+		}
+		spark.WriteString(glyphs.Spark[lvl])
+	}
+	fmt.Fprintf(w, "#SPK %d %d %s\n", minLen, maxLen, spark.String())
+}
+
+// OutputBasisStats outputs properties of a cycle basis that indicate whether
+// it skews the per-vertex and per-edge tallies: the total, mean, and maximum
+// length of the base cycles; the mean and maximum number of base cycles
+// through each edge that lies in any, along with an edge that achieves the
+// maximum; and the number of edges that lie in more than one base cycle as a
+// fraction of the edges that lie in any.
+func OutputBasisStats(w io.Writer, bcs [][][2]string) {
+	if len(bcs) == 0 {
+		return
+	}
+
+	// Tally cycle lengths and the number of base cycles through each edge.
+	total, longest := 0, 0
+	through := make(map[[2]string]int)
+	for _, c := range bcs {
+		total += len(c)
+		if len(c) > longest {
+			longest = len(c)
+		}
+		for _, e := range c {
+			through[e]++
+		}
+	}
+	fmt.Fprintf(w, "#BLEN %d %f %d\n", total, float64(total)/float64(len(bcs)), longest)
+
+	// Report how heavily the base cycles overlap.
+	var busiest [2]string
+	most, shared := 0, 0
+	for e, n := range through {
+		if n > most || (n == most && (e[0] < busiest[0] || (e[0] == busiest[0] && e[1] < busiest[1]))) {
+			busiest, most = e, n
+		}
+		if n > 1 {
+			shared++
+		}
+	}
+	fmt.Fprintf(w, "#BOVL %f %d | %s %s\n", float64(total)/float64(len(through)), most, busiest[0], busiest[1])
+	fmt.Fprintf(w, "#BSHR %d / %d = %f\n", shared, len(through), fraction(shared, len(through)))
+}
+
+// OutputSwitching outputs a heuristic estimate of a graph's frustration index
+// and the switching set that achieves it.  If invariant is true, the set,
+// which depends on the gauge, is omitted, and the search begins from the
+// canonical gauge so that gauge-equivalent graphs receive the same estimate.
+func OutputSwitching(w io.Writer, g Graph, restarts int, invariant bool, rng *rand.Rand) {
+	sg := g.signedGraph()
+	if invariant {
+		sg = sg.canonicalGauge()
+	}
+	neg, sw := sg.frustrationIndexHeuristic(restarts, rng)
+	if !invariant {
+		fmt.Fprint(w, "SWS ")
+		for v, x := range sw {
+			if x < 0 {
+				fmt.Fprintf(w, " %s", sg.Names[v])
+			}
+		}
+		fmt.Fprintln(w, "")
+	}
+	fmt.Fprintf(w, "#FIH %d / %d = %f\n", neg, len(sg.Edges), float64(neg)/float64(len(sg.Edges)))
+}
+
+// OutputFrustratedCut heuristically finds the bipartition of the vertices
+// that cuts the most frustrated edges (those a membership rule deems
+// frustrated) and outputs the side of the bipartition on which
+// each endpoint of a frustrated edge lies, followed by the number of
+// frustrated edges cut.  Each cut edge is "explained" by the grouping: it
+// joins the two groups rather than lying within one.
+func OutputFrustratedCut(w io.Writer, g Graph, ps [][]string, isFrust []bool, rule MembershipRule, restarts int, rng *rand.Rand) {
+	// Gather the frustrated edges.
+	fes := g.frustratedEdges(ps, isFrust, rule)
+	var es [][2]string
+	for _, e := range g.sortedEdges() {
+		if fes[e] {
+			es = append(es, e)
+		}
+	}
+
+	// Maximize the cut, and output the groups and the size of the cut.
+	sg := cutGraph(es)
+	uncut, sw := sg.frustrationIndexHeuristic(restarts, rng)
+	for v, x := range sw {
+		grp := 0
+		if x < 0 {
+			grp = 1
+		}
+		fmt.Fprintf(w, "CUTG %d | %s\n", grp, sg.Names[v])
+	}
+	cut := len(es) - uncut
+	fmt.Fprintf(w, "#CUT %d / %d = %f\n", cut, len(es), fraction(cut, len(es)))
+}
+
+// OutputSBM outputs the fit of a two-group signed stochastic block model:
+// the group to which each vertex belongs, the probability of a ferromagnetic
+// edge within and between groups, and how much of the graph's
+// antiferromagnetism is explained by the grouping rather than left over as
+// residual, frustration-inducing disorder.
+func OutputSBM(w io.Writer, g Graph, rng *rand.Rand) {
+	// Fit the model.
+	sg := g.signedGraph()
+	fit := sg.fitSBM(rng)
+	c := fit.Counts
+
+	// Output each vertex's group.
+	for v, grp := range fit.Group {
+		fmt.Fprintf(w, "SBMG %d | %s\n", grp, sg.Names[v])
+	}
+
+	// Output the model parameters.
+	ratio := func(a, b int) float64 {
+		if b == 0 {
+			return 0
+		}
+		return float64(a) / float64(b)
+	}
+	fmt.Fprintf(w, "#SBMP %f %f\n", ratio(c.InPos, c.InPos+c.InNeg), ratio(c.OutPos, c.OutPos+c.OutNeg))
+
+	// Output how much of the antiferromagnetism the groups explain and
+	// how much disorder remains.  Which edges count as explained depends
+	// on whether the fitted groups are assortative (ferromagnetic within,
+	// antiferromagnetic between) or disassortative.
+	explained, residual := c.OutNeg, c.InNeg+c.OutPos
+	if ratio(c.InPos, c.InPos+c.InNeg) < ratio(c.OutPos, c.OutPos+c.OutNeg) {
+		explained, residual = c.InNeg, c.InPos+c.OutNeg
+	}
+	neg := c.InNeg + c.OutNeg
+	ne := len(sg.Edges)
+	fmt.Fprintf(w, "#SBME %d / %d = %f\n", explained, neg, ratio(explained, neg))
+	fmt.Fprintf(w, "#SBMR %d / %d = %f\n", residual, ne, ratio(residual, ne))
+
+	// Output the log-likelihood of the null and block models and
+	// McFadden's pseudo-R^2 of the latter relative to the former.
+	ll0, ll := c.nullLogLikelihood(), c.logLikelihood()
+	r2 := 0.0
+	if ll0 != 0 {
+		r2 = 1 - ll/ll0
+	}
+	fmt.Fprintf(w, "#SBML %f %f %f\n", ll0, ll, r2)
+}
+
+// OutputCycleList outputs a list of cycles without any frustration analysis.
+// The "ndjson" format outputs one JSON object per line, each listing a
+// cycle's vertices and edges.  The "edges" format outputs each cycle as a
+// block of "u v" lines, with blocks separated by a blank line.
+func OutputCycleList(w io.Writer, g Graph, cs [][][2]string, format string) {
+	switch format {
+	case "ndjson":
+		type cycle struct {
+			Vertices []string    `json:"vertices"`
+			Edges    [][2]string `json:"edges"`
+		}
+		enc := json.NewEncoder(w)
+		for _, c := range cs {
+			p := g.edgesToPath(c)
+			CheckError(enc.Encode(cycle{Vertices: p, Edges: g.pathToEdges(p)}))
+		}
+	case "edges":
+		for i, c := range cs {
+			if i > 0 {
+				fmt.Fprintln(w, "")
+			}
+			p := g.edgesToPath(c)
+			for _, e := range g.pathToEdges(p) {
+				fmt.Fprintf(w, "%s %s\n", e[0], e[1])
+			}
+		}
+	default:
+		Abortf("Unrecognized cycle format %q", format)
+	}
+}
+
+// OutputIfBalanced determines if a graph is balanced (i.e., contains no
+// frustrated cycles).  If so, it outputs the gauge transformation that makes
+// every coupling ferromagnetic and returns true.  Otherwise, it outputs
+// nothing and returns false.
+func OutputIfBalanced(w io.Writer, g Graph) bool {
+	sg := g.signedGraph()
+	sw, ok := sg.balancingSwitching()
+	if !ok {
+		return false
+	}
+	nsw := 0 // Number of switched vertices
+	for v, x := range sw {
+		fmt.Fprintf(w, "GAUGE %+d | %s\n", x, sg.Names[v])
+		if x < 0 {
+			nsw++
+		}
+	}
+	fmt.Fprintf(w, "#BALANCED %d / %d = %f\n", nsw, len(sw), float64(nsw)/float64(len(sw)))
+	return true
+}
+
+// OutputSolverComparison runs each built-in solver on the same graph and
+// outputs, for each, the energy it found, the number of edges its solution
+// leaves unsatisfied, how well those edges agree with the edges left
+// unsatisfied by the lowest-energy solution (Jaccard index), and the time
+// it took.  If preproc is true, the solvers are run on the graph as
+// simplified by preprocess, and their solutions are expanded back to the
+// original graph before being evaluated.  If fixSpin is true and the graph
+// has no external fields, the solvers hold one spin fixed to break the global
+// spin-flip symmetry.  When the exact solver runs on the unsimplified graph,
+// the ground-state degeneracy is output as well.
+func OutputSolverComparison(w io.Writer, g Graph, sweeps int, preproc, fixSpin bool, rng *rand.Rand) {
+	// Run each solver in turn.
+	type result struct {
+		Name  string  // Solver name
+		S     []int   // Spin assignment
+		E     float64 // Energy of S
+		Un    []int   // Edges left unsatisfied by S
+		Secs  float64 // Wall-clock time in seconds
+		Agree float64 // Agreement with the best solution's unsatisfied edges
+	}
+	im := g.isingModel()
+	rim := im
+	full := func(s []int) []int { return s }
+	if preproc {
+		// Simplify the graph and report how much smaller it became.
+		rg, red := g.preprocess()
+		rim = rg.isingModel()
+		fmt.Fprintf(w, "#PRE %d %d %d / %d\n", len(red.Fixed), len(red.Ties), len(rim.Names), len(im.Names))
+
+		// Expand solutions by name back to the original graph.
+		full = func(s []int) []int {
+			if s == nil {
+				return nil
+			}
+			byName := make(map[string]int, len(im.Names))
+			for i, x := range s {
+				byName[rim.Names[i]] = x
+			}
+			red.expand(byName)
+			fs := make([]int, len(im.Names))
+			for i, v := range im.Names {
+				fs[i] = byName[v]
+			}
+			return fs
+		}
+	}
+	if fixSpin {
+		if fim, ok := rim.fixSpin(); ok {
+			rim = fim
+		} else {
+			Warn("W007-ignored-option", "--fix-spin", "Not fixing a spin because the model is not symmetric under a global spin flip")
+		}
+	}
+	var nGS uint64 // Number of ground states enumerated by the exact solver
+	solvers := []struct {
+		Name  string
+		Solve func() []int
+	}{
+		{"greedy", func() []int { return rim.SolveGreedy(rng) }},
+		{"sa", func() []int { return rim.SolveAnnealing(sweeps, rng) }},
+		{"pt", func() []int { return rim.SolveTempering(sweeps, rng) }},
+		{"exact", func() []int {
+			s, n := rim.solveExact()
+			nGS = n
+			return s
+		}},
+	}
+	rs := make([]result, 0, len(solvers))
+	best := -1
+	for _, sv := range solvers {
+		start := time.Now()
+		s := full(sv.Solve())
+		secs := time.Since(start).Seconds()
+		if s == nil {
+			Warn("W008-skipped-solver", sv.Name, "Skipping the %s solver as infeasible for %d variables", sv.Name, len(rim.Names))
+			continue
+		}
+		rs = append(rs, result{Name: sv.Name, S: s, E: im.Energy(s), Un: im.unsatisfied(s), Secs: secs})
+		if best < 0 || rs[len(rs)-1].E < rs[best].E {
+			best = len(rs) - 1
+		}
+	}
+
+	// Compare each solver's unsatisfied edges to the best solver's.
+	bestUn := make(map[int]Empty, len(rs[best].Un))
+	for _, k := range rs[best].Un {
+		bestUn[k] = Empty{}
+	}
+	for i, r := range rs {
+		both := 0
+		for _, k := range r.Un {
+			if _, ok := bestUn[k]; ok {
+				both++
+			}
+		}
+		either := len(r.Un) + len(bestUn) - both
+		rs[i].Agree = 1.0
+		if either > 0 {
+			rs[i].Agree = float64(both) / float64(either)
+		}
+	}
+
+	// Output the results.
+	for _, r := range rs {
+		fmt.Fprintf(w, "SOL  %s %f %d %f %f\n", r.Name, r.E, len(r.Un), r.Agree, r.Secs)
+	}
+	fmt.Fprintf(w, "#SOL %s %f %d / %d = %f\n", rs[best].Name, rs[best].E, len(rs[best].Un), len(im.Edges), float64(len(rs[best].Un))/float64(len(im.Edges)))
+	outputGroundStateEnergy(w, g, rs[best].E)
+	if nGS > 0 && !preproc {
+		all, mod := rim.degeneracy(nGS)
+		fmt.Fprintf(w, "#DEG %d %d\n", all, mod)
+	}
+}
+
+// outputGroundStateEnergy outputs an estimate of a graph's ground-state
+// energy both in the internal Ising convention and in the convention of the
+// input file, which may differ by a constant offset.
+func outputGroundStateEnergy(w io.Writer, g Graph, e float64) {
+	fmt.Fprintf(w, "#GSE %f %f %f\n", e, e+g.Offset, g.Offset)
+}
+
+// OutputBatchResults analyzes each problem in a batch of bqpjson problems and
+// outputs a single JSON object with two fields: "results", which maps each
+// problem's ID to its results, and "provenance", which describes the run.
+// If useNames is true, vertices are named by the metadata's var_names rather
+// than by their variable IDs.
+func OutputBatchResults(w io.Writer, hr *HashingReader, c Classifier, allCycs, useNames bool, rules ClassRules, prov Provenance, rng *rand.Rand) {
+	fmt.Fprint(w, "{\n  \"results\": {")
+	n := 0 // Number of problems output so far
+	ReadBqpjsonBatch(hr, func(id string, g Graph) {
+		if useNames {
+			g = g.WithVarNames()
+		}
+		bcs, ecs, _ := g.findCycles(allCycs, rng)
+		res := AnalyzeGraph(g, bcs, ecs, c, allCycs, rules)
+		key, err := json.Marshal(id)
+		CheckError(err)
+		val, err := json.MarshalIndent(res, "    ", "  ")
+		CheckError(err)
+		if n > 0 {
+			fmt.Fprint(w, ",")
+		}
+		fmt.Fprintf(w, "\n    %s: %s", key, val)
+		n++
+	})
+	prov.InputSHA256 = hr.Sum()
+	pj, err := json.MarshalIndent(prov, "  ", "  ")
+	CheckError(err)
+	fmt.Fprintf(w, "\n  },\n  \"provenance\": %s\n}\n", pj)
+}
+
+// OutputSpanningForest outputs a spanning forest that maximizes the total
+// coupler magnitude, and hence satisfies as many strong couplers as
+// possible, followed by each chord (non-forest edge) whose fundamental cycle
+// with respect to that forest is frustrated.  Such chords are necessarily
+// incompatible with the forest's couplers.  Coupler strengths are output in
+// the given view, and cycles are deemed frustrated by a given Classifier.
+func OutputSpanningForest(w io.Writer, g Graph, view string, c Classifier) {
+	sg := g.signedGraph()
+	tEdges, ntEdges := sg.maxWeightSpanningForest()
+	for _, i := range tEdges {
+		e := sg.edgeName(i)
+		fmt.Fprintf(w, "TE   %v | %s %s\n", g.couplerIn(view, e), e[0], e[1])
+	}
+	f := sg.treeForest(tEdges)
+	nfch := 0 // Number of frustrated chords
+	for _, i := range ntEdges {
+		e := sg.edgeName(i)
+		if c.Classify(g, sg.pathNames(f.fundamentalCycle(sg.Edges[i]))) {
+			fmt.Fprintf(w, "FCH  %v | %s %s\n", g.couplerIn(view, e), e[0], e[1])
+			nfch++
+		}
+	}
+	fmt.Fprintf(w, "#FCH %d / %d = %f\n", nfch, len(ntEdges), fraction(nfch, len(ntEdges)))
+}
+
+// OutputAudit checks each of a list of candidate solutions against a graph.
+// For each solution it reports the energy and the edges the solution leaves
+// unsatisfied, distinguishing edges that lie in at least one frustrated base
+// cycle, which some edge in the cycle must violate, from edges that lie in
+// none, which indicate a possibly suboptimal solution.  Cycles are deemed
+// frustrated by a given Classifier.
+func OutputAudit(w io.Writer, g Graph, sols []Solution, c Classifier, rng *rand.Rand) {
+	if len(sols) == 0 {
+		Abortf("No solutions were found to audit")
+	}
+
+	// Determine which edges appear in a frustrated base cycle.
+	im := g.isingModel()
+	_, cs, _ := g.findCycles(false, rng)
+	ps, isFrust := g.classifyCycles(cs, c)
+	fEdges, _ := tallyEdges(ps, isFrust)
+
+	// Audit each solution in turn.
+	best, bestE := "", math.Inf(1)
+	for _, sol := range sols {
+		s, extra := sol.spinVector(im.Names)
+		if extra > 0 {
+			Warn("W005-unknown-variables", sol.Label, "Ignoring %d variable(s) in solution %s that do not appear in the graph", extra, sol.Label)
+		}
+		e := im.Energy(s)
+		if e < bestE {
+			best, bestE = sol.Label, e
+		}
+		un := im.unsatisfied(s)
+		lines := make([]string, len(un))
+		nAvoid := 0
+		for i, k := range un {
+			u, v := im.Names[im.Edges[k][0]], im.Names[im.Edges[k][1]]
+			tag := "F"
+			if fEdges[[2]string{u, v}] == 0 {
+				tag = "NF"
+				nAvoid++
+			}
+			lines[i] = fmt.Sprintf("AUDE %s %s | %s %s\n", sol.Label, tag, u, v)
+		}
+		fmt.Fprintf(w, "AUD  %s %f %d %d\n", sol.Label, e, len(un), nAvoid)
+		for _, ln := range lines {
+			fmt.Fprint(w, ln)
+		}
+	}
+	fmt.Fprintf(w, "#AUD %s %f %d\n", best, bestE, len(sols))
+	outputGroundStateEnergy(w, g, bestE)
+}
+
+// outputEdgeGroups breaks down frustration statistics by groups of edges.
+// group maps each edge to the name of its group.  For each group, it outputs
+// the number of frustrated cycles containing an edge from the group, the
+// number of such cycles, the number of frustrated edges in the group, and the
+// number of edges in the group, sorted from most to fewest frustrated
+// cycles.
+func outputEdgeGroups(w io.Writer, g Graph, tag string, ps [][]string, isFrust []bool, group func(e [2]string) string) {
+	// Tally edges and cycles by group.
+	type tally struct {
+		FE, E int // Frustrated and total edges
+		FC, C int // Frustrated and total cycles
+	}
+	tallies := make(map[string]*tally)
+	get := func(e [2]string) *tally {
+		k := group(e)
+		if _, ok := tallies[k]; !ok {
+			tallies[k] = &tally{}
+		}
+		return tallies[k]
+	}
+	fEdges, nfEdges := tallyEdges(ps, isFrust)
+	for e := range g.Es {
+		t := get(e)
+		t.E++
+		if fEdges[e] > nfEdges[e] {
+			t.FE++
+		}
+	}
+	for i, p := range ps {
+		ts := make(map[*tally]Empty)
+		for _, e := range g.pathToEdges(p) {
+			ts[get(e)] = Empty{}
+		}
+		for t := range ts {
+			t.C++
+			if isFrust[i] {
+				t.FC++
+			}
+		}
+	}
+
+	// Output the tallies from most to least frustrated.
+	ks := make([]string, 0, len(tallies))
+	for k := range tallies {
+		ks = append(ks, k)
+	}
+	sort.Slice(ks, func(i, j int) bool {
+		ti, tj := tallies[ks[i]], tallies[ks[j]]
+		if ti.FC != tj.FC {
+			return ti.FC > tj.FC
+		}
+		return ks[i] < ks[j]
+	})
+	for _, k := range ks {
+		t := tallies[k]
+		fmt.Fprintf(w, "%-4s %d %d %d %d | %s\n", tag, t.FC, t.C, t.FE, t.E, k)
+	}
+}
+
+// OutputMacroBreakdown breaks down frustration statistics by the QMASM macro
+// and macro instantiation from which each edge arose.  A cycle is attributed
+// to every macro and instance that contributes at least one of its edges.
+func OutputMacroBreakdown(w io.Writer, g Graph, ps [][]string, isFrust []bool) {
+	if g.EOrigin == nil {
+		Warn("W007-ignored-option", "--by-macro", "Ignoring --by-macro for an input format that has no macros")
+		return
+	}
+	top := func(s string) string {
+		if s == "" {
+			return "<top>"
+		}
+		return s
+	}
+	outputEdgeGroups(w, g, "MAC", ps, isFrust, func(e [2]string) string { return top(g.EOrigin[e].Macro) })
+	outputEdgeGroups(w, g, "MACI", ps, isFrust, func(e [2]string) string { return top(g.EOrigin[e].Instance) })
+}
+
+// OutputEdgeKindBreakdown breaks down frustration statistics by edge kind
+// (chain, logical, penalty, or encoding).  A cycle is attributed to every
+// kind of edge it contains.
+func OutputEdgeKindBreakdown(w io.Writer, g Graph, ps [][]string, isFrust []bool) {
+	if g.EKind == nil {
+		Warn("W007-ignored-option", "--by-edge-kind", "Ignoring --by-edge-kind for an input format that has no edge kinds (try --embedding)")
+		return
+	}
+	outputEdgeGroups(w, g, "EK", ps, isFrust, func(e [2]string) string { return g.EKind[e] })
+}
+
+// OutputSubsetBreakdown breaks down frustration statistics by whether edges
+// lie inside a subset of the vertices, outside it, or on its boundary (i.e.,
+// with one endpoint inside and one outside).  A cycle is attributed to every
+// such group of edges it contains.
+func OutputSubsetBreakdown(w io.Writer, g Graph, ps [][]string, isFrust []bool, sub map[string]Empty) {
+	nIn := 0 // Number of subset vertices that appear in the graph
+	for v := range sub {
+		if _, ok := g.Vs[v]; ok {
+			nIn++
+		}
+	}
+	if nIn < len(sub) {
+		Warn("W005-unknown-variables", "--subset", "Ignoring %d --subset variables that do not appear in the graph", len(sub)-nIn)
+	}
+	outputEdgeGroups(w, g, "SUB", ps, isFrust, func(e [2]string) string {
+		_, in0 := sub[e[0]]
+		_, in1 := sub[e[1]]
+		switch {
+		case in0 && in1:
+			return "inside"
+		case !in0 && !in1:
+			return "outside"
+		default:
+			return "boundary"
+		}
+	})
+	fmt.Fprintf(w, "#SUB %d / %d = %f\n", nIn, len(g.Vs), float64(nIn)/float64(len(g.Vs)))
+}
+
+// namePrefix returns the portion of a vertex name that precedes the first of
+// a set of delimiter characters or, if the name begins with or contains none
+// of them, the entire name.
+func namePrefix(v, delims string) string {
+	if i := strings.IndexAny(v, delims); i > 0 {
+		return v[:i]
+	}
+	return v
+}
+
+// OutputPrefixBreakdown breaks down frustration statistics by the prefix of
+// each vertex name that precedes the first of a set of delimiter characters,
+// which for models produced by a modeling front end names the family of
+// variables (e.g., "route" for "route[7][2]").  For each family, it outputs
+// the number of frustrated cycles through a vertex of the family, the number
+// of such cycles, the number of frustrated vertices in the family, and the
+// number of vertices in the family, sorted from most to fewest frustrated
+// cycles.  It then breaks down the edges by the family or pair of families
+// they join.
+func OutputPrefixBreakdown(w io.Writer, g Graph, ps [][]string, isFrust []bool, delims string) {
+	// Tally vertices and cycles by family.
+	type tally struct {
+		FV, V int // Frustrated and total vertices
+		FC, C int // Frustrated and total cycles
+	}
+	tallies := make(map[string]*tally)
+	get := func(v string) *tally {
+		k := namePrefix(v, delims)
+		if _, ok := tallies[k]; !ok {
+			tallies[k] = &tally{}
+		}
+		return tallies[k]
+	}
+	vts, _ := g.tallyMembers(ps, isFrust, false)
+	for v := range g.Vs {
+		t := get(v)
+		t.V++
+		if mt := vts[v]; mt != nil && mt.frustrated(MembershipRule{}) {
+			t.FV++
+		}
+	}
+	for i, p := range ps {
+		ts := make(map[*tally]Empty)
+		for _, v := range p {
+			ts[get(v)] = Empty{}
+		}
+		for t := range ts {
+			t.C++
+			if isFrust[i] {
+				t.FC++
+			}
+		}
+	}
+
+	// Output the tallies from most to least frustrated.
+	ks := make([]string, 0, len(tallies))
+	for k := range tallies {
+		ks = append(ks, k)
+	}
+	sort.Slice(ks, func(i, j int) bool {
+		ti, tj := tallies[ks[i]], tallies[ks[j]]
+		if ti.FC != tj.FC {
+			return ti.FC > tj.FC
+		}
+		return ks[i] < ks[j]
+	})
+	for _, k := range ks {
+		t := tallies[k]
+		fmt.Fprintf(w, "PFX  %d %d %d %d | %s\n", t.FC, t.C, t.FV, t.V, k)
+	}
+
+	// Break down the edges by the families of their endpoints.
+	outputEdgeGroups(w, g, "PFXE", ps, isFrust, func(e [2]string) string {
+		p0, p1 := namePrefix(e[0], delims), namePrefix(e[1], delims)
+		switch {
+		case p0 == p1:
+			return p0
+		case p0 > p1:
+			p0, p1 = p1, p0
+		}
+		return p0 + " " + p1
+	})
+	fmt.Fprintf(w, "#PFX %d / %d = %f\n", len(tallies), len(g.Vs), fraction(len(tallies), len(g.Vs)))
+}
+
+// OutputSoftFrustration reports, for each inverse temperature, the mean soft
+// cycle product of the cycles through each edge and summary statistics over
+// all frustrated cycles.  isFrust says whether each cycle is frustrated.
+func OutputSoftFrustration(w io.Writer, g Graph, ecs [][][2]string, isFrust []bool, betas []float64) {
+	es := g.sortedEdges()
+	for _, beta := range betas {
+		// Accumulate each edge's cycle products.
+		prods := g.softCycleProducts(ecs, beta)
+		sum := make(map[[2]string]float64, len(g.Es))
+		num := make(map[[2]string]int, len(g.Es))
+		fSum, nf := 0.0, 0
+		for i, ec := range ecs {
+			for _, e := range ec {
+				sum[e] += prods[i]
+				num[e]++
+			}
+			if isFrust[i] {
+				fSum += prods[i]
+				nf++
+			}
+		}
+
+		// Output the mean for each edge that lies in a cycle.
+		for _, e := range es {
+			if num[e] > 0 {
+				fmt.Fprintf(w, "SFE  %g %f | %s %s\n", beta, sum[e]/float64(num[e]), e[0], e[1])
+			}
+		}
+		mean := 0.0
+		if nf > 0 {
+			mean = fSum / float64(nf)
+		}
+		fmt.Fprintf(w, "#SFE %g %f %f\n", beta, -fSum, -mean)
+	}
+}
+
+// OutputExactFrustrationIndex computes and reports a graph's exact
+// frustration index and a switching set that attains it.  If invariant is
+// true, the set, which depends on the gauge, is omitted.
+func OutputExactFrustrationIndex(w io.Writer, g Graph, invariant bool, rng *rand.Rand) {
+	sg := g.signedGraph()
+	neg, sw := sg.frustrationIndexExact(rng)
+	if !invariant {
+		fmt.Fprint(w, "SWX ")
+		for v, x := range sw {
+			if x < 0 {
+				fmt.Fprintf(w, " %s", sg.Names[v])
+			}
+		}
+		fmt.Fprintln(w, "")
+	}
+	fmt.Fprintf(w, "#FIX %d / %d = %f\n", neg, len(sg.Edges), float64(neg)/float64(len(sg.Edges)))
+}
+
+// OutputCycleSample estimates frustration statistics from a random sample of
+// cycles.  Each sampled cycle is weighted by the ratio of its root edge's
+// probability under uniform edge selection to its probability under the
+// proposal distribution, making both estimates unbiased regardless of the
+// proposal.
+func OutputCycleSample(w io.Writer, g Graph, n int, weighting string, rng *rand.Rand) {
+	outputSampleTally(w, g.TallySamples(n, weighting, rng))
+}
+
+// A sampleTally accumulates the importance-weighted observations made while
+// sampling cycles.  Tallies from independent samples of the same graph can be
+// combined by summing their fields.
+type sampleTally struct {
+	N         int     `json:"n"`          // Number of samples
+	Weighting string  `json:"weighting"`  // Proposal distribution
+	Eligible  int     `json:"eligible"`   // Number of edges eligible to root a cycle
+	SumW      float64 `json:"sum_w"`      // Sum of importance weights
+	SumW2     float64 `json:"sum_w2"`     // Sum of squared importance weights
+	SumF      float64 `json:"sum_f"`      // Sum of observations of the frustrated fraction
+	SumF2     float64 `json:"sum_f2"`     // Sum of squared observations of the frustrated fraction
+	SumAbsJ   float64 `json:"sum_abs_j"`  // Sum of observations of the |J|-weighted fraction
+	SumAbsJ2  float64 `json:"sum_abs_j2"` // Sum of squared observations of the |J|-weighted fraction
+}
+
+// TallySamples samples n cycles and tallies the observations each estimator
+// requires.
+func (g Graph) TallySamples(n int, weighting string, rng *rand.Rand) sampleTally {
+	t := sampleTally{N: n, Weighting: weighting}
+	if weighting == "cycle-space" {
+		// Uniform samples need no importance weights.
+		frust, d := g.sampleCycleSpace(n, rng)
+		t.Eligible = d
+		for _, f := range frust {
+			t.SumW++
+			t.SumW2++
+			if f {
+				t.SumF++
+				t.SumF2++
+			}
+		}
+		return t
+	}
+	samples, m, sumJ := g.sampleCycles(n, weighting, rng)
+	t.Eligible = m
+	for _, s := range samples {
+		iw := 1 / (float64(m) * s.Q)
+		t.SumW += iw
+		t.SumW2 += iw * iw
+		if s.Frustrated {
+			wf := s.AbsJ / sumJ / s.Q
+			t.SumF += iw
+			t.SumF2 += iw * iw
+			t.SumAbsJ += wf
+			t.SumAbsJ2 += wf * wf
+		}
+	}
+	return t
+}
+
+// meanStdErr returns the mean of n values and the standard error of that
+// mean given the sum of the values and the sum of their squares.
+func meanStdErr(n int, sum, sum2 float64) (float64, float64) {
+	mean := sum / float64(n)
+	if n < 2 {
+		return mean, 0
+	}
+	ss := math.Max(sum2-float64(n)*mean*mean, 0)
+	return mean, math.Sqrt(ss / float64(n-1) / float64(n))
+}
+
+// outputSampleTally outputs the estimates derived from a sample tally.
+func outputSampleTally(w io.Writer, t sampleTally) {
+	if t.Eligible == 0 {
+		Notify.Print("Graph is acyclic; no frustration can exist")
+		return
+	}
+	ff, ffErr := meanStdErr(t.N, t.SumF, t.SumF2)
+	fmt.Fprintf(w, "#SMP  %d %s %d %f\n", t.N, t.Weighting, t.Eligible, t.SumW*t.SumW/t.SumW2)
+	lo, hi := confidenceInterval(ff, ffErr)
+	fmt.Fprintf(w, "#SMPF %f %f %f %f\n", ff, ffErr, lo, hi)
+	if t.Weighting == "cycle-space" {
+		return // Cycles are not rooted at an edge whose |J| could weight them.
+	}
+	wf, wfErr := meanStdErr(t.N, t.SumAbsJ, t.SumAbsJ2)
+	lo, hi = confidenceInterval(wf, wfErr)
+	fmt.Fprintf(w, "#SMPW %f %f %f %f\n", wf, wfErr, lo, hi)
+}
+
+// confidenceInterval returns an approximate 95% confidence interval for a
+// fraction estimated with a given standard error, clamped to [0, 1].
+func confidenceInterval(est, stdErr float64) (float64, float64) {
+	const z95 = 1.959964 // 97.5th percentile of the standard normal distribution
+	return math.Max(est-z95*stdErr, 0), math.Min(est+z95*stdErr, 1)
+}
@@ -0,0 +1,387 @@
+/* This file structures a frustration analysis as a pipeline of stages:
+parsing, preprocessing, cycle discovery, classification, and reporting.  Each
+stage is defined by an interface, and implementations registered by name can
+be selected from the command line, so site-specific analyses can be added in
+a separate file without modifying the program. */
+
+package frustration
+
+import (
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// An Analysis is the state passed from one pipeline stage to the next.
+type Analysis struct {
+	Graph         Graph          // Graph being analyzed
+	BaseCycles    [][][2]string  // Base cycles (nil if not computed)
+	Cycles        [][][2]string  // Cycles to analyze, each a list of edges
+	NumDup        int            // Number of duplicate cycles removed
+	Paths         [][]string     // Each element of Cycles as a vertex path
+	Frustrated    []bool         // Whether each element of Cycles is frustrated
+	Trivial       []trivialCycle // Trivially resolvable frustrated cycles (nil if not sought)
+	NumFrustrated int            // Number of frustrated cycles before any were excluded
+	Rng           *rand.Rand     // Source of randomness for stochastic stages
+	Timer         *PhaseTimer    // Accumulator of per-phase timings (may be nil)
+}
+
+// A Parser reads a graph from an input file.
+type Parser interface {
+	Parse(r io.Reader) Graph
+}
+
+// A Preprocessor transforms an analysis's graph before cycles are found.
+type Preprocessor interface {
+	Preprocess(a *Analysis)
+}
+
+// A CycleFinder fills in an analysis's cycles.
+type CycleFinder interface {
+	FindCycles(a *Analysis)
+}
+
+// A Classifier says whether a cycle, given as a vertex path, is frustrated.
+// Cycles are classified concurrently, so Classify must be safe to call from
+// multiple goroutines.
+type Classifier interface {
+	Classify(g Graph, p []string) bool
+}
+
+// A Reporter outputs some aspect of a completed analysis.
+type Reporter interface {
+	Report(w io.Writer, a *Analysis)
+}
+
+// A CycleVisit describes one classified cycle to a CycleVisitor.  Edge i
+// joins Path[i] and Path[(i+1)%len(Path)] and has coupling sign Signs[i]:
+// +1 if it acts ferromagnetically or -1 if it acts antiferromagnetically.
+type CycleVisit struct {
+	Index      int         // Position of the cycle in the analysis's Cycles
+	Path       []string    // Vertices in cycle order
+	Edges      [][2]string // Edges in cycle order
+	Signs      []int       // Coupling sign of each edge
+	Frustrated bool        // Whether the pipeline's Classifier deemed the cycle frustrated
+}
+
+// A CycleVisitor is invoked once for each cycle as it is classified, letting
+// library callers compute statistics of their own in the same pass.  The
+// slices in a CycleVisit must not be modified.
+type CycleVisitor interface {
+	VisitCycle(a *Analysis, c CycleVisit)
+}
+
+// ParserFunc adapts an ordinary function to a Parser.
+type ParserFunc func(r io.Reader) Graph
+
+// Parse invokes f(r).
+func (f ParserFunc) Parse(r io.Reader) Graph { return f(r) }
+
+// PreprocessorFunc adapts an ordinary function to a Preprocessor.
+type PreprocessorFunc func(a *Analysis)
+
+// Preprocess invokes f(a).
+func (f PreprocessorFunc) Preprocess(a *Analysis) { f(a) }
+
+// CycleFinderFunc adapts an ordinary function to a CycleFinder.
+type CycleFinderFunc func(a *Analysis)
+
+// FindCycles invokes f(a).
+func (f CycleFinderFunc) FindCycles(a *Analysis) { f(a) }
+
+// ClassifierFunc adapts an ordinary function to a Classifier.
+type ClassifierFunc func(g Graph, p []string) bool
+
+// Classify invokes f(g, p).
+func (f ClassifierFunc) Classify(g Graph, p []string) bool { return f(g, p) }
+
+// CycleVisitorFunc adapts an ordinary function to a CycleVisitor.
+type CycleVisitorFunc func(a *Analysis, c CycleVisit)
+
+// VisitCycle invokes f(a, c).
+func (f CycleVisitorFunc) VisitCycle(a *Analysis, c CycleVisit) { f(a, c) }
+
+// ReporterFunc adapts an ordinary function to a Reporter.
+type ReporterFunc func(w io.Writer, a *Analysis)
+
+// Report invokes f(w, a).
+func (f ReporterFunc) Report(w io.Writer, a *Analysis) { f(w, a) }
+
+// registry maps each kind of stage to a map from name to implementation.
+// The built-in implementations are registered here; others can be added with
+// the Register* functions from an init function.
+var registry = map[string]map[string]interface{}{
+	"input format": {
+		"qmasm":    ParserFunc(ReadQMASMFile),
+		"qubist":   ParserFunc(ReadQubistFile),
+		"qubo":     ParserFunc(ReadQUBOFile),
+		"bqpjson":  ParserFunc(ReadBqpjsonFile),
+		"ffg":      ParserFunc(ReadFFGFile),
+		"maxcut":   ParserFunc(ReadMaxCutFile),
+		"coloring": ColoringParser{},
+		"sapi":     SAPIParser{},
+		"graphml":  GraphMLParser{},
+		"mtx":      ParserFunc(ReadMatrixMarketFile),
+		"csv":      DelimitedParser{},
+		"tsv":      DelimitedParser{Delimiter: '\t'},
+	},
+	"preprocessor": {
+		"dominance": PreprocessorFunc(preprocessDominance),
+		"core":      PreprocessorFunc(preprocessCore),
+	},
+	"cycle finder": {
+		"basis":         BasisFinder{},
+		"minimum-basis": BasisFinder{Minimum: true},
+	},
+	"classifier": {
+		"sign-parity":    ClassifierFunc(Graph.isFrustrated),
+		"coupler-parity": ClassifierFunc(Graph.isCouplerFrustrated),
+	},
+	"classifier family": {
+		"min-coupling": ClassifierFamily(minCouplingClassifier),
+		"softened":     ClassifierFamily(softenedClassifier),
+	},
+	"reporter": {},
+}
+
+// register adds a stage of a given kind to the registry.
+func register(kind, name string, stage interface{}) {
+	if _, ok := registry[kind][name]; ok {
+		Abortf("A %s named %q is already registered", kind, name)
+	}
+	registry[kind][name] = stage
+}
+
+// lookup returns the stage of a given kind registered under a given name.
+func lookup(kind, name string) interface{} {
+	stage, ok := registry[kind][name]
+	if !ok {
+		names := make([]string, 0, len(registry[kind]))
+		for n := range registry[kind] {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		Abortf("Unrecognized %s %q (available: %s)", kind, name, strings.Join(names, ", "))
+	}
+	return stage
+}
+
+// lookupList applies lookup to each name in a comma-separated list.
+func lookupList(kind, list string) []interface{} {
+	var stages []interface{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			stages = append(stages, lookup(kind, name))
+		}
+	}
+	return stages
+}
+
+// RegisterParser makes a Parser available as an input format.
+func RegisterParser(name string, p Parser) { register("input format", name, p) }
+
+// RegisterPreprocessor makes a Preprocessor available to --preprocessors.
+func RegisterPreprocessor(name string, p Preprocessor) { register("preprocessor", name, p) }
+
+// RegisterCycleFinder makes a CycleFinder available to --cycle-finder.
+func RegisterCycleFinder(name string, cf CycleFinder) { register("cycle finder", name, cf) }
+
+// RegisterClassifier makes a Classifier available to --classifier.
+func RegisterClassifier(name string, c Classifier) { register("classifier", name, c) }
+
+// RegisterReporter makes a Reporter available to --reporters.
+func RegisterReporter(name string, rep Reporter) { register("reporter", name, rep) }
+
+// LookupParser returns the Parser registered under a given name.
+func LookupParser(name string) Parser { return lookup("input format", name).(Parser) }
+
+// LookupPreprocessors returns the Preprocessors named in a comma-separated
+// list.
+func LookupPreprocessors(list string) []Preprocessor {
+	var ps []Preprocessor
+	for _, p := range lookupList("preprocessor", list) {
+		ps = append(ps, p.(Preprocessor))
+	}
+	return ps
+}
+
+// LookupCycleFinder returns the CycleFinder registered under a given name.
+func LookupCycleFinder(name string) CycleFinder { return lookup("cycle finder", name).(CycleFinder) }
+
+// LookupReporters returns the Reporters named in a comma-separated list.
+func LookupReporters(list string) []Reporter {
+	var rs []Reporter
+	for _, rep := range lookupList("reporter", list) {
+		rs = append(rs, rep.(Reporter))
+	}
+	return rs
+}
+
+// A Pipeline is the sequence of stages that follows parsing.
+type Pipeline struct {
+	Preprocessors []Preprocessor // Graph transformations, applied in order
+	CycleFinder   CycleFinder    // Source of the cycles to analyze
+	Classifier    Classifier     // Judge of which cycles are frustrated
+	Visitors      []CycleVisitor // Callbacks invoked, in order, for each classified cycle
+	Reporters     []Reporter     // Outputs, produced in order
+	Threads       int            // Number of goroutines that classify cycles (0: GOMAXPROCS)
+}
+
+// Preprocess applies each of the pipeline's preprocessors to an analysis.
+func (pl Pipeline) Preprocess(a *Analysis) {
+	for _, p := range pl.Preprocessors {
+		a.Timer.Time("preprocess", func() { p.Preprocess(a) })
+	}
+}
+
+// FindCycles finds the cycles to analyze.
+func (pl Pipeline) FindCycles(a *Analysis) {
+	pl.CycleFinder.FindCycles(a)
+}
+
+// Classify converts each cycle to a path and says whether it is frustrated,
+// dividing the cycles among the pipeline's goroutines, and then passes each
+// cycle, in order, to the pipeline's visitors.
+func (pl Pipeline) Classify(a *Analysis) {
+	a.Timer.Time("classify", func() {
+		a.Paths, a.Frustrated = a.Graph.classifyConcurrently(a.Cycles, pl.Classifier, pl.Threads)
+		if len(pl.Visitors) == 0 {
+			return
+		}
+		for i, p := range a.Paths {
+			cv := a.Graph.cycleVisit(i, p, a.Frustrated[i])
+			for _, v := range pl.Visitors {
+				v.VisitCycle(a, cv)
+			}
+		}
+	})
+}
+
+// cycleVisit describes a classified cycle for the benefit of CycleVisitors.
+func (g Graph) cycleVisit(i int, p []string, frust bool) CycleVisit {
+	cv := CycleVisit{
+		Index:      i,
+		Path:       p,
+		Edges:      g.pathToEdges(p),
+		Signs:      make([]int, len(p)),
+		Frustrated: frust,
+	}
+	for k, u := range p {
+		cv.Signs[k], _ = g.couplingSign(u, p[(k+1)%len(p)])
+	}
+	return cv
+}
+
+// Report invokes each of the pipeline's reporters in turn.
+func (pl Pipeline) Report(w io.Writer, a *Analysis) {
+	a.Timer.Time("output", func() {
+		for _, rep := range pl.Reporters {
+			rep.Report(w, a)
+		}
+	})
+}
+
+// Run applies each stage of the pipeline in turn to a graph and returns the
+// resulting analysis.  If any stage aborts, Run returns the error instead of
+// panicking.
+func (pl Pipeline) Run(w io.Writer, g Graph, rng *rand.Rand) (a *Analysis, err error) {
+	defer RecoverFatal(&err)
+	a = &Analysis{Graph: g, Rng: rng}
+	pl.Preprocess(a)
+	pl.FindCycles(a)
+	pl.Classify(a)
+	pl.Report(w, a)
+	return a, nil
+}
+
+// preprocessDominance replaces an analysis's graph with the graph that
+// remains after fixing and eliminating variables by field dominance and
+// coupler persistency.
+func preprocessDominance(a *Analysis) {
+	rg, red := a.Graph.preprocess()
+	rg.Offset = a.Graph.Offset + red.Offset
+	a.Graph = rg
+}
+
+// preprocessCore replaces an analysis's graph with its frustration core.
+func preprocessCore(a *Analysis) {
+	a.Graph = a.Graph.FrustrationCore()
+}
+
+// A BasisFinder finds a graph's base cycles.
+type BasisFinder struct {
+	Minimum bool // Find a minimum cycle basis rather than a spanning tree's fundamental cycles
+}
+
+// FindCycles sets both the base cycles and the cycles to analyze to the
+// graph's base cycles.
+func (bf BasisFinder) FindCycles(a *Analysis) {
+	a.Timer.Time("basis", func() {
+		if bf.Minimum {
+			a.BaseCycles = a.Graph.minimumCycleBasis()
+			a.Cycles = a.BaseCycles
+			return
+		}
+		a.BaseCycles, a.Cycles, a.NumDup = a.Graph.findCycles(false, a.Rng)
+	})
+}
+
+// An ElementaryFinder finds a graph's elementary cycles, refusing to do so
+// if their estimated number exceeds a budget unless forced.
+type ElementaryFinder struct {
+	Budget  float64     // Maximum estimated number of elementary cycles
+	Force   bool        // Proceed even if the budget is exceeded
+	Rule    CombineRule // Restriction on which combinations to retain
+	Minimum bool        // Use a minimum cycle basis as the base cycles
+	MaxLen  int         // If positive, enumerate only the cycles of at most this many edges
+}
+
+// FindCycles sets the base cycles to the graph's base cycles and the cycles
+// to analyze to the graph's elementary cycles, or to those no longer than
+// MaxLen.
+func (ef ElementaryFinder) FindCycles(a *Analysis) {
+	BasisFinder{Minimum: ef.Minimum}.FindCycles(a)
+	a.NumDup = 0
+	if len(a.BaseCycles) == 0 {
+		return
+	}
+
+	// Enumerate short cycles directly if requested.  The enumeration's
+	// cost is bounded by the length limit, so it bypasses the budget.
+	if ef.MaxLen > 0 {
+		a.Timer.Time("combine", func() { a.Cycles = a.Graph.shortCycles(ef.MaxLen) })
+		return
+	}
+
+	// Refuse to enumerate the elementary cycles if doing so would likely
+	// take too long.  The estimate does not account for a restrictive
+	// combination rule, so such a rule bypasses the budget.
+	if !ef.Rule.Restricted() {
+		est := a.Graph.estimateElementaryCycles(a.BaseCycles, a.Rng)
+		switch {
+		case est <= ef.Budget:
+		case ef.Force:
+			Notify.Printf("Proceeding with an estimated %.3g elementary cycles (from %d base cycles)", est, len(a.BaseCycles))
+		default:
+			Abortf("An estimated %.3g elementary cycles (from %d base cycles) exceeds the budget of %.3g; specify --force to proceed anyway", est, len(a.BaseCycles), ef.Budget)
+		}
+	}
+	a.Timer.Time("combine", func() {
+		a.Cycles, a.NumDup = a.Graph.dedupCycles(a.Graph.elementaryCycles(a.BaseCycles, ef.Rule))
+	})
+}
+
+// A ThroughEdgeFinder finds cycles passing through given edges.
+type ThroughEdgeFinder struct {
+	Edges [][2]string // Edges through which every cycle must pass
+	All   bool        // true to find all elementary cycles through the edges
+}
+
+// FindCycles sets the cycles to analyze to the cycles through the given
+// edges.  No base cycles are computed.
+func (tf ThroughEdgeFinder) FindCycles(a *Analysis) {
+	a.Timer.Time("cycles", func() {
+		a.Cycles = a.Graph.cyclesThroughEdges(tf.Edges, tf.All)
+	})
+}
@@ -0,0 +1,152 @@
+/* This file implements standard Ising preprocessing rules that fix or
+eliminate variables whose optimal values are implied by dominance. */
+
+package frustration
+
+import (
+	"math"
+)
+
+// A tie records that a variable was eliminated by coupler persistency: in
+// some ground state, the spin of V equals Sign times the spin of To.
+type tie struct {
+	V, To string // Eliminated and surviving variables
+	Sign  int    // +1 if the spins are equal, -1 if they are opposite
+}
+
+// A reduction records how preprocessing simplified a graph so that a
+// solution to the simplified graph can be expanded to the original.
+type reduction struct {
+	Fixed  map[string]int // Variables fixed to a spin by field dominance
+	Ties   []tie          // Variables tied to another, in elimination order
+	Offset float64        // Energy removed from the graph by preprocessing
+}
+
+// preprocess repeatedly applies two rules until neither applies, returning
+// the simplified graph and a record of the simplification.  Both rules
+// preserve at least one ground state.
+//
+// Field dominance: if |h_i| >= sum_j |J_ij|, then s_i = -sign(h_i).
+//
+// Coupler persistency: if |J_ij| >= |h_i| + sum_{k != j} |J_ik|, then
+// s_i = -sign(J_ij) s_j.
+func (g Graph) preprocess() (Graph, reduction) {
+	// Copy the graph into a mutable adjacency structure.
+	h := make(map[string]float64, len(g.Vs))
+	for v, wt := range g.Vs {
+		h[v] = wt
+	}
+	adj := make(map[string]map[string]float64, len(g.Vs))
+	for v := range g.Vs {
+		adj[v] = make(map[string]float64)
+	}
+	for e, wt := range g.Es {
+		adj[e[0]][e[1]] += wt
+		adj[e[1]][e[0]] += wt
+	}
+	red := reduction{Fixed: make(map[string]int)}
+
+	// removeEdge removes the edge between u and v.
+	removeEdge := func(u, v string) {
+		delete(adj[u], v)
+		delete(adj[v], u)
+	}
+
+	// Process a worklist of vertices, initially all of them in sorted order
+	// for determinism.
+	work := g.sortedVertices()
+	queued := make(map[string]bool, len(work))
+	for _, v := range work {
+		queued[v] = true
+	}
+	enqueue := func(v string) {
+		if !queued[v] {
+			queued[v] = true
+			work = append(work, v)
+		}
+	}
+	for len(work) > 0 {
+		i := work[0]
+		work = work[1:]
+		queued[i] = false
+		if _, ok := adj[i]; !ok {
+			continue // Already eliminated
+		}
+
+		// Find i's total coupling and strongest coupler.
+		sum, maxJ, maxV := 0.0, 0.0, ""
+		for k, j := range adj[i] {
+			sum += math.Abs(j)
+			if math.Abs(j) > maxJ || (math.Abs(j) == maxJ && k < maxV) {
+				maxJ, maxV = math.Abs(j), k
+			}
+		}
+
+		switch {
+		case math.Abs(h[i]) >= sum:
+			// Field dominance: fix s_i and fold its couplers into
+			// its neighbors' fields.
+			s := 1
+			if h[i] > 0 {
+				s = -1
+			}
+			red.Fixed[i] = s
+			red.Offset += h[i] * float64(s)
+			for k, j := range adj[i] {
+				h[k] += j * float64(s)
+				removeEdge(i, k)
+				enqueue(k)
+			}
+			delete(adj, i)
+			delete(h, i)
+
+		case maxJ >= math.Abs(h[i])+sum-maxJ:
+			// Coupler persistency: replace s_i with sign*s_j.
+			j := maxV
+			sign := 1
+			if adj[i][j] > 0 {
+				sign = -1
+			}
+			red.Ties = append(red.Ties, tie{V: i, To: j, Sign: sign})
+			red.Offset += adj[i][j] * float64(sign)
+			removeEdge(i, j)
+			h[j] += h[i] * float64(sign)
+			for k, jk := range adj[i] {
+				removeEdge(i, k)
+				adj[j][k] += jk * float64(sign)
+				adj[k][j] = adj[j][k]
+				if adj[j][k] == 0 {
+					removeEdge(j, k)
+				}
+				enqueue(k)
+			}
+			enqueue(j)
+			delete(adj, i)
+			delete(h, i)
+		}
+	}
+
+	// Convert the adjacency structure back to a graph.
+	rg := Graph{Vs: h, Es: make(map[[2]string]float64)}
+	for u, ns := range adj {
+		for v, j := range ns {
+			if u < v {
+				rg.Es[[2]string{u, v}] = j
+			}
+		}
+	}
+	return rg, red
+}
+
+// expand maps a solution to a preprocessed graph, given as a map from
+// variable name to spin, to a solution to the original graph.  The map is
+// modified in place.
+func (red reduction) expand(s map[string]int) {
+	for v, x := range red.Fixed {
+		s[v] = x
+	}
+	for i := len(red.Ties) - 1; i >= 0; i-- {
+		t := red.Ties[i]
+		s[t.V] = t.Sign * s[t.To]
+	}
+}
@@ -0,0 +1,135 @@
+/* This file records the provenance of a set of results so that output files
+are self-describing. */
+
+package frustration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// version is find-frustration's version string.  Release builds can set it
+// with -ldflags "-X github.com/lanl/find-frustration/frustration.version=...".
+var version = "devel"
+
+// A Provenance records how a set of results was produced.
+type Provenance struct {
+	Version     string            `json:"version"`      // find-frustration version
+	Command     string            `json:"command"`      // Subcommand ("analyze" if none)
+	InputFile   string            `json:"input_file"`   // Input file name ("-" for standard input)
+	InputSHA256 string            `json:"input_sha256"` // SHA-256 hash of the input bytes
+	Format      string            `json:"format"`       // Input format
+	Flags       map[string]string `json:"flags"`        // Command-line flags that were explicitly set
+	Seed        int64             `json:"seed"`         // Seed of the pseudorandom number generator
+	Timestamp   string            `json:"timestamp"`    // Time of the run in RFC 3339 format
+	Hostname    string            `json:"hostname"`     // Name of the host that produced the results
+}
+
+// NewProvenance returns a Provenance for the current run.  The caller is
+// responsible for filling in InputSHA256.
+func NewProvenance(cmd, inName, inFmt string, seed int64) Provenance {
+	if cmd == "" {
+		cmd = "analyze"
+	}
+	if inName == "" {
+		inName = "-"
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	flags := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+	return Provenance{
+		Version:   version,
+		Command:   cmd,
+		InputFile: inName,
+		Format:    inFmt,
+		Flags:     flags,
+		Seed:      seed,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Hostname:  host,
+	}
+}
+
+// WriteText writes the provenance as a sequence of lines, each beginning
+// with the given tag.
+func (p Provenance) WriteText(w io.Writer, tag string) {
+	names := make([]string, 0, len(p.Flags))
+	for n := range p.Flags {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	flags := make([]string, len(names))
+	for i, n := range names {
+		flags[i] = fmt.Sprintf("--%s=%s", n, p.Flags[n])
+	}
+	fmt.Fprintf(w, "%s version %s\n", tag, p.Version)
+	fmt.Fprintf(w, "%s command %s\n", tag, p.Command)
+	fmt.Fprintf(w, "%s input %s\n", tag, p.InputFile)
+	fmt.Fprintf(w, "%s sha256 %s\n", tag, p.InputSHA256)
+	fmt.Fprintf(w, "%s format %s\n", tag, p.Format)
+	fmt.Fprintf(w, "%s flags %s\n", tag, strings.Join(flags, " "))
+	fmt.Fprintf(w, "%s seed %d\n", tag, p.Seed)
+	fmt.Fprintf(w, "%s time %s\n", tag, p.Timestamp)
+	fmt.Fprintf(w, "%s host %s\n", tag, p.Hostname)
+}
+
+// WriteXMLComment writes the provenance as an XML comment, one item per
+// line.  XML forbids "--" within a comment, so each such pair of hyphens,
+// as in a flag name, is written as "- -".
+func (p Provenance) WriteXMLComment(w io.Writer) {
+	var sb strings.Builder
+	p.WriteText(&sb, " ")
+	s := sb.String()
+	for strings.Contains(s, "--") {
+		s = strings.ReplaceAll(s, "--", "- -")
+	}
+	fmt.Fprintf(w, "<!--\n%s-->\n", s)
+}
+
+// WriteNDJSON writes the provenance as a single-line JSON object with a
+// "provenance" field.
+func (p Provenance) WriteNDJSON(w io.Writer) {
+	CheckError(json.NewEncoder(w).Encode(struct {
+		Provenance Provenance `json:"provenance"`
+	}{p}))
+}
+
+// A HashingReader computes a SHA-256 hash of everything read through it.
+type HashingReader struct {
+	r io.Reader // Underlying reader
+	h hash.Hash // Running hash
+}
+
+// NewHashingReader wraps a reader with a HashingReader.
+func NewHashingReader(r io.Reader) *HashingReader {
+	return &HashingReader{r: r, h: sha256.New()}
+}
+
+// Read reads from the underlying reader and updates the hash.
+func (hr *HashingReader) Read(p []byte) (int, error) {
+	n, err := hr.r.Read(p)
+	hr.h.Write(p[:n])
+	return n, err
+}
+
+// Sum consumes any unread input and returns the hexadecimal SHA-256 hash of
+// the complete input.
+func (hr *HashingReader) Sum() string {
+	_, err := io.Copy(ioutil.Discard, hr)
+	CheckError(err)
+	return hex.EncodeToString(hr.h.Sum(nil))
+}
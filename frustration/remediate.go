@@ -0,0 +1,218 @@
+/* This file plans how to edit a problem's couplers to eliminate its
+frustration.  It combines the what-if analysis of --sign-flips, the
+weakest-link reasoning of --energy-gaps, and the search for a small set of
+edges that touches every frustrated cycle into a greedy sequence of concrete
+edits, re-evaluating every candidate edit after each step. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Kinds of coupler edit
+const (
+	EditFlip     = "flip"     // Negate the coupler
+	EditReweight = "reweight" // Change the coupler's magnitude so that its sign or the fields' sign prevails
+	EditRemove   = "remove"   // Set the coupler to zero
+)
+
+// ParseEditKinds parses a comma-separated list of edit kinds.
+func ParseEditKinds(s string) map[string]bool {
+	kinds := make(map[string]bool)
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		switch k {
+		case EditFlip, EditReweight, EditRemove:
+			kinds[k] = true
+		default:
+			Abortf("Unrecognized edit kind %q", k)
+		}
+	}
+	return kinds
+}
+
+// A couplerEdit is one step of a remediation plan.
+type couplerEdit struct {
+	Edge   [2]string // Coupler to edit
+	Kind   string    // Kind of edit
+	OldJ   float64   // Coupler strength before the edit
+	NewJ   float64   // Coupler strength after the edit
+	Fixed  int       // Frustrated cycles the edit makes non-frustrated or eliminates
+	Broken int       // Non-frustrated cycles the edit makes frustrated
+}
+
+// Gain returns the net reduction in frustrated cycles an edit achieves.
+func (ce couplerEdit) Gain() int {
+	return ce.Fixed - ce.Broken
+}
+
+// candidateEdits returns every permitted edit of a coupler given the number
+// of live frustrated and non-frustrated cycles through it.  A flip or
+// reweighting toggles the frustration of every cycle through the coupler,
+// while a removal eliminates those cycles.  A flip has no effect on a
+// coupler whose sign is determined by the external fields on its endpoints,
+// and a reweighting is possible only where the fields and the coupler
+// disagree in sign.
+func (g Graph) candidateEdits(e [2]string, f, nf int, kinds map[string]bool) []couplerEdit {
+	j := g.Es[e]
+	if j == 0 {
+		return nil
+	}
+	var cands []couplerEdit
+	_, byField := g.couplingSign(e[0], e[1])
+	if kinds[EditFlip] && !byField {
+		cands = append(cands, couplerEdit{Edge: e, Kind: EditFlip, OldJ: j, NewJ: -j, Fixed: f, Broken: nf})
+	}
+
+	// The fields prevail over the coupler exactly when both are stronger
+	// than it.  Reweighting the coupler across that threshold changes its
+	// sign only if the fields and the coupler disagree.
+	hu, hv := g.Vs[e[0]], g.Vs[e[1]]
+	hmin := math.Min(math.Abs(hu), math.Abs(hv))
+	if kinds[EditReweight] && hmin > 0 && (hu*hv < 0) != (j > 0) {
+		newJ := math.Copysign(hmin, j) // Strengthen the coupler until it prevails.
+		if !byField {
+			newJ = math.Copysign(hmin/2, j) // Weaken the coupler until the fields prevail.
+		}
+		cands = append(cands, couplerEdit{Edge: e, Kind: EditReweight, OldJ: j, NewJ: newJ, Fixed: f, Broken: nf})
+	}
+	if kinds[EditRemove] {
+		cands = append(cands, couplerEdit{Edge: e, Kind: EditRemove, OldJ: j, NewJ: 0, Fixed: f})
+	}
+	return cands
+}
+
+// betterEdit says whether edit a is preferable to edit b: it achieves a
+// greater net reduction in frustrated cycles or, failing that, changes a
+// sign rather than removing a coupler or, failing that, touches a weaker
+// coupler.
+func betterEdit(a, b couplerEdit) bool {
+	switch {
+	case a.Gain() != b.Gain():
+		return a.Gain() > b.Gain()
+	case (a.Kind == EditRemove) != (b.Kind == EditRemove):
+		return b.Kind == EditRemove
+	case math.Abs(a.OldJ) != math.Abs(b.OldJ):
+		return math.Abs(a.OldJ) < math.Abs(b.OldJ)
+	case a.Edge != b.Edge:
+		return a.Edge[0] < b.Edge[0] || (a.Edge[0] == b.Edge[0] && a.Edge[1] < b.Edge[1])
+	}
+	return a.Kind < b.Kind
+}
+
+// planRemediation greedily selects coupler edits, one per coupler, each of
+// which maximizes the net reduction in frustrated cycles given the edits
+// before it, until no permitted edit reduces frustration further.  It
+// returns the edits and the number of frustrated cycles remaining after
+// each.
+func (g Graph) planRemediation(ps [][]string, isFrust []bool, kinds map[string]bool) ([]couplerEdit, []int) {
+	// Index the cycles through each edge.
+	through := make(map[[2]string][]int)
+	for i, p := range ps {
+		for k, u := range p {
+			v := p[(k+1)%len(p)]
+			if u > v {
+				u, v = v, u
+			}
+			through[[2]string{u, v}] = append(through[[2]string{u, v}], i)
+		}
+	}
+	es := make([][2]string, 0, len(through))
+	for e := range through {
+		es = append(es, e)
+	}
+	sort.Slice(es, func(i, j int) bool {
+		if es[i][0] != es[j][0] {
+			return es[i][0] < es[j][0]
+		}
+		return es[i][1] < es[j][1]
+	})
+
+	// Repeatedly apply the best edit.
+	frust := append([]bool(nil), isFrust...)
+	alive := make([]bool, len(ps))
+	remaining := 0
+	for i := range alive {
+		alive[i] = true
+		if frust[i] {
+			remaining++
+		}
+	}
+	edited := make(map[[2]string]bool)
+	var plan []couplerEdit
+	var left []int
+	for remaining > 0 {
+		var best *couplerEdit
+		for _, e := range es {
+			if edited[e] {
+				continue
+			}
+			f, nf := 0, 0
+			for _, i := range through[e] {
+				switch {
+				case !alive[i]:
+				case frust[i]:
+					f++
+				default:
+					nf++
+				}
+			}
+			if f == 0 {
+				continue
+			}
+			for _, c := range g.candidateEdits(e, f, nf, kinds) {
+				if c.Gain() > 0 && (best == nil || betterEdit(c, *best)) {
+					c := c
+					best = &c
+				}
+			}
+		}
+		if best == nil {
+			break
+		}
+
+		// Apply the edit to the cycles through the edited coupler.
+		edited[best.Edge] = true
+		for _, i := range through[best.Edge] {
+			switch {
+			case !alive[i]:
+			case best.Kind == EditRemove:
+				alive[i] = false
+			default:
+				frust[i] = !frust[i]
+			}
+		}
+		remaining -= best.Gain()
+		plan = append(plan, *best)
+		left = append(left, remaining)
+	}
+	return plan, left
+}
+
+// OutputRemediation outputs a ranked plan of coupler edits that eliminates
+// as many frustrated cycles as possible, with the number of frustrated
+// cycles remaining after each step, followed by the fraction of frustrated
+// cycles the plan eliminates.
+func OutputRemediation(w io.Writer, g Graph, ps [][]string, isFrust []bool, kinds map[string]bool) {
+	nf := 0
+	for _, f := range isFrust {
+		if f {
+			nf++
+		}
+	}
+	plan, left := g.planRemediation(ps, isFrust, kinds)
+	for i, ce := range plan {
+		fmt.Fprintf(w, "REM  %d %s %v %v %d %d %d | %s %s\n",
+			i+1, ce.Kind, ce.OldJ, ce.NewJ, ce.Fixed, ce.Broken, left[i], ce.Edge[0], ce.Edge[1])
+	}
+	fixed := nf
+	if len(left) > 0 {
+		fixed -= left[len(left)-1]
+	}
+	fmt.Fprintf(w, "#REM %d / %d = %f\n", fixed, nf, fraction(fixed, nf))
+}
@@ -0,0 +1,99 @@
+/* This file selects a human-scale, representative sample of the cycles to
+output when there are far too many to list.  Cycles are drawn by reservoir
+sampling, stratified by length so that rare lengths are represented alongside
+common ones. */
+
+package frustration
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// A cycleReservoir maintains a uniform sample of at most K cycles of each
+// length from a stream of cycles.
+type cycleReservoir struct {
+	K      int           // Maximum number of cycles to retain per length
+	Seen   map[int]int   // Map from a length to the number of cycles seen
+	Sample map[int][]int // Map from a length to indexes of retained cycles
+	rng    *rand.Rand    // Source of randomness
+}
+
+// newCycleReservoir returns an empty reservoir of k cycles per length.
+func newCycleReservoir(k int, rng *rand.Rand) *cycleReservoir {
+	return &cycleReservoir{
+		K:      k,
+		Seen:   make(map[int]int),
+		Sample: make(map[int][]int),
+		rng:    rng,
+	}
+}
+
+// Add offers the cycle with a given index and length to the reservoir.
+func (r *cycleReservoir) Add(i, n int) {
+	r.Seen[n]++
+	s := r.Sample[n]
+	if len(s) < r.K {
+		r.Sample[n] = append(s, i)
+		return
+	}
+	if j := r.rng.Intn(r.Seen[n]); j < r.K {
+		s[j] = i
+	}
+}
+
+// Select returns, in increasing order, the indexes of at most K cycles drawn
+// from the reservoir.  Each length receives one cycle, in decreasing order
+// of frequency, while the budget lasts, and the remainder of the budget is
+// apportioned among lengths in proportion to their frequency (by the
+// D'Hondt method).
+func (r *cycleReservoir) Select() []int {
+	// Order the lengths from most to least common.
+	ns := make([]int, 0, len(r.Seen))
+	for n := range r.Seen {
+		ns = append(ns, n)
+	}
+	sort.Slice(ns, func(i, j int) bool {
+		if r.Seen[ns[i]] != r.Seen[ns[j]] {
+			return r.Seen[ns[i]] > r.Seen[ns[j]]
+		}
+		return ns[i] < ns[j]
+	})
+
+	// Apportion the budget among the lengths.
+	quota := make(map[int]int, len(ns))
+	budget := r.K
+	for _, n := range ns {
+		if budget == 0 {
+			break
+		}
+		quota[n] = 1
+		budget--
+	}
+	for ; budget > 0; budget-- {
+		best := -1
+		for _, n := range ns {
+			if quota[n] >= len(r.Sample[n]) {
+				continue // Every retained cycle of this length is taken.
+			}
+			if best < 0 || r.Seen[n]*(quota[best]+1) > r.Seen[best]*(quota[n]+1) {
+				best = n
+			}
+		}
+		if best < 0 {
+			break
+		}
+		quota[best]++
+	}
+
+	// Draw each length's quota uniformly from its retained cycles.
+	var sel []int
+	for _, n := range ns {
+		s := r.Sample[n]
+		for _, j := range r.rng.Perm(len(s))[:quota[n]] {
+			sel = append(sel, s[j])
+		}
+	}
+	sort.Ints(sel)
+	return sel
+}
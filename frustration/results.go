@@ -0,0 +1,242 @@
+/* This file gathers the results of a frustration analysis into a structure
+that can be serialized. */
+
+package frustration
+
+import (
+	"flag"
+	"sort"
+)
+
+// A VertexResult tallies the cycles in which a vertex appears.
+type VertexResult struct {
+	Name                string `json:"name"`                  // Vertex name
+	Frustrated          bool   `json:"frustrated"`            // true if more often in frustrated than non-frustrated cycles
+	FrustratedCycles    int    `json:"frustrated_cycles"`     // Number of frustrated cycles containing the vertex
+	NonFrustratedCycles int    `json:"non_frustrated_cycles"` // Number of non-frustrated cycles containing the vertex
+}
+
+// An EdgeResult tallies the cycles in which an edge appears.
+type EdgeResult struct {
+	Vertices            [2]string `json:"vertices"`              // Edge endpoints
+	Frustrated          bool      `json:"frustrated"`            // true if more often in frustrated than non-frustrated cycles
+	FrustratedCycles    int       `json:"frustrated_cycles"`     // Number of frustrated cycles containing the edge
+	NonFrustratedCycles int       `json:"non_frustrated_cycles"` // Number of non-frustrated cycles containing the edge
+}
+
+// A CycleResult says whether a cycle is frustrated.
+type CycleResult struct {
+	Vertices   []string `json:"vertices"`   // Vertices in cycle order
+	Frustrated bool     `json:"frustrated"` // true if the cycle is frustrated
+}
+
+// A VariableResult associates a bqpjson variable ID with its name.
+type VariableResult struct {
+	ID   int    `json:"id"`   // bqpjson variable ID
+	Name string `json:"name"` // Name given by the input's metadata ("" if none)
+}
+
+// A Summary presents aggregate frustration statistics.
+type Summary struct {
+	FrustratedVertices  int     `json:"frustrated_vertices"`  // Number of frustrated vertices
+	TotalVertices       int     `json:"total_vertices"`       // Total number of vertices
+	VertexFraction      float64 `json:"vertex_fraction"`      // Fraction of vertices that are frustrated
+	FrustratedEdges     int     `json:"frustrated_edges"`     // Number of frustrated edges
+	TotalEdges          int     `json:"total_edges"`          // Total number of edges
+	EdgeFraction        float64 `json:"edge_fraction"`        // Fraction of edges that are frustrated
+	FrustratedCycles    int     `json:"frustrated_cycles"`    // Number of frustrated cycles
+	TotalCycles         int     `json:"total_cycles"`         // Total number of cycles analyzed
+	CycleFraction       float64 `json:"cycle_fraction"`       // Fraction of cycles that are frustrated
+	BaseCycles          int     `json:"base_cycles"`          // Number of base cycles
+	ElementaryCycles    int     `json:"elementary_cycles"`    // Number of elementary cycles (0 if not computed)
+	FrustrationPossible bool    `json:"frustration_possible"` // false if the graph is acyclic
+	VertexRule          string  `json:"vertex_rule"`          // Rule by which vertices were deemed frustrated
+	EdgeRule            string  `json:"edge_rule"`            // Rule by which edges were deemed frustrated
+}
+
+// Results represents everything learned from a frustration analysis.
+type Results struct {
+	Vertices  []VertexResult   `json:"vertices"`            // Per-vertex tallies
+	Edges     []EdgeResult     `json:"edges"`               // Per-edge tallies
+	Cycles    []CycleResult    `json:"cycles"`              // Per-cycle frustration
+	Summary   Summary          `json:"summary"`             // Aggregate statistics
+	Variables []VariableResult `json:"variables,omitempty"` // Variable ID-to-name table (bqpjson with var_names only)
+}
+
+// jsonFlags is the set of command-line flags that can accompany
+// --output-format=json because they influence the analysis without adding
+// text reports to the output.
+var jsonFlags = map[string]bool{
+	"all-cycles":          true,
+	"balance-check":       true,
+	"cancel-tolerance":    true,
+	"classifier":          true,
+	"colors":              true,
+	"combine-max-len":     true,
+	"cycle-budget":        true,
+	"cycle-finder":        true,
+	"delimiter":           true,
+	"edge-rule":           true,
+	"embedding":           true,
+	"exact-arithmetic":    true,
+	"fail-on-warning":     true,
+	"f":                   true,
+	"flush-interval":      true,
+	"force":               true,
+	"format":              true,
+	"frustration-def":     true,
+	"frustration-dot":     true,
+	"graphml-field":       true,
+	"graphml-weight":      true,
+	"matrices-out":        true,
+	"max-line-bytes":      true,
+	"max-memory":          true,
+	"max-name-bytes":      true,
+	"o":                   true,
+	"output":              true,
+	"output-buffer":       true,
+	"output-format":       true,
+	"preprocessed-format": true,
+	"preprocessed-out":    true,
+	"preprocessors":       true,
+	"record":              true,
+	"replay":              true,
+	"sapi-h":              true,
+	"save-results":        true,
+	"seed":                true,
+	"shrinking-only":      true,
+	"suppress":            true,
+	"threads":             true,
+	"through-edge":        true,
+	"vertex-names":        true,
+	"vertex-rule":         true,
+}
+
+// CheckJSONFlags aborts if any flag specified on the command line would add
+// a text report to JSON output.
+func CheckJSONFlags() {
+	flag.Visit(func(f *flag.Flag) {
+		if !jsonFlags[f.Name] {
+			Abortf("--output-format=json cannot be combined with --%s, which produces text output", f.Name)
+		}
+	})
+}
+
+// fraction divides two integers, returning 0 when the denominator is 0.
+func fraction(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// AnalyzeGraph analyzes a graph's frustration given its base cycles and the
+// cycles to analyze, each expressed as a list of edges.  allCycs says whether
+// the latter are the graph's elementary cycles rather than its base cycles.
+// rules decides which vertices and edges are frustrated.  Vertices and edges
+// appear in the results in sorted order.
+func AnalyzeGraph(g Graph, bcs, ecs [][][2]string, c Classifier, allCycs bool, rules ClassRules) Results {
+	ps, isFrust := g.classifyCycles(ecs, c)
+	return g.TallyResults(len(bcs), ps, isFrust, allCycs, rules)
+}
+
+// TallyResults gathers the results of a frustration analysis given the
+// number of base cycles and the analyzed cycles, expressed as paths, and
+// whether each is frustrated, deeming vertices and edges frustrated
+// according to the given rules.
+func (g Graph) TallyResults(nBase int, ps [][]string, isFrust []bool, allCycs bool, rules ClassRules) Results {
+	var res Results
+	sum := &res.Summary
+	res.Cycles = make([]CycleResult, len(ps))
+	for i, p := range ps {
+		res.Cycles[i] = CycleResult{Vertices: p, Frustrated: isFrust[i]}
+		if isFrust[i] {
+			sum.FrustratedCycles++
+		}
+	}
+
+	// Tally each vertex.
+	vts, ets := g.tallyMembers(ps, isFrust, rules.Weighted())
+	vNames := g.sortedVertices()
+	res.Vertices = make([]VertexResult, 0, len(vNames))
+	for _, v := range vNames {
+		t := vts[v]
+		if t == nil {
+			continue // Vertex appears in no cycle.
+		}
+		vr := VertexResult{Name: v, Frustrated: t.frustrated(rules.Vertex), FrustratedCycles: t.F, NonFrustratedCycles: t.NF}
+		res.Vertices = append(res.Vertices, vr)
+		if vr.Frustrated {
+			sum.FrustratedVertices++
+		}
+	}
+
+	// Tally each edge.
+	es := g.sortedEdges()
+	res.Edges = make([]EdgeResult, 0, len(es))
+	for _, e := range es {
+		t := ets[e]
+		if t == nil {
+			continue // Edge appears in no cycle.
+		}
+		er := EdgeResult{Vertices: e, Frustrated: t.frustrated(rules.Edge), FrustratedCycles: t.F, NonFrustratedCycles: t.NF}
+		res.Edges = append(res.Edges, er)
+		if er.Frustrated {
+			sum.FrustratedEdges++
+		}
+	}
+
+	// Summarize the results.
+	sum.TotalVertices = len(g.Vs)
+	sum.TotalEdges = len(g.Es)
+	sum.TotalCycles = len(ps)
+	sum.VertexFraction = fraction(sum.FrustratedVertices, sum.TotalVertices)
+	sum.EdgeFraction = fraction(sum.FrustratedEdges, sum.TotalEdges)
+	sum.CycleFraction = fraction(sum.FrustratedCycles, sum.TotalCycles)
+	sum.BaseCycles = nBase
+	if allCycs {
+		sum.ElementaryCycles = len(ps)
+	}
+	sum.FrustrationPossible = nBase > 0
+	sum.VertexRule = rules.Vertex.String()
+	sum.EdgeRule = rules.Edge.String()
+
+	// Tabulate the bqpjson variable names, if any, in order of ID.
+	if g.VarNames != nil {
+		res.Variables = make([]VariableResult, 0, len(g.VarIDs))
+		for _, id := range g.VarIDs {
+			res.Variables = append(res.Variables, VariableResult{ID: id, Name: g.VarNames[id]})
+		}
+		sort.Slice(res.Variables, func(i, j int) bool { return res.Variables[i].ID < res.Variables[j].ID })
+	}
+	return res
+}
+
+// Redacted returns a copy of the results in which every vertex name has been
+// replaced by its alias.  The variable table, which would undo the
+// redaction, is omitted.
+func (res Results) Redacted(alias func(v string) string) Results {
+	red := Results{
+		Vertices: make([]VertexResult, len(res.Vertices)),
+		Edges:    make([]EdgeResult, len(res.Edges)),
+		Cycles:   make([]CycleResult, len(res.Cycles)),
+		Summary:  res.Summary,
+	}
+	for i, vr := range res.Vertices {
+		vr.Name = alias(vr.Name)
+		red.Vertices[i] = vr
+	}
+	for i, er := range res.Edges {
+		er.Vertices = [2]string{alias(er.Vertices[0]), alias(er.Vertices[1])}
+		red.Edges[i] = er
+	}
+	for i, cr := range res.Cycles {
+		vs := make([]string, len(cr.Vertices))
+		for j, v := range cr.Vertices {
+			vs[j] = alias(v)
+		}
+		cr.Vertices = vs
+		red.Cycles[i] = cr
+	}
+	return red
+}
@@ -0,0 +1,329 @@
+/* This file estimates frustration statistics from a random sample of cycles
+rather than from a complete cycle basis. */
+
+package frustration
+
+import (
+	"math"
+	"math/bits"
+	"math/rand"
+	"sort"
+)
+
+// A cycleSample is one randomly sampled cycle.
+type cycleSample struct {
+	Edge       int     // Index of the edge through which the cycle was sampled
+	Path       []int   // Vertices in cycle order
+	Frustrated bool    // true if the cycle is frustrated
+	Q          float64 // Probability with which Edge was proposed
+	AbsJ       float64 // Magnitude of Edge's coupler strength
+}
+
+// bridges says, for each edge, whether removing it disconnects its endpoints
+// (i.e., it lies on no cycle).  It uses an iterative version of Tarjan's
+// bridge-finding algorithm.
+func (sg signedGraph) bridges() []bool {
+	// Map each edge to its index.
+	eIdx := make(map[[2]int]int, len(sg.Edges))
+	for i, e := range sg.Edges {
+		eIdx[e] = i
+		eIdx[[2]int{e[1], e[0]}] = i
+	}
+
+	// Perform a depth-first search from each unvisited vertex, tracking
+	// discovery times and the earliest time reachable from each subtree.
+	nv := len(sg.Names)
+	disc := make([]int, nv) // 0 means "not yet visited".
+	low := make([]int, nv)
+	isBridge := make([]bool, len(sg.Edges))
+	type frame struct {
+		V, ParentEdge, Next int
+	}
+	t := 0
+	for root := 0; root < nv; root++ {
+		if disc[root] != 0 {
+			continue
+		}
+		t++
+		disc[root], low[root] = t, t
+		stack := []frame{{V: root, ParentEdge: -1}}
+		for len(stack) > 0 {
+			f := &stack[len(stack)-1]
+			if f.Next < len(sg.Adj[f.V]) {
+				// Visit the next neighbor.
+				to := sg.Adj[f.V][f.Next].To
+				f.Next++
+				ei := eIdx[[2]int{f.V, to}]
+				if ei == f.ParentEdge {
+					continue
+				}
+				if disc[to] == 0 {
+					t++
+					disc[to], low[to] = t, t
+					stack = append(stack, frame{V: to, ParentEdge: ei})
+				} else if disc[to] < low[f.V] {
+					low[f.V] = disc[to]
+				}
+				continue
+			}
+
+			// Finish the vertex and propagate its low time upward.
+			stack = stack[:len(stack)-1]
+			if f.ParentEdge >= 0 {
+				p := &stack[len(stack)-1]
+				if low[f.V] < low[p.V] {
+					low[p.V] = low[f.V]
+				}
+				if low[f.V] > disc[p.V] {
+					isBridge[f.ParentEdge] = true
+				}
+			}
+		}
+	}
+	return isBridge
+}
+
+// randomShortestCycle returns a cycle through edge ei formed by the edge
+// itself and a shortest path between its endpoints that avoids the edge,
+// chosen uniformly at random among all such shortest paths.  It returns nil
+// if the edge is a bridge.
+func (sg signedGraph) randomShortestCycle(ei int, rng *rand.Rand) []int {
+	// Perform a breadth-first search from v, excluding edge uv, counting
+	// the shortest paths to each vertex.
+	u, v := sg.Edges[ei][0], sg.Edges[ei][1]
+	nv := len(sg.Names)
+	dist := make([]int, nv)
+	for i := range dist {
+		dist[i] = -1
+	}
+	count := make([]float64, nv)
+	dist[v], count[v] = 0, 1
+	queue := []int{v}
+	for len(queue) > 0 && dist[u] < 0 {
+		x := queue[0]
+		queue = queue[1:]
+		for _, a := range sg.Adj[x] {
+			if (x == u && a.To == v) || (x == v && a.To == u) {
+				continue
+			}
+			switch {
+			case dist[a.To] < 0:
+				dist[a.To] = dist[x] + 1
+				count[a.To] = count[x]
+				queue = append(queue, a.To)
+			case dist[a.To] == dist[x]+1:
+				count[a.To] += count[x]
+			}
+		}
+	}
+	if dist[u] < 0 {
+		return nil
+	}
+
+	// Walk back from u to v, choosing each predecessor with probability
+	// proportional to its number of shortest paths.
+	path := []int{u}
+	for x := u; x != v; {
+		var preds []int
+		total := 0.0
+		for _, a := range sg.Adj[x] {
+			if (x == u && a.To == v) || dist[a.To] != dist[x]-1 {
+				continue
+			}
+			preds = append(preds, a.To)
+			total += count[a.To]
+		}
+		r := rng.Float64() * total
+		next := preds[len(preds)-1]
+		for _, p := range preds {
+			if r < count[p] {
+				next = p
+				break
+			}
+			r -= count[p]
+		}
+		path = append(path, next)
+		x = next
+	}
+	return path
+}
+
+// isFrustratedPath says whether a cycle, expressed as vertex indices, has an
+// odd number of antiferromagnetic couplings.
+func (sg signedGraph) isFrustratedPath(p []int, sign map[[2]int]int) bool {
+	prod := 1
+	for i, x := range p {
+		y := p[(i+1)%len(p)]
+		if x > y {
+			x, y = y, x
+		}
+		prod *= sign[[2]int{x, y}]
+	}
+	return prod < 0
+}
+
+// sampleCycles samples n cycles, each rooted at an edge and formed from a
+// uniformly random shortest path closing that edge.  Only edges that lie on
+// a cycle and have nonzero strength are eligible as roots.  With weighting
+// "uniform", every eligible edge is equally likely to be proposed; with
+// weighting "abs-j", edges are proposed with probability proportional to
+// |J|.  It returns the samples, the number of eligible edges, and their total
+// |J|.
+func (g Graph) sampleCycles(n int, weighting string, rng *rand.Rand) ([]cycleSample, int, float64) {
+	// Determine the eligible edges and their proposal probabilities.
+	sg := g.signedGraph()
+	isBridge := sg.bridges()
+	sign := make(map[[2]int]int, len(sg.Edges))
+	var elig []int        // Indices of eligible edges
+	var wts, js []float64 // Proposal weight and |J| of each eligible edge
+	total, sumJ := 0.0, 0.0
+	for i, e := range sg.Edges {
+		sign[e] = sg.Signs[i]
+		j := math.Abs(sg.Weights[i])
+		if isBridge[i] || j == 0 {
+			continue
+		}
+		wt := 1.0
+		switch weighting {
+		case "uniform":
+		case "abs-j":
+			wt = j
+		default:
+			Abortf("Unrecognized sample weighting %q", weighting)
+		}
+		elig = append(elig, i)
+		wts = append(wts, wt)
+		js = append(js, j)
+		total += wt
+		sumJ += j
+	}
+	if len(elig) == 0 {
+		return nil, 0, 0
+	}
+	cum := make([]float64, len(wts))
+	acc := 0.0
+	for i, wt := range wts {
+		acc += wt
+		cum[i] = acc
+	}
+
+	// Sample each cycle.
+	samples := make([]cycleSample, n)
+	for s := range samples {
+		k := sort.SearchFloat64s(cum, rng.Float64()*total)
+		if k >= len(elig) {
+			k = len(elig) - 1
+		}
+		p := sg.randomShortestCycle(elig[k], rng)
+		samples[s] = cycleSample{
+			Edge:       elig[k],
+			Path:       p,
+			Frustrated: sg.isFrustratedPath(p, sign),
+			Q:          wts[k] / total,
+			AbsJ:       js[k],
+		}
+	}
+	return samples, len(elig), sumJ
+}
+
+// cycleSpaceAttempts is the number of random cycle-space elements that
+// sampleCycleSpace may draw per cycle found before giving up.
+const cycleSpaceAttempts = 100000
+
+// sampleCycleSpace samples n elementary cycles uniformly at random and says
+// whether each is frustrated.  Each attempt draws a uniformly random element
+// of the cycle space, the sum of a random subset of the base cycles, and
+// keeps it only if it is a single cycle; every elementary cycle is one such
+// element, so the kept cycles are uniformly distributed.  It also returns the
+// dimension of the cycle space.
+func (g Graph) sampleCycleSpace(n int, rng *rand.Rand) ([]bool, int) {
+	// Represent each base cycle as a bit set of edge indices.
+	sg := g.signedGraph()
+	eIdx := make(map[[2]int]int, 2*len(sg.Edges))
+	for i, e := range sg.Edges {
+		eIdx[e] = i
+		eIdx[[2]int{e[1], e[0]}] = i
+	}
+	bcs := sg.baseCycles(rng)
+	if len(bcs) == 0 {
+		return nil, 0
+	}
+	words := (len(sg.Edges) + 63) / 64
+	basis := make([][]uint64, len(bcs))
+	for i, c := range bcs {
+		basis[i] = make([]uint64, words)
+		for j, u := range c {
+			k := eIdx[[2]int{u, c[(j+1)%len(c)]}]
+			basis[i][k/64] |= 1 << uint(k%64)
+		}
+	}
+
+	// Draw random cycle-space elements until enough are single cycles.
+	elt := make([]uint64, words)
+	nbrs := make([][2]int, len(sg.Names)) // Neighbors of each vertex in elt
+	deg := make([]int, len(sg.Names))     // Degree of each vertex in elt
+	var touched []int                     // Vertices of nonzero degree
+	frust := make([]bool, 0, n)
+	for tries := 1; len(frust) < n; tries++ {
+		if tries > cycleSpaceAttempts*(len(frust)+1) {
+			Abortf("Fewer than 1 in %d random elements of the cycle space is a single cycle; sample with --sample-weighting=uniform instead", cycleSpaceAttempts)
+		}
+		for k := range elt {
+			elt[k] = 0
+		}
+		for _, b := range basis {
+			if rng.Intn(2) == 1 {
+				for k := range elt {
+					elt[k] ^= b[k]
+				}
+			}
+		}
+
+		// Accept the element if every vertex has degree 0 or 2 and
+		// the edges form a single closed walk.
+		for _, v := range touched {
+			deg[v] = 0
+		}
+		touched = touched[:0]
+		ok, ne, neg := true, 0, 0
+		for k, word := range elt {
+			for ; word != 0 && ok; word &= word - 1 {
+				i := k*64 + bits.TrailingZeros64(word)
+				ne++
+				if sg.Signs[i] < 0 {
+					neg++
+				}
+				for j, v := range sg.Edges[i] {
+					if deg[v] == 0 {
+						touched = append(touched, v)
+					}
+					if deg[v] == 2 {
+						ok = false
+						break
+					}
+					nbrs[v][deg[v]] = sg.Edges[i][1-j]
+					deg[v]++
+				}
+			}
+		}
+		if !ok || ne == 0 {
+			continue
+		}
+		steps := 0
+		for prev, v := -1, touched[0]; ; steps++ {
+			next := nbrs[v][0]
+			if next == prev {
+				next = nbrs[v][1]
+			}
+			prev, v = v, next
+			if v == touched[0] {
+				steps++
+				break
+			}
+		}
+		if steps == ne {
+			frust = append(frust, neg%2 == 1)
+		}
+	}
+	return frust, len(basis)
+}
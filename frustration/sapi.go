@@ -0,0 +1,130 @@
+/* This file reads Ising problems dumped by legacy SAPI clients, which stored
+each problem as two files: a vector h of external fields indexed by qubit and
+a dictionary J of coupler strengths keyed by pairs of qubits.  Both are
+typically written as Python literals ("[0.0, 0.5, ...]" and "{(0, 4): -1.0,
+...}"), but one "i h" or "i j J" entry per line is accepted as well. */
+
+package frustration
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// sapiPunctuation replaces the punctuation of Python list, tuple, and
+// dictionary literals with spaces.
+var sapiPunctuation = strings.NewReplacer(
+	"[", " ", "]", " ",
+	"(", " ", ")", " ",
+	"{", " ", "}", " ",
+	":", " ", ",", " ")
+
+// readSAPIText reads an entire SAPI dump.
+func readSAPIText(r io.Reader) string {
+	buf, err := ioutil.ReadAll(r)
+	CheckError(err)
+	return string(buf)
+}
+
+// sapiQubit parses a qubit index, returning it in canonical form.
+func sapiQubit(s string) string {
+	q, err := strconv.Atoi(s)
+	if err != nil || q < 0 {
+		Abortf("Invalid SAPI qubit index %q", s)
+	}
+	return strconv.Itoa(q)
+}
+
+// ReadSAPIFields reads a SAPI h vector and returns a map from each qubit to
+// its field.  The vector may be a Python list whose ith element is the field
+// on qubit i, a Python dictionary that maps qubits to fields, one field per
+// line, or one "i h" pair per line.
+func ReadSAPIFields(r io.Reader) map[string]float64 {
+	s := readSAPIText(r)
+	toks := strings.Fields(sapiPunctuation.Replace(s))
+	hs := make(map[string]float64, len(toks))
+	paired := strings.Contains(s, ":")
+	if !paired && !strings.Contains(s, "[") {
+		// Decide between one field and one pair per line by the
+		// first line.
+		for _, ln := range strings.Split(s, "\n") {
+			if fs := strings.Fields(ln); len(fs) > 0 {
+				paired = len(fs) == 2
+				break
+			}
+		}
+	}
+	if !paired {
+		for q, t := range toks {
+			h, err := strconv.ParseFloat(t, 64)
+			CheckError(err)
+			hs[strconv.Itoa(q)] = h
+		}
+		return hs
+	}
+	if len(toks)%2 != 0 {
+		Abortf("SAPI h vector has a qubit without a field")
+	}
+	for i := 0; i < len(toks); i += 2 {
+		h, err := strconv.ParseFloat(toks[i+1], 64)
+		CheckError(err)
+		hs[sapiQubit(toks[i])] += h
+	}
+	return hs
+}
+
+// ReadSAPICouplers reads a SAPI J dictionary (or list of "i j J" lines) and
+// returns a map from each pair of qubits to its coupler strength and a map
+// from each pair to the total magnitude of the entries summed into it.
+// Couplers listed in both orders are summed.
+func ReadSAPICouplers(r io.Reader) (map[[2]string]float64, map[[2]string]float64) {
+	toks := strings.Fields(sapiPunctuation.Replace(readSAPIText(r)))
+	if len(toks)%3 != 0 {
+		Abortf("SAPI J dictionary does not consist of (i, j): J entries")
+	}
+	js := make(map[[2]string]float64, len(toks)/3)
+	mags := make(map[[2]string]float64, len(toks)/3)
+	for i := 0; i < len(toks); i += 3 {
+		u, v := sapiQubit(toks[i]), sapiQubit(toks[i+1])
+		if u == v {
+			Abortf("SAPI J dictionary couples qubit %s to itself", u)
+		}
+		j, err := strconv.ParseFloat(toks[i+2], 64)
+		CheckError(err)
+		if u > v {
+			u, v = v, u
+		}
+		js[[2]string{u, v}] += j
+		mags[[2]string{u, v}] += math.Abs(j)
+	}
+	return js, mags
+}
+
+// A SAPIParser reads a legacy SAPI problem: a J dictionary from the input
+// and, if Fields is non-nil, an h vector from Fields.  Qubits with neither a
+// field nor a coupler, which fill out the h vectors of sparse problems, are
+// omitted.
+type SAPIParser struct {
+	Fields io.Reader // Source of the h vector (nil: no fields)
+}
+
+// Parse reads a legacy SAPI problem.
+func (sp SAPIParser) Parse(r io.Reader) Graph {
+	es, mags := ReadSAPICouplers(r)
+	vs := make(map[string]float64)
+	for e := range es {
+		vs[e[0]] += 0.0
+		vs[e[1]] += 0.0
+	}
+	if sp.Fields != nil {
+		for q, h := range ReadSAPIFields(sp.Fields) {
+			if _, ok := vs[q]; ok || h != 0 {
+				vs[q] += h
+			}
+		}
+	}
+	return Graph{Vs: vs, Es: es, EMags: mags}
+}
@@ -0,0 +1,98 @@
+/* This file condenses a frustration analysis into a single score. */
+
+package frustration
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// scoreRestarts is the number of random switching restarts used to bound the
+// frustration index when computing a score.
+const scoreRestarts = 10
+
+// A Score is a single number in [0, 1] summarizing how frustrated a graph is,
+// along with the components from which it was computed.
+type Score struct {
+	Score            float64    `json:"score"`             // Weighted mean of the three components
+	CycleFraction    float64    `json:"cycle_fraction"`    // Fraction of base cycles that are frustrated
+	WeightedFraction float64    `json:"weighted_fraction"` // Fraction of cycle energy gaps belonging to frustrated cycles
+	IndexFraction    float64    `json:"index_fraction"`    // Frustration-index bound relative to its maximum of half the edges
+	Weights          [3]float64 `json:"weights"`           // Weights applied to the three components
+}
+
+// ParseScoreWeights parses a comma-separated list of three non-negative
+// weights.
+func ParseScoreWeights(s string) [3]float64 {
+	var wts [3]float64
+	fs := strings.Split(s, ",")
+	if len(fs) != 3 {
+		Abortf("Expected three comma-separated score weights but saw %q", s)
+	}
+	sum := 0.0
+	for i, f := range fs {
+		wt, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		CheckError(err)
+		if wt < 0 {
+			Abortf("Score weights must be non-negative")
+		}
+		wts[i] = wt
+		sum += wt
+	}
+	if sum == 0 {
+		Abortf("At least one score weight must be positive")
+	}
+	return wts
+}
+
+// ComputeScore computes a graph's frustration score as a weighted mean of
+// (1) the fraction of base cycles that are frustrated, (2) the fraction of
+// the total energy gap (twice the weakest coupler magnitude) across all base
+// cycles that is contributed by frustrated cycles, and (3) an upper bound on
+// the frustration index divided by half the number of edges, the
+// frustration index's maximum possible value.  Cycles are deemed frustrated
+// by a given Classifier.
+func ComputeScore(g Graph, wts [3]float64, c Classifier, rng *rand.Rand) Score {
+	sc := Score{Weights: wts}
+	_, cs, _ := g.findCycles(false, rng)
+	if len(cs) == 0 {
+		return sc // An acyclic graph cannot be frustrated.
+	}
+
+	// Compute the frustrated fraction of cycles, both unweighted and
+	// weighted by energy gap.
+	ps, isFrust := g.classifyCycles(cs, c)
+	nfcs := 0
+	fGap, allGap := 0.0, 0.0
+	for i, p := range ps {
+		minJ := math.Inf(1)
+		for _, e := range g.pathToEdges(p) {
+			minJ = math.Min(minJ, math.Abs(g.Es[e]))
+		}
+		allGap += 2 * minJ
+		if isFrust[i] {
+			nfcs++
+			fGap += 2 * minJ
+		}
+	}
+	sc.CycleFraction = fraction(nfcs, len(ps))
+	if allGap > 0 {
+		sc.WeightedFraction = fGap / allGap
+	}
+
+	// Bound the frustration index.
+	neg, _ := g.signedGraph().frustrationIndexHeuristic(scoreRestarts, rng)
+	sc.IndexFraction = math.Min(2*float64(neg)/float64(len(g.Es)), 1)
+
+	// Combine the components.
+	sc.Score = (wts[0]*sc.CycleFraction + wts[1]*sc.WeightedFraction + wts[2]*sc.IndexFraction) / (wts[0] + wts[1] + wts[2])
+	return sc
+}
+
+// String formats a score as a pair of tagged lines.
+func (sc Score) String() string {
+	return fmt.Sprintf("#SCORE %f\n#SCOREC %f %f %f\n", sc.Score, sc.CycleFraction, sc.WeightedFraction, sc.IndexFraction)
+}
@@ -0,0 +1,151 @@
+/* This file implements find-frustration's server mode, which exposes its
+analyses over HTTP. */
+
+package frustration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// A Server answers HTTP requests for frustration analyses.
+type Server struct {
+	Weights  [3]float64  // Default score weights
+	Jobs     *JobQueue   // Queue of asynchronous analyses (nil if disabled)
+	Redact   bool        // Hide vertex names from callers other than a job's owner
+	Seed     int64       // Seed for each request's pseudorandom choices (0 for the clock)
+	Limits   InputLimits // Bounds on what a request's input may contain
+	MaxInput int64       // Maximum size in bytes of a request body (0: unlimited)
+}
+
+// A limitedBody reads a request body of bounded size and records whether the
+// bound was exceeded.
+type limitedBody struct {
+	r        io.Reader // Underlying http.MaxBytesReader
+	tooLarge bool      // true if a read was refused for exceeding the bound
+}
+
+// Read reads from the underlying body, noting whether the read failed because
+// the body was too large.
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	n, err := lb.r.Read(p)
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		lb.tooLarge = true
+	}
+	return n, err
+}
+
+// limitBody wraps a request's body so that reading more than s.MaxInput bytes
+// fails.  A positive max imposes a further, smaller bound.
+func (s *Server) limitBody(w http.ResponseWriter, r *http.Request, max int64) *limitedBody {
+	if s.MaxInput > 0 && (max <= 0 || s.MaxInput < max) {
+		max = s.MaxInput
+	}
+	if max <= 0 {
+		return &limitedBody{r: r.Body}
+	}
+	return &limitedBody{r: http.MaxBytesReader(w, r.Body, max)}
+}
+
+// status returns the HTTP status with which to report an error that arose
+// while reading a request body: 413 if the body was too large and 400
+// otherwise.
+func (lb *limitedBody) status() int {
+	if lb.tooLarge {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// newRand returns a pseudorandom number generator for a single request.
+func newRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// bearerToken returns the access token presented in a request's
+// Authorization header or "" if none was presented.
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return ""
+	}
+	return strings.TrimSpace(auth[len(prefix):])
+}
+
+// writeJSON writes a value as the JSON body of an HTTP response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError writes an error message as a JSON HTTP response.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+// handleScore computes the score of a problem provided in the request body.
+// The "format" query parameter names the input format (default: "qubist"),
+// and the "weights" query parameter optionally overrides the default score
+// weights.
+func (s *Server) handleScore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s requires POST", r.URL.Path))
+		return
+	}
+	var sc Score
+	body := s.limitBody(w, r, 0)
+	err := func() (err error) {
+		defer RecoverFatal(&err)
+		q := r.URL.Query()
+		inFmt := q.Get("format")
+		if inFmt == "" {
+			inFmt = "qubist"
+		}
+		checkUntrustedFormat(inFmt)
+		wts := s.Weights
+		if q.Get("weights") != "" {
+			wts = ParseScoreWeights(q.Get("weights"))
+		}
+		g := ReadGraph(inFmt, body, s.Limits)
+		sc = ComputeScore(g, wts, signParity, newRand(s.Seed))
+		return nil
+	}()
+	if err != nil {
+		writeError(w, body.status(), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sc)
+}
+
+// Handler returns an http.Handler that serves all of the server's endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/score", s.handleScore)
+	if s.Jobs != nil {
+		mux.HandleFunc("/jobs", s.handleSubmitJob)
+		mux.HandleFunc("/jobs/", s.handleGetJob)
+	}
+	return mux
+}
+
+// Serve listens for and responds to HTTP requests on the given address.
+func (s *Server) Serve(addr string) {
+	Notify.Printf("Listening on %s", addr)
+	CheckError(http.ListenAndServe(addr, s.Handler()))
+}
@@ -0,0 +1,276 @@
+/* This file divides an analysis among independent jobs, such as the tasks of
+a cluster job array, and merges their partial results.  Each cycle is
+assigned to a shard by a hash of its edges, so every job agrees on the
+partition without communicating. */
+
+package frustration
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A ShardSpec identifies one of Count equal parts of a workload.
+type ShardSpec struct {
+	Index int `json:"index"` // Shard number, from 0 to Count-1
+	Count int `json:"count"` // Total number of shards
+}
+
+// ParseShard parses a shard specification of the form "i/N".
+func ParseShard(s string) ShardSpec {
+	f := strings.Split(s, "/")
+	if len(f) == 2 {
+		i, err1 := strconv.Atoi(strings.TrimSpace(f[0]))
+		n, err2 := strconv.Atoi(strings.TrimSpace(f[1]))
+		if err1 == nil && err2 == nil && n > 0 && i >= 0 && i < n {
+			return ShardSpec{Index: i, Count: n}
+		}
+	}
+	Abortf("Invalid shard %q; expected i/N with 0 <= i < N", s)
+	return ShardSpec{}
+}
+
+// String formats a shard specification as "i/N".
+func (sh ShardSpec) String() string {
+	return fmt.Sprintf("%d/%d", sh.Index, sh.Count)
+}
+
+// owns says whether a cycle, expressed as a list of edges, belongs to the
+// shard.  The decision depends only on the cycle's set of edges, not on the
+// order or orientation in which they are listed.
+func (sh ShardSpec) owns(c [][2]string) bool {
+	keys := make([]string, len(c))
+	for i, e := range c {
+		if e[0] > e[1] {
+			e[0], e[1] = e[1], e[0]
+		}
+		keys[i] = e[0] + "\x00" + e[1]
+	}
+	sort.Strings(keys)
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\x00\x00")))
+	return binary.BigEndian.Uint64(sum[:8])%uint64(sh.Count) == uint64(sh.Index)
+}
+
+// FilterCycles returns the cycles that belong to the shard.
+func (sh ShardSpec) FilterCycles(cs [][][2]string) [][][2]string {
+	var mine [][][2]string
+	for _, c := range cs {
+		if sh.owns(c) {
+			mine = append(mine, c)
+		}
+	}
+	return mine
+}
+
+// TallyShard finds an analysis's cycles, then classifies and tallies only
+// those that belong to a shard.
+func (pl Pipeline) TallyShard(a *Analysis, sh ShardSpec, allCycs bool, trivRatio float64, exclTriv bool) Results {
+	pl.FindCycles(a)
+	a.Cycles = sh.FilterCycles(a.Cycles)
+	pl.Classify(a)
+	if trivRatio > 0 {
+		a.Timer.Time("classify", func() { a.FindTrivial(trivRatio, exclTriv) })
+	}
+	return a.Graph.TallyResults(len(a.BaseCycles), a.Paths, a.Frustrated, allCycs, ClassRules{})
+}
+
+// SampleShare returns the number of an n-cycle sample that the shard draws
+// and a generator, independent of every other shard's, from which to draw
+// them.
+func (sh ShardSpec) SampleShare(n int, seed int64) (int, *rand.Rand) {
+	m := n / sh.Count
+	if sh.Index < n%sh.Count {
+		m++
+	}
+	return m, rand.New(rand.NewSource(seed + int64(sh.Index)))
+}
+
+// A ShardFile holds one shard's partial results: either tallies over the
+// shard's cycles or a tally of the shard's share of a cycle sample.
+type ShardFile struct {
+	Shard      ShardSpec    `json:"shard"`             // Which shard produced the file
+	Provenance Provenance   `json:"provenance"`        // Provenance of the shard's run
+	Results    *Results     `json:"results,omitempty"` // Tallies over the shard's cycles
+	Sample     *sampleTally `json:"sample,omitempty"`  // Tally of the shard's sampled cycles
+}
+
+// Write writes a shard file in JSON format.
+func (sf ShardFile) Write(w io.Writer) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	CheckError(enc.Encode(sf))
+}
+
+// ReadShardFile reads a shard file written by ShardFile.Write.
+func ReadShardFile(r io.Reader) ShardFile {
+	var sf ShardFile
+	CheckError(json.NewDecoder(r).Decode(&sf))
+	if sf.Shard.Count <= 0 || (sf.Results == nil) == (sf.Sample == nil) {
+		Abortf("Input is not a find-frustration shard file")
+	}
+	return sf
+}
+
+// checkShards aborts unless a set of shard files constitutes exactly one
+// shard of each number from a single sharded run, and sorts them by shard
+// number.
+func checkShards(sfs []ShardFile) {
+	if len(sfs) == 0 {
+		Abortf("No shard files were specified")
+	}
+	sort.Slice(sfs, func(i, j int) bool { return sfs[i].Shard.Index < sfs[j].Shard.Index })
+	first := sfs[0]
+	for i, sf := range sfs {
+		switch {
+		case sf.Shard.Count != first.Shard.Count:
+			Abortf("Shard %s and shard %s come from runs with different shard counts", first.Shard, sf.Shard)
+		case sf.Provenance.InputSHA256 != first.Provenance.InputSHA256:
+			Abortf("Shard %s and shard %s were computed from different inputs", first.Shard, sf.Shard)
+		case sf.Provenance.Seed != first.Provenance.Seed:
+			Abortf("Shard %s and shard %s were computed with different seeds", first.Shard, sf.Shard)
+		case (sf.Sample == nil) != (first.Sample == nil):
+			Abortf("Shard %s and shard %s mix sampled and exhaustive analyses", first.Shard, sf.Shard)
+		case sf.Shard.Index != i:
+			if i > 0 && sf.Shard.Index == sfs[i-1].Shard.Index {
+				Abortf("Shard %s was specified more than once", sf.Shard)
+			}
+			Abortf("Shard %d/%d is missing", i, first.Shard.Count)
+		}
+	}
+	if len(sfs) != first.Shard.Count {
+		Abortf("Shard %d/%d is missing", len(sfs), first.Shard.Count)
+	}
+}
+
+// mergeResults combines the results of disjoint sets of cycles from the same
+// graph into the results of their union.
+func mergeResults(parts []Results, rules ClassRules) Results {
+	// Sum the per-vertex and per-edge tallies.
+	type counts struct{ F, NF int }
+	vTally := make(map[string]counts)
+	eTally := make(map[[2]string]counts)
+	var res Results
+	for _, p := range parts {
+		for _, vr := range p.Vertices {
+			c := vTally[vr.Name]
+			vTally[vr.Name] = counts{c.F + vr.FrustratedCycles, c.NF + vr.NonFrustratedCycles}
+		}
+		for _, er := range p.Edges {
+			c := eTally[er.Vertices]
+			eTally[er.Vertices] = counts{c.F + er.FrustratedCycles, c.NF + er.NonFrustratedCycles}
+		}
+		res.Cycles = append(res.Cycles, p.Cycles...)
+	}
+
+	// Reassemble the per-vertex and per-edge results in sorted order.
+	sum := &res.Summary
+	vNames := make([]string, 0, len(vTally))
+	for v := range vTally {
+		vNames = append(vNames, v)
+	}
+	sort.Strings(vNames)
+	res.Vertices = make([]VertexResult, len(vNames))
+	for i, v := range vNames {
+		c := vTally[v]
+		f := rules.Vertex.frustrated(c.F, c.NF, 0, 0)
+		res.Vertices[i] = VertexResult{Name: v, Frustrated: f, FrustratedCycles: c.F, NonFrustratedCycles: c.NF}
+		if f {
+			sum.FrustratedVertices++
+		}
+	}
+	es := make([][2]string, 0, len(eTally))
+	for e := range eTally {
+		es = append(es, e)
+	}
+	sort.Slice(es, func(i, j int) bool {
+		if es[i][0] != es[j][0] {
+			return es[i][0] < es[j][0]
+		}
+		return es[i][1] < es[j][1]
+	})
+	res.Edges = make([]EdgeResult, len(es))
+	for i, e := range es {
+		c := eTally[e]
+		f := rules.Edge.frustrated(c.F, c.NF, 0, 0)
+		res.Edges[i] = EdgeResult{Vertices: e, Frustrated: f, FrustratedCycles: c.F, NonFrustratedCycles: c.NF}
+		if f {
+			sum.FrustratedEdges++
+		}
+	}
+
+	// Combine the summaries.  Properties of the graph as a whole are the
+	// same in every part.
+	first := parts[0].Summary
+	sum.TotalVertices = first.TotalVertices
+	sum.TotalEdges = first.TotalEdges
+	sum.BaseCycles = first.BaseCycles
+	sum.FrustrationPossible = first.FrustrationPossible
+	sum.VertexRule = rules.Vertex.String()
+	sum.EdgeRule = rules.Edge.String()
+	for _, p := range parts {
+		sum.FrustratedCycles += p.Summary.FrustratedCycles
+		sum.TotalCycles += p.Summary.TotalCycles
+		sum.ElementaryCycles += p.Summary.ElementaryCycles
+	}
+	sum.VertexFraction = fraction(sum.FrustratedVertices, sum.TotalVertices)
+	sum.EdgeFraction = fraction(sum.FrustratedEdges, sum.TotalEdges)
+	sum.CycleFraction = fraction(sum.FrustratedCycles, sum.TotalCycles)
+	res.Variables = parts[0].Variables
+	return res
+}
+
+// mergeSamples combines the tallies of independent samples of the same
+// graph.
+func mergeSamples(parts []sampleTally) sampleTally {
+	t := sampleTally{Weighting: parts[0].Weighting, Eligible: parts[0].Eligible}
+	for _, p := range parts {
+		if p.Weighting != t.Weighting {
+			Abortf("Cannot merge samples drawn with %q and %q weighting", t.Weighting, p.Weighting)
+		}
+		t.N += p.N
+		t.SumW += p.SumW
+		t.SumW2 += p.SumW2
+		t.SumF += p.SumF
+		t.SumF2 += p.SumF2
+		t.SumAbsJ += p.SumAbsJ
+		t.SumAbsJ2 += p.SumAbsJ2
+	}
+	return t
+}
+
+// OutputMergedShards merges the partial results of every shard of a run.
+// Tallies over cycles are output as JSON results accompanied by each shard's
+// provenance.  Sample tallies are output in the same form as an unsharded
+// sample, preceded by a line identifying each shard.
+func OutputMergedShards(w io.Writer, sfs []ShardFile, rules ClassRules) {
+	checkShards(sfs)
+	if sfs[0].Sample != nil {
+		parts := make([]sampleTally, len(sfs))
+		for i, sf := range sfs {
+			parts[i] = *sf.Sample
+			p := sf.Provenance
+			fmt.Fprintf(w, "#SHARD %s %s %d %s %s\n", sf.Shard, p.InputSHA256, p.Seed, p.Hostname, p.Timestamp)
+		}
+		outputSampleTally(w, mergeSamples(parts))
+		return
+	}
+	parts := make([]Results, len(sfs))
+	provs := make([]Provenance, len(sfs))
+	for i, sf := range sfs {
+		parts[i] = *sf.Results
+		provs[i] = sf.Provenance
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	CheckError(enc.Encode(struct {
+		Results Results      `json:"results"`
+		Shards  []Provenance `json:"shards"`
+	}{mergeResults(parts, rules), provs}))
+}
@@ -0,0 +1,55 @@
+/* This file computes a temperature-dependent, "soft" measure of frustration
+in which each cycle's sign product is replaced by a product of thermal edge
+correlations. */
+
+package frustration
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseBetas parses a comma-separated list of positive inverse temperatures.
+func ParseBetas(s string) []float64 {
+	fs := strings.Split(s, ",")
+	betas := make([]float64, len(fs))
+	for i, f := range fs {
+		b, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		CheckError(err)
+		if b <= 0 {
+			Abortf("Inverse temperatures must be positive but saw %v", b)
+		}
+		betas[i] = b
+	}
+	return betas
+}
+
+// softEdgeWeights maps each edge to its signed thermal correlation at
+// inverse temperature beta: tanh(beta*|J|) with the sign returned by
+// couplingSign.  As beta grows, every weight approaches +1 or -1; as beta
+// shrinks, every weight approaches 0.
+func (g Graph) softEdgeWeights(beta float64) map[[2]string]float64 {
+	ws := make(map[[2]string]float64, len(g.Es))
+	for e, j := range g.Es {
+		s, _ := g.couplingSign(e[0], e[1])
+		ws[e] = float64(s) * math.Tanh(beta*math.Abs(j))
+	}
+	return ws
+}
+
+// softCycleProducts returns the product of soft edge weights around each
+// cycle.  A negative product indicates a frustrated cycle, and its magnitude
+// indicates how strongly the frustration persists at the given temperature.
+func (g Graph) softCycleProducts(ecs [][][2]string, beta float64) []float64 {
+	ws := g.softEdgeWeights(beta)
+	prods := make([]float64, len(ecs))
+	for i, ec := range ecs {
+		p := 1.0
+		for _, e := range ec {
+			p *= ws[e]
+		}
+		prods[i] = p
+	}
+	return prods
+}
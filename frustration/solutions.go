@@ -0,0 +1,271 @@
+/* This file provides functions for reading candidate solutions (spin
+assignments) in the formats produced by various solvers. */
+
+package frustration
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A Solution is a labeled assignment of spins to variables.
+type Solution struct {
+	Label string         // Name by which to refer to the solution
+	Spins map[string]int // Map from a variable name to +1 or -1
+}
+
+// spinValue maps a spin (-1/+1) or Boolean (0/1) value to a spin.  Boolean
+// values map to spins as in quboToIsing: 0 to -1 and 1 to +1.
+func spinValue(x float64) int {
+	switch x {
+	case -1, 0:
+		return -1
+	case 1:
+		return 1
+	default:
+		Abortf("Solution value %v is neither a spin nor a Boolean", x)
+	}
+	return 0
+}
+
+// parseSpin parses a textual spin or Boolean value.
+func parseSpin(s string) int {
+	switch strings.ToLower(s) {
+	case "true", "t":
+		return 1
+	case "false", "f":
+		return -1
+	}
+	x, err := strconv.ParseFloat(s, 64)
+	CheckError(err)
+	return spinValue(x)
+}
+
+// normalizeName canonicalizes a variable name so that names produced by
+// different solvers for the same variable compare equal.  Surrounding
+// whitespace and trailing commas are discarded, and integral numbers are
+// written in plain decimal.
+func normalizeName(s string) string {
+	s = strings.TrimRight(strings.TrimSpace(s), ",")
+	if x, err := strconv.ParseFloat(s, 64); err == nil && x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+		return strconv.FormatInt(int64(x), 10)
+	}
+	return s
+}
+
+// jsonLabel converts a JSON variable label to a string.  Strings are taken
+// verbatim; anything else (numbers, tuples) is represented by its JSON text.
+func jsonLabel(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// ReadBqpjsonSolutions returns the solutions listed in a bqpjson document's
+// "solutions" block.
+func ReadBqpjsonSolutions(data []byte) []Solution {
+	var doc struct {
+		Solutions []struct {
+			ID         json.RawMessage `json:"id"`
+			Assignment []struct {
+				ID    int     `json:"id"`
+				Value float64 `json:"value"`
+			} `json:"assignment"`
+		} `json:"solutions"`
+	}
+	CheckError(json.Unmarshal(data, &doc))
+	sols := make([]Solution, 0, len(doc.Solutions))
+	for i, bs := range doc.Solutions {
+		sol := Solution{Label: strconv.Itoa(i), Spins: make(map[string]int, len(bs.Assignment))}
+		if len(bs.ID) > 0 {
+			sol.Label = jsonLabel(bs.ID)
+		}
+		for _, a := range bs.Assignment {
+			sol.Spins[strconv.Itoa(a.ID)] = spinValue(a.Value)
+		}
+		sols = append(sols, sol)
+	}
+	return sols
+}
+
+// ReadDimodSolutions returns the samples in a dimod SampleSet serialized as
+// JSON (cf. dimod's SampleSet.to_serializable).  Samples must not have been
+// packed into bits, i.e., they must have been serialized with
+// pack_samples=False.
+func ReadDimodSolutions(data []byte) []Solution {
+	// Parse the parts of the SampleSet we care about.
+	var doc struct {
+		Labels []json.RawMessage `json:"variable_labels"`
+		Record struct {
+			Sample json.RawMessage `json:"sample"`
+		} `json:"record"`
+	}
+	CheckError(json.Unmarshal(data, &doc))
+
+	// Samples are either a bare 2-D array or a serialized NumPy array
+	// whose "data" field is a 2-D array.
+	var rows [][]float64
+	if err := json.Unmarshal(doc.Record.Sample, &rows); err != nil {
+		var arr struct {
+			Data [][]float64 `json:"data"`
+		}
+		if json.Unmarshal(doc.Record.Sample, &arr) != nil {
+			Abortf("Failed to parse dimod samples (were they serialized with pack_samples=False?)")
+		}
+		rows = arr.Data
+	}
+
+	// Convert each sample to a Solution.
+	sols := make([]Solution, 0, len(rows))
+	for i, row := range rows {
+		if len(row) != len(doc.Labels) {
+			Abortf("dimod sample %d has %d values but there are %d variable labels", i, len(row), len(doc.Labels))
+		}
+		sol := Solution{Label: strconv.Itoa(i), Spins: make(map[string]int, len(row))}
+		for j, x := range row {
+			sol.Spins[jsonLabel(doc.Labels[j])] = spinValue(x)
+		}
+		sols = append(sols, sol)
+	}
+	return sols
+}
+
+// ReadTextSolutions returns the solutions in a plain-text file in which each
+// line contains a variable name and its value.  Blank lines separate
+// solutions, and "#" begins a comment.
+func ReadTextSolutions(r io.Reader) []Solution {
+	var sols []Solution
+	var sol Solution
+	flush := func() {
+		if len(sol.Spins) > 0 {
+			sol.Label = strconv.Itoa(len(sols))
+			sols = append(sols, sol)
+		}
+		sol = Solution{}
+	}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		// Discard comments.
+		ln := sc.Text()
+		if hIdx := strings.Index(ln, "#"); hIdx >= 0 {
+			ln = ln[:hIdx]
+		}
+
+		// Parse the line.
+		fs := strings.Fields(ln)
+		switch len(fs) {
+		case 0:
+			flush()
+		case 2:
+			if sol.Spins == nil {
+				sol.Spins = make(map[string]int)
+			}
+			sol.Spins[fs[0]] = parseSpin(fs[1])
+		default:
+			Abortf("Failed to parse solution line %q", strings.TrimSpace(ln))
+		}
+	}
+	CheckError(sc.Err())
+	flush()
+	return sols
+}
+
+// ReadQMASMSolutions returns the solutions in the output of QMASM (cf.
+// https://github.com/lanl/qmasm).  Each solution begins with a "Solution #"
+// header and lists one or more variable names followed by a spin and a
+// Boolean value.
+func ReadQMASMSolutions(r io.Reader) []Solution {
+	var sols []Solution
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		// Start a new solution at each header.
+		ln := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(ln, "Solution #") {
+			label := strings.TrimPrefix(ln, "Solution #")
+			if sp := strings.IndexAny(label, " :("); sp >= 0 {
+				label = label[:sp]
+			}
+			sols = append(sols, Solution{Label: label, Spins: make(map[string]int)})
+			continue
+		}
+		if len(sols) == 0 {
+			continue // Preamble
+		}
+
+		// Parse a data line, ignoring column headers and rules.
+		fs := strings.Fields(ln)
+		if len(fs) < 3 || fs[0] == "Name(s)" || strings.Trim(fs[0], "-") == "" {
+			continue
+		}
+		s := parseSpin(fs[len(fs)-2])
+		for _, v := range fs[:len(fs)-2] {
+			sols[len(sols)-1].Spins[v] = s
+		}
+	}
+	CheckError(sc.Err())
+	return sols
+}
+
+// ReadSolutions reads solutions in the named format.  The "auto" format
+// infers the format from the file's contents.
+func ReadSolutions(solFmt string, r io.Reader) []Solution {
+	data, err := ioutil.ReadAll(r)
+	CheckError(err)
+	if solFmt == "auto" {
+		solFmt = "text"
+		switch {
+		case bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")):
+			var keys map[string]json.RawMessage
+			CheckError(json.Unmarshal(data, &keys))
+			if _, ok := keys["record"]; ok {
+				solFmt = "dimod"
+			} else {
+				solFmt = "bqpjson"
+			}
+		case bytes.Contains(data, []byte("Solution #")):
+			solFmt = "qmasm"
+		}
+	}
+	switch solFmt {
+	case "bqpjson":
+		return ReadBqpjsonSolutions(data)
+	case "dimod":
+		return ReadDimodSolutions(data)
+	case "text":
+		return ReadTextSolutions(bytes.NewReader(data))
+	case "qmasm":
+		return ReadQMASMSolutions(bytes.NewReader(data))
+	default:
+		Abortf("Unrecognized solution format %q", solFmt)
+	}
+	return nil
+}
+
+// spinVector maps a solution onto a graph's sorted vertices after
+// normalizing the names on both sides.  It aborts if any vertex is left
+// unassigned and returns the number of solution variables not found in the
+// graph.
+func (sol Solution) spinVector(names []string) ([]int, int) {
+	norm := make(map[string]int, len(sol.Spins))
+	for v, s := range sol.Spins {
+		norm[normalizeName(v)] = s
+	}
+	s := make([]int, len(names))
+	for i, v := range names {
+		x, ok := norm[normalizeName(v)]
+		if !ok {
+			Abortf("Solution %s assigns no value to variable %q", sol.Label, v)
+		}
+		s[i] = x
+		delete(norm, normalizeName(v))
+	}
+	return s, len(norm)
+}
@@ -0,0 +1,338 @@
+/* This file provides a handful of simple solvers that search for low-energy
+spin assignments of a graph treated as an Ising Hamiltonian. */
+
+package frustration
+
+import (
+	"math"
+	"math/rand"
+)
+
+// greedyRestarts is the number of random starting points the greedy solver
+// descends from.
+const greedyRestarts = 10
+
+// maxExactVars is the largest number of variables for which exhaustive
+// search is considered feasible.
+const maxExactVars = 24
+
+// ptReplicas is the number of replicas used by parallel tempering.
+const ptReplicas = 8
+
+// A coupling is one direction of a weighted edge.
+type coupling struct {
+	To int     // Index of the neighboring vertex
+	J  float64 // Coupler strength
+}
+
+// An isingModel represents a Graph as vertex indices and weights.
+type isingModel struct {
+	Names []string     // Map from a vertex index to a vertex name
+	H     []float64    // Map from a vertex index to an external field
+	Adj   [][]coupling // Map from a vertex index to its incident couplings
+	Edges [][2]int     // List of edges, each as a pair of vertex indices
+	J     []float64    // Strength of each edge in Edges
+	Fixed int          // Index of a spin held at +1 by the solvers (-1 if none)
+}
+
+// isingModel converts a Graph to an isingModel.  Vertices are indexed in
+// lexicographic order of their names.
+func (g Graph) isingModel() isingModel {
+	// Assign each vertex an index.
+	names := g.sortedVertices()
+	idx := make(map[string]int, len(names))
+	im := isingModel{
+		Names: names,
+		H:     make([]float64, len(names)),
+		Adj:   make([][]coupling, len(names)),
+		Edges: make([][2]int, 0, len(g.Es)),
+		J:     make([]float64, 0, len(g.Es)),
+		Fixed: -1,
+	}
+	for i, v := range names {
+		idx[v] = i
+		im.H[i] = g.Vs[v]
+	}
+
+	// Add each edge in a deterministic order.
+	es := g.sortedEdges()
+	for _, e := range es {
+		u, v, j := idx[e[0]], idx[e[1]], g.Es[e]
+		im.Edges = append(im.Edges, [2]int{u, v})
+		im.J = append(im.J, j)
+		im.Adj[u] = append(im.Adj[u], coupling{To: v, J: j})
+		im.Adj[v] = append(im.Adj[v], coupling{To: u, J: j})
+	}
+	return im
+}
+
+// flipSymmetric says whether the model's energy is invariant under a global
+// spin flip, which is the case when every external field is zero.
+func (im isingModel) flipSymmetric() bool {
+	for _, h := range im.H {
+		if h != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fixSpin returns a copy of the model in which the solvers hold the first
+// spin at +1, breaking the global spin-flip symmetry.  Doing so loses no
+// ground states up to that symmetry.  It returns false if the model is not
+// flip-symmetric or has no spins.
+func (im isingModel) fixSpin() (isingModel, bool) {
+	if len(im.Names) == 0 || !im.flipSymmetric() {
+		return im, false
+	}
+	im.Fixed = 0
+	return im, true
+}
+
+// Energy returns the energy of a spin assignment.
+func (im isingModel) Energy(s []int) float64 {
+	e := 0.0
+	for i, h := range im.H {
+		e += h * float64(s[i])
+	}
+	for k, uv := range im.Edges {
+		e += im.J[k] * float64(s[uv[0]]*s[uv[1]])
+	}
+	return e
+}
+
+// unsatisfied returns the indices of the edges left unsatisfied by a spin
+// assignment, i.e., those that contribute positive energy.
+func (im isingModel) unsatisfied(s []int) []int {
+	un := make([]int, 0, len(im.Edges))
+	for k, uv := range im.Edges {
+		if im.J[k]*float64(s[uv[0]]*s[uv[1]]) > 0 {
+			un = append(un, k)
+		}
+	}
+	return un
+}
+
+// localField returns the effective field acting on vertex i.
+func (im isingModel) localField(s []int, i int) float64 {
+	f := im.H[i]
+	for _, c := range im.Adj[i] {
+		f += c.J * float64(s[c.To])
+	}
+	return f
+}
+
+// randomSpins returns a random spin assignment.
+func (im isingModel) randomSpins(rng *rand.Rand) []int {
+	s := make([]int, len(im.Names))
+	for i := range s {
+		s[i] = 2*rng.Intn(2) - 1
+	}
+	if im.Fixed >= 0 {
+		s[im.Fixed] = 1
+	}
+	return s
+}
+
+// maxCoefficient returns the largest field or coupler magnitude in the model,
+// or 1 if all are zero.
+func (im isingModel) maxCoefficient() float64 {
+	mc := 0.0
+	for _, h := range im.H {
+		mc = math.Max(mc, math.Abs(h))
+	}
+	for _, j := range im.J {
+		mc = math.Max(mc, math.Abs(j))
+	}
+	if mc == 0 {
+		mc = 1
+	}
+	return mc
+}
+
+// descend repeatedly flips the spin that most reduces the energy until no
+// flip reduces it further.  s is modified in place.
+func (im isingModel) descend(s []int) {
+	for {
+		best, bestDelta := -1, 0.0
+		for i := range s {
+			if i == im.Fixed {
+				continue
+			}
+			if d := -2 * float64(s[i]) * im.localField(s, i); d < bestDelta {
+				best, bestDelta = i, d
+			}
+		}
+		if best < 0 {
+			return
+		}
+		s[best] = -s[best]
+	}
+}
+
+// SolveGreedy performs steepest descent from several random starting points
+// and returns the lowest-energy assignment found.
+func (im isingModel) SolveGreedy(rng *rand.Rand) []int {
+	var best []int
+	bestE := math.Inf(1)
+	for r := 0; r < greedyRestarts; r++ {
+		s := im.randomSpins(rng)
+		im.descend(s)
+		if e := im.Energy(s); e < bestE {
+			best, bestE = s, e
+		}
+	}
+	return best
+}
+
+// metropolisSweep attempts to flip each spin once at inverse temperature
+// beta.
+func (im isingModel) metropolisSweep(s []int, beta float64, rng *rand.Rand) {
+	for i := range s {
+		if i == im.Fixed {
+			continue
+		}
+		d := -2 * float64(s[i]) * im.localField(s, i)
+		if d <= 0 || rng.Float64() < math.Exp(-beta*d) {
+			s[i] = -s[i]
+		}
+	}
+}
+
+// SolveAnnealing performs simulated annealing with a linear inverse
+// temperature schedule and returns the lowest-energy assignment encountered.
+func (im isingModel) SolveAnnealing(sweeps int, rng *rand.Rand) []int {
+	mc := im.maxCoefficient()
+	b0, b1 := 0.1/mc, 5.0/mc
+	s := im.randomSpins(rng)
+	best := append([]int(nil), s...)
+	bestE := im.Energy(s)
+	for k := 0; k < sweeps; k++ {
+		beta := b0 + (b1-b0)*float64(k)/float64(sweeps)
+		im.metropolisSweep(s, beta, rng)
+		if e := im.Energy(s); e < bestE {
+			copy(best, s)
+			bestE = e
+		}
+	}
+	im.descend(best)
+	return best
+}
+
+// SolveTempering performs parallel tempering over a geometric ladder of
+// inverse temperatures and returns the lowest-energy assignment encountered.
+func (im isingModel) SolveTempering(sweeps int, rng *rand.Rand) []int {
+	// Prepare one replica per temperature.
+	mc := im.maxCoefficient()
+	b0, b1 := 0.1/mc, 5.0/mc
+	betas := make([]float64, ptReplicas)
+	reps := make([][]int, ptReplicas)
+	es := make([]float64, ptReplicas)
+	for r := range reps {
+		betas[r] = b0 * math.Pow(b1/b0, float64(r)/float64(ptReplicas-1))
+		reps[r] = im.randomSpins(rng)
+		es[r] = im.Energy(reps[r])
+	}
+	best := append([]int(nil), reps[0]...)
+	bestE := es[0]
+
+	// Alternate between Metropolis sweeps and replica exchanges.
+	for k := 0; k < sweeps; k++ {
+		for r, s := range reps {
+			im.metropolisSweep(s, betas[r], rng)
+			es[r] = im.Energy(s)
+			if es[r] < bestE {
+				copy(best, s)
+				bestE = es[r]
+			}
+		}
+		for r := 0; r < ptReplicas-1; r++ {
+			d := (betas[r] - betas[r+1]) * (es[r] - es[r+1])
+			if d >= 0 || rng.Float64() < math.Exp(d) {
+				reps[r], reps[r+1] = reps[r+1], reps[r]
+				es[r], es[r+1] = es[r+1], es[r]
+			}
+		}
+	}
+	im.descend(best)
+	return best
+}
+
+// groundStateTolerance is the relative energy difference below which two
+// assignments are considered degenerate.
+const groundStateTolerance = 1e-9
+
+// exhaustive enumerates every spin assignment (except for the fixed spin, if
+// any) in Gray-code order.  It returns one assignment with minimal energy and
+// the number of enumerated assignments that attain that energy.  It returns
+// nil if the model has more than maxExactVars unfixed variables.
+func (im isingModel) exhaustive() ([]int, uint64) {
+	// Determine which spins to enumerate.
+	n := len(im.Names)
+	free := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		if i != im.Fixed {
+			free = append(free, i)
+		}
+	}
+	if len(free) > maxExactVars {
+		return nil, 0
+	}
+	s := make([]int, n)
+	for i := range s {
+		s[i] = -1
+	}
+	if im.Fixed >= 0 {
+		s[im.Fixed] = 1
+	}
+
+	// Enumerate the assignments, keeping track of the best and how many
+	// tie it.
+	e := im.Energy(s)
+	best := append([]int(nil), s...)
+	bestE := e
+	count := uint64(1)
+	tol := groundStateTolerance * im.maxCoefficient()
+	for k := uint64(1); k < uint64(1)<<uint(len(free)); k++ {
+		// Flip the spin corresponding to the lowest set bit of k.
+		b := 0
+		for k&(uint64(1)<<uint(b)) == 0 {
+			b++
+		}
+		i := free[b]
+		e -= 2 * float64(s[i]) * im.localField(s, i)
+		s[i] = -s[i]
+		switch {
+		case e < bestE-tol:
+			copy(best, s)
+			bestE = e
+			count = 1
+		case e <= bestE+tol:
+			count++
+		}
+	}
+	return best, count
+}
+
+// SolveExhaustive enumerates every spin assignment in Gray-code order and
+// returns one with minimal energy.  It returns nil if the model has more
+// than maxExactVars variables.
+func (im isingModel) SolveExhaustive() []int {
+	best, _ := im.exhaustive()
+	return best
+}
+
+// degeneracy converts the number of ground states found by exhaustive to the
+// ground-state degeneracy both counting every assignment and counting an
+// assignment and its global flip only once.  The two differ only for
+// flip-symmetric models.
+func (im isingModel) degeneracy(n uint64) (uint64, uint64) {
+	switch {
+	case im.Fixed >= 0:
+		return 2 * n, n
+	case im.flipSymmetric():
+		return n, n / 2
+	default:
+		return n, n
+	}
+}
@@ -0,0 +1,120 @@
+/* This file computes structural statistics of a graph that are cheap enough
+to triage many unknown instances before committing to a full analysis. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// components returns the size of each connected component of a graph, in
+// decreasing order.
+func (g Graph) components() []int {
+	adj := g.sortedAdjacency()
+	seen := make(map[string]bool, len(g.Vs))
+	var sizes []int
+	for _, root := range g.sortedVertices() {
+		if seen[root] {
+			continue
+		}
+		seen[root] = true
+		n := 0
+		stack := []string{root}
+		for len(stack) > 0 {
+			u := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			n++
+			for _, v := range adj[u] {
+				if !seen[v] {
+					seen[v] = true
+					stack = append(stack, v)
+				}
+			}
+		}
+		sizes = append(sizes, n)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+	return sizes
+}
+
+// OutputGraphStats outputs a graph's structural statistics: its numbers of
+// vertices and edges, its density, its degree distribution, its connected
+// components, the fraction of its couplings that are antiferromagnetic, the
+// fraction of its vertices with a nonzero external field, and the dimension
+// and size of its cycle space.  No cycles are computed.
+func OutputGraphStats(w io.Writer, g Graph) {
+	// Output the size and density.
+	nv, ne := len(g.Vs), len(g.Es)
+	pairs := nv * (nv - 1) / 2
+	fmt.Fprintf(w, "#VTX %d\n", nv)
+	fmt.Fprintf(w, "#EDG %d\n", ne)
+	fmt.Fprintf(w, "#DNS %d / %d = %f\n", ne, pairs, fraction(ne, pairs))
+
+	// Output the degree distribution.
+	adj := g.sortedAdjacency()
+	hist := make(map[int]int)
+	minDeg, maxDeg := math.MaxInt32, 0
+	for v := range g.Vs {
+		d := len(adj[v])
+		hist[d]++
+		if d < minDeg {
+			minDeg = d
+		}
+		if d > maxDeg {
+			maxDeg = d
+		}
+	}
+	if nv == 0 {
+		minDeg = 0
+	}
+	for d := minDeg; d <= maxDeg; d++ {
+		if hist[d] > 0 {
+			fmt.Fprintf(w, "DEGD %d %d\n", d, hist[d])
+		}
+	}
+	mean := 0.0
+	if nv > 0 {
+		mean = 2 * float64(ne) / float64(nv)
+	}
+	fmt.Fprintf(w, "#DEGD %d %f %d\n", minDeg, mean, maxDeg)
+
+	// Output the connected components.
+	comps := g.components()
+	largest, isolated := 0, 0
+	if len(comps) > 0 {
+		largest = comps[0]
+	}
+	for _, n := range comps {
+		if n == 1 {
+			isolated++
+		}
+	}
+	fmt.Fprintf(w, "#CMP %d %d %d\n", len(comps), largest, isolated)
+
+	// Output the sign balance and the prevalence of external fields.
+	nafm := 0
+	for e := range g.Es {
+		if s, _ := g.couplingSign(e[0], e[1]); s < 0 {
+			nafm++
+		}
+	}
+	fmt.Fprintf(w, "#AFM %d / %d = %f\n", nafm, ne, fraction(nafm, ne))
+	nh := 0
+	for _, h := range g.Vs {
+		if h != 0 {
+			nh++
+		}
+	}
+	fmt.Fprintf(w, "#HFLD %d / %d = %f\n", nh, nv, fraction(nh, nv))
+
+	// Output the dimension of the cycle space, which is also the number of
+	// base cycles, and the number of elements it contains, in scientific
+	// notation computed from its logarithm so as not to overflow.
+	dim := ne - nv + len(comps)
+	lg := float64(dim) * math.Log10(2)
+	exp := math.Floor(lg)
+	fmt.Fprintf(w, "#CSD %d %.3fe+%02.0f\n", dim, math.Pow(10, lg-exp), exp)
+}
@@ -0,0 +1,127 @@
+/* This file analyzes a family of problem instances that differ in a single
+parameter, such as a penalty weight encoded in each file's name, and tabulates
+how frustration evolves as the parameter varies. */
+
+package frustration
+
+import (
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultSweepPattern matches the last number in a file name, ignoring any
+// extension.
+const defaultSweepPattern = `([0-9]*\.?[0-9]+(?:[eE][-+]?[0-9]+)?)[^0-9]*(?:\.[^.]*)?$`
+
+// A SweepPoint represents the frustration of one instance in a parameter
+// sweep.
+type SweepPoint struct {
+	Param   float64 // Value of the swept parameter
+	File    string  // Name of the instance's file
+	Summary Summary // Aggregate statistics
+}
+
+// ParseSweepPattern compiles a regular expression that extracts a sweep
+// parameter from a file name.  An empty pattern selects the default, which
+// matches the last number in the name other than in its extension.
+func ParseSweepPattern(s string) *regexp.Regexp {
+	if s == "" {
+		s = defaultSweepPattern
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		Abortf("Invalid sweep pattern %q (%v)", s, err)
+	}
+	return re
+}
+
+// sweepParam extracts a sweep parameter from a file name using the first
+// parenthesized subexpression of a regular expression or, if it has none,
+// the entire match.  It returns false if no parameter could be extracted.
+func sweepParam(re *regexp.Regexp, name string) (float64, bool) {
+	m := re.FindStringSubmatch(filepath.Base(name))
+	if m == nil {
+		return 0, false
+	}
+	s := m[0]
+	if len(m) > 1 {
+		s = m[1]
+	}
+	p, err := strconv.ParseFloat(s, 64)
+	return p, err == nil
+}
+
+// SweepDirectory analyzes every instance in a directory whose name yields a
+// sweep parameter and returns the results in increasing order of the
+// parameter.  Hidden files and subdirectories are ignored.  Every instance
+// is analyzed with the same pseudorandom seed so that differences in the
+// results reflect differences in the instances.
+func SweepDirectory(dir, inFmt string, re *regexp.Regexp, c Classifier, allCycs bool, rules ClassRules, lim InputLimits, seed int64) []SweepPoint {
+	fis, err := ioutil.ReadDir(dir)
+	CheckError(err)
+	var pts []SweepPoint
+	for _, fi := range fis {
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		p, ok := sweepParam(re, fi.Name())
+		if !ok {
+			Warn("W009-skipped-file", fi.Name(), "Skipping %s, from whose name no parameter could be extracted", fi.Name())
+			continue
+		}
+		fn := filepath.Join(dir, fi.Name())
+		var g Graph
+		err := func() (err error) {
+			defer RecoverFatal(&err)
+			f, err := os.Open(fn)
+			CheckError(err)
+			defer f.Close()
+			g = ReadGraph(inFmt, f, lim)
+			return nil
+		}()
+		if err != nil {
+			Abortf("%s: %v", fn, err)
+		}
+		bcs, ecs, _ := g.findCycles(allCycs, rand.New(rand.NewSource(seed)))
+		res := AnalyzeGraph(g, bcs, ecs, c, allCycs, rules)
+		pts = append(pts, SweepPoint{Param: p, File: fi.Name(), Summary: res.Summary})
+	}
+	if len(pts) == 0 {
+		Abortf("No instances with a parameter in their name were found in %s", dir)
+	}
+	sort.SliceStable(pts, func(i, j int) bool { return pts[i].Param < pts[j].Param })
+	return pts
+}
+
+// OutputSweepCSV outputs the results of a parameter sweep as CSV, one row
+// per instance, suitable for plotting frustration against the parameter.
+func OutputSweepCSV(w io.Writer, pts []SweepPoint) {
+	cw := csv.NewWriter(w)
+	CheckError(cw.Write([]string{
+		"param", "file",
+		"frustrated_vertices", "total_vertices", "vertex_fraction",
+		"frustrated_edges", "total_edges", "edge_fraction",
+		"frustrated_cycles", "total_cycles", "cycle_fraction",
+	}))
+	itoa := strconv.Itoa
+	ftoa := func(x float64) string { return strconv.FormatFloat(x, 'g', -1, 64) }
+	for _, pt := range pts {
+		s := pt.Summary
+		CheckError(cw.Write([]string{
+			ftoa(pt.Param), pt.File,
+			itoa(s.FrustratedVertices), itoa(s.TotalVertices), ftoa(s.VertexFraction),
+			itoa(s.FrustratedEdges), itoa(s.TotalEdges), ftoa(s.EdgeFraction),
+			itoa(s.FrustratedCycles), itoa(s.TotalCycles), ftoa(s.CycleFraction),
+		}))
+	}
+	cw.Flush()
+	CheckError(cw.Error())
+}
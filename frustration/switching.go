@@ -0,0 +1,292 @@
+/* This file provides functions for estimating a graph's frustration index
+(the minimum number of edges whose sign must change to eliminate all
+frustration) by local search over vertex switchings. */
+
+package frustration
+
+import (
+	"container/heap"
+	"math/rand"
+	"sort"
+)
+
+// A signedArc is one direction of a signed edge.
+type signedArc struct {
+	To   int // Index of the neighboring vertex
+	Sign int // +1 for ferromagnetic, -1 for antiferromagnetic
+}
+
+// A signedGraph represents a Graph with its vertex names interned as dense
+// integer indices, which lets algorithms use slices in place of the Graph's
+// string-keyed maps.  Names serves as the symbol table for reporting results.
+type signedGraph struct {
+	Names    []string       // Map from a vertex index to a vertex name
+	Index    map[string]int // Map from a vertex name to a vertex index
+	Adj      [][]signedArc  // Map from a vertex index to its incident arcs, in increasing order of index
+	Edges    [][2]int       // List of edges, each as a pair of vertex indices, in lexicographic order
+	Signs    []int          // Sign of each edge in Edges
+	Fields   []float64      // Map from a vertex index to its weight (nil if not derived from a Graph)
+	Weights  []float64      // Weight of each edge in Edges (nil if not derived from a Graph)
+	deadline deadline       // Time after which cycle computations abort (zero for none)
+}
+
+// signedGraph converts a Graph to a signedGraph.  Vertices are indexed in
+// lexicographic order of their names, so comparing two vertices' indices is
+// equivalent to comparing their names.
+func (g Graph) signedGraph() signedGraph {
+	// Assign each vertex an index.
+	names := g.sortedVertices()
+	idx := make(map[string]int, len(names))
+	for i, v := range names {
+		idx[v] = i
+	}
+
+	// Determine the sign of each edge.
+	es := g.sortedEdges()
+	sg := signedGraph{
+		Names:    names,
+		Index:    idx,
+		Adj:      make([][]signedArc, len(names)),
+		Edges:    make([][2]int, len(es)),
+		Signs:    make([]int, len(es)),
+		Fields:   make([]float64, len(names)),
+		Weights:  make([]float64, len(es)),
+		deadline: g.deadline,
+	}
+	for i, v := range names {
+		sg.Fields[i] = g.Vs[v]
+	}
+	for i, e := range es {
+		u, v := idx[e[0]], idx[e[1]]
+		s, _ := g.couplingSign(e[0], e[1])
+		sg.Edges[i] = [2]int{u, v}
+		sg.Signs[i] = s
+		sg.Weights[i] = g.Es[e]
+		sg.Adj[u] = append(sg.Adj[u], signedArc{To: v, Sign: s})
+		sg.Adj[v] = append(sg.Adj[v], signedArc{To: u, Sign: s})
+	}
+	return sg
+}
+
+// edgeName returns the vertex names of the edge with a given index.
+func (sg signedGraph) edgeName(i int) [2]string {
+	e := sg.Edges[i]
+	return [2]string{sg.Names[e[0]], sg.Names[e[1]]}
+}
+
+// pathNames converts a path of vertex indices to a path of vertex names.
+func (sg signedGraph) pathNames(p []int) []string {
+	names := make([]string, len(p))
+	for i, v := range p {
+		names[i] = sg.Names[v]
+	}
+	return names
+}
+
+// pathEdges converts a cycle of vertex indices to a list of edges named by
+// their vertices, as pathToEdges does for a cycle of vertex names.
+func (sg signedGraph) pathEdges(p []int) [][2]string {
+	edges := make([][2]string, len(p))
+	for i, u := range p {
+		v := p[(i+1)%len(p)]
+		if u > v {
+			u, v = v, u
+		}
+		edges[i] = [2]string{sg.Names[u], sg.Names[v]}
+	}
+	return edges
+}
+
+// cutGraph returns a signedGraph whose edges are the given edges, all
+// antiferromagnetic, and whose vertices are their endpoints, indexed in
+// lexicographic order of their names.  Minimizing its negative edges by
+// switching maximizes the number of edges cut by the bipartition into
+// switched and unswitched vertices.
+func cutGraph(es [][2]string) signedGraph {
+	idx := make(map[string]int)
+	for _, e := range es {
+		idx[e[0]] = 0
+		idx[e[1]] = 0
+	}
+	names := make([]string, 0, len(idx))
+	for v := range idx {
+		names = append(names, v)
+	}
+	sort.Strings(names)
+	for i, v := range names {
+		idx[v] = i
+	}
+	sg := signedGraph{
+		Names: names,
+		Index: idx,
+		Adj:   make([][]signedArc, len(names)),
+		Edges: make([][2]int, len(es)),
+		Signs: make([]int, len(es)),
+	}
+	for i, e := range es {
+		u, v := idx[e[0]], idx[e[1]]
+		sg.Edges[i] = [2]int{u, v}
+		sg.Signs[i] = -1
+		sg.Adj[u] = append(sg.Adj[u], signedArc{To: v, Sign: -1})
+		sg.Adj[v] = append(sg.Adj[v], signedArc{To: u, Sign: -1})
+	}
+	return sg
+}
+
+// negativeEdges returns the number of edges that are negative after
+// switching every vertex v for which sw[v] is -1.
+func (sg signedGraph) negativeEdges(sw []int) int {
+	neg := 0
+	for i, e := range sg.Edges {
+		if sg.Signs[i]*sw[e[0]]*sw[e[1]] < 0 {
+			neg++
+		}
+	}
+	return neg
+}
+
+// balancingSwitching searches for a switching that makes every edge positive.
+// It returns the switching (-1 for a switched vertex, +1 otherwise) and true
+// if one exists or nil and false if the graph is unbalanced (i.e., contains
+// at least one frustrated cycle).
+func (sg signedGraph) balancingSwitching() ([]int, bool) {
+	sw := make([]int, len(sg.Names)) // 0 means "not yet visited".
+	for root := range sw {
+		// Perform a breadth-first search from each unvisited vertex,
+		// switching each neighbor as needed to make the connecting
+		// edge positive.
+		if sw[root] != 0 {
+			continue
+		}
+		sw[root] = 1
+		queue := []int{root}
+		for len(queue) > 0 {
+			u := queue[0]
+			queue = queue[1:]
+			for _, a := range sg.Adj[u] {
+				want := a.Sign * sw[u]
+				switch sw[a.To] {
+				case 0:
+					sw[a.To] = want
+					queue = append(queue, a.To)
+				case want:
+				default:
+					return nil, false
+				}
+			}
+		}
+	}
+	return sw, true
+}
+
+// A gainItem associates a vertex with the reduction in negative edges that
+// switching it would produce.
+type gainItem struct {
+	V    int // Vertex index
+	Gain int // Reduction in the number of negative edges
+}
+
+// A gainHeap is a max-heap of gainItems.  Stale entries are tolerated and
+// must be checked against the current gains by the caller.
+type gainHeap []gainItem
+
+func (h gainHeap) Len() int            { return len(h) }
+func (h gainHeap) Less(i, j int) bool  { return h[i].Gain > h[j].Gain }
+func (h gainHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *gainHeap) Push(x interface{}) { *h = append(*h, x.(gainItem)) }
+func (h *gainHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// localSwitch repeatedly switches the vertex that most reduces the number of
+// negative edges until no switch reduces it further.  sw is modified in place.
+func (sg signedGraph) localSwitch(sw []int) {
+	// Compute each vertex's initial gain: the number of negative incident
+	// edges minus the number of positive incident edges.
+	gain := make([]int, len(sg.Names))
+	h := make(gainHeap, 0, len(sg.Names))
+	for v, arcs := range sg.Adj {
+		for _, a := range arcs {
+			gain[v] -= a.Sign * sw[v] * sw[a.To]
+		}
+		if gain[v] > 0 {
+			h = append(h, gainItem{V: v, Gain: gain[v]})
+		}
+	}
+	heap.Init(&h)
+
+	// Greedily switch vertices, updating the gains of their neighbors.
+	for h.Len() > 0 {
+		it := heap.Pop(&h).(gainItem)
+		if it.Gain != gain[it.V] || it.Gain <= 0 {
+			continue // Stale entry
+		}
+		v := it.V
+		sw[v] = -sw[v]
+		gain[v] = -gain[v]
+		for _, a := range sg.Adj[v] {
+			// Edge va just changed sign.  If it is now negative,
+			// switching a.To would fix it; otherwise switching
+			// a.To would break it.
+			if a.Sign*sw[v]*sw[a.To] < 0 {
+				gain[a.To] += 2
+			} else {
+				gain[a.To] -= 2
+			}
+			if gain[a.To] > 0 {
+				heap.Push(&h, gainItem{V: a.To, Gain: gain[a.To]})
+			}
+		}
+	}
+}
+
+// DefaultRestarts is the number of random restarts used to estimate the
+// frustration index heuristically when no other number is specified.
+const DefaultRestarts = 100
+
+// frustrationIndexHeuristic estimates the frustration index of a graph by
+// performing locally switching-optimal search from the unswitched graph and
+// from the given number of random switchings.  It returns the best number of
+// negative edges found (an upper bound on the frustration index) and the
+// corresponding switching (-1 for a switched vertex, +1 otherwise).
+func (sg signedGraph) frustrationIndexHeuristic(restarts int, rng *rand.Rand) (int, []int) {
+	nv := len(sg.Names)
+	var best []int
+	bestNeg := len(sg.Edges) + 1
+	for r := 0; r <= restarts; r++ {
+		// Start from either the original graph or a random switching.
+		sw := make([]int, nv)
+		for v := range sw {
+			sw[v] = 1
+			if r > 0 && rng.Intn(2) == 0 {
+				sw[v] = -1
+			}
+		}
+
+		// Descend to a local optimum and retain the best seen.
+		sg.localSwitch(sw)
+		if neg := sg.negativeEdges(sw); neg < bestNeg {
+			bestNeg = neg
+			best = sw
+		}
+	}
+
+	// Switching every vertex is a no-op so report the smaller of the two
+	// equivalent switching sets.
+	nsw := 0
+	for _, x := range best {
+		if x < 0 {
+			nsw++
+		}
+	}
+	if 2*nsw > nv {
+		for v := range best {
+			best[v] = -best[v]
+		}
+	}
+	return bestNeg, best
+}
@@ -0,0 +1,141 @@
+/* This file provides functions for detecting simple symmetries in a graph and
+for collapsing symmetric lines of output into a single line per class. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// refineColors partitions the graph's vertices into classes that cannot be
+// distinguished by their external field or by the fields and couplings of
+// any neighborhood around them (1-dimensional Weisfeiler-Lehman color
+// refinement).  Vertices related by a graph automorphism always share a
+// class.  It returns a map from each vertex to its class number.  Class
+// numbers depend only on graph structure, not on vertex names.
+func (g Graph) refineColors() map[string]int {
+	return g.refineColorsVisit(nil)
+}
+
+// refineColorsVisit is refineColors but additionally passes each round's
+// vertex signatures, which are themselves independent of vertex names, to a
+// visit function (if non-nil).
+func (g Graph) refineColorsVisit(visit func(sigs map[string]string)) map[string]int {
+	// Precompute each vertex's neighbors and couplings.
+	type arc struct {
+		To string  // Neighboring vertex
+		J  float64 // Coupler strength
+	}
+	adj := make(map[string][]arc, len(g.Vs))
+	for e, wt := range g.Es {
+		adj[e[0]] = append(adj[e[0]], arc{To: e[1], J: wt})
+		adj[e[1]] = append(adj[e[1]], arc{To: e[0], J: wt})
+	}
+
+	// Initially color vertices by field alone.
+	sigs := make(map[string]string, len(g.Vs))
+	for v, h := range g.Vs {
+		sigs[v] = fmt.Sprint(h)
+	}
+	if visit != nil {
+		visit(sigs)
+	}
+	color, nc := canonicalColors(sigs)
+
+	// Repeatedly recolor each vertex by its color and the multiset of its
+	// neighbors' colors and couplings until the number of colors stops
+	// increasing.
+	for {
+		for v := range g.Vs {
+			nbrs := make([]string, len(adj[v]))
+			for i, a := range adj[v] {
+				nbrs[i] = fmt.Sprintf("%v:%d", a.J, color[a.To])
+			}
+			sort.Strings(nbrs)
+			sigs[v] = fmt.Sprintf("%d[%s]", color[v], strings.Join(nbrs, ","))
+		}
+		if visit != nil {
+			visit(sigs)
+		}
+		newColor, newNC := canonicalColors(sigs)
+		if newNC == nc {
+			return color
+		}
+		color, nc = newColor, newNC
+	}
+}
+
+// canonicalColors maps each vertex to the rank of its signature among all
+// distinct signatures.  It also returns the number of distinct signatures.
+func canonicalColors(sigs map[string]string) (map[string]int, int) {
+	uniq := make(map[string]int, len(sigs))
+	for _, s := range sigs {
+		uniq[s] = 0
+	}
+	sorted := make([]string, 0, len(uniq))
+	for s := range uniq {
+		sorted = append(sorted, s)
+	}
+	sort.Strings(sorted)
+	for i, s := range sorted {
+		uniq[s] = i
+	}
+	color := make(map[string]int, len(sigs))
+	for v, s := range sigs {
+		color[v] = uniq[s]
+	}
+	return color, len(sorted)
+}
+
+// edgeClass returns a class identifier for an edge given the classes of the
+// graph's vertices.  Edges of equal strength whose endpoints belong to the
+// same pair of classes share an edge class.
+func (g Graph) edgeClass(color map[string]int, e [2]string) string {
+	c0, c1 := color[e[0]], color[e[1]]
+	if c0 > c1 {
+		c0, c1 = c1, c0
+	}
+	return fmt.Sprintf("%d %d %v", c0, c1, g.Es[e])
+}
+
+// A classCollapser gathers report lines that are identical except for the
+// vertex or edge they name and that describe symmetric vertices or edges.  It
+// then outputs one line per class.
+type classCollapser struct {
+	keys  []string             // Class keys in order of first appearance
+	lines map[string][2]string // Map from a class key to a tag and the text following the member count
+	names map[string][]string  // Map from a class key to its members' names
+}
+
+// newClassCollapser returns an empty classCollapser.
+func newClassCollapser() *classCollapser {
+	return &classCollapser{
+		lines: make(map[string][2]string),
+		names: make(map[string][]string),
+	}
+}
+
+// Add adds a named member to the class identified by the given tag, text,
+// and symmetry class.
+func (cc *classCollapser) Add(tag, text, class, name string) {
+	key := tag + "\x00" + text + "\x00" + class
+	if _, ok := cc.lines[key]; !ok {
+		cc.keys = append(cc.keys, key)
+		cc.lines[key] = [2]string{tag, text}
+	}
+	cc.names[key] = append(cc.names[key], name)
+}
+
+// Output outputs one line per class, each of the form "〈tag〉 〈# of
+// members〉 〈text〉 | 〈member〉…".
+func (cc *classCollapser) Output(w io.Writer) {
+	for _, key := range cc.keys {
+		ln := cc.lines[key]
+		names := cc.names[key]
+		sort.Strings(names)
+		fmt.Fprintf(w, "%-4s %d %s | %s\n", ln[0], len(names), ln[1], strings.Join(names, " "))
+	}
+}
@@ -0,0 +1,46 @@
+/* This file measures how long each phase of a run takes. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// A PhaseTimer accumulates the wall-clock time spent in each named phase of
+// a run.  A nil *PhaseTimer is valid and records nothing.
+type PhaseTimer struct {
+	names []string                 // Phase names in order of first use
+	durs  map[string]time.Duration // Map from a phase name to its duration
+}
+
+// NewPhaseTimer returns an empty PhaseTimer.
+func NewPhaseTimer() *PhaseTimer {
+	return &PhaseTimer{durs: make(map[string]time.Duration)}
+}
+
+// Time invokes a function and charges the time it takes to the named phase.
+func (pt *PhaseTimer) Time(name string, f func()) {
+	if pt == nil {
+		f()
+		return
+	}
+	start := time.Now()
+	f()
+	if _, ok := pt.durs[name]; !ok {
+		pt.names = append(pt.names, name)
+	}
+	pt.durs[name] += time.Since(start)
+}
+
+// Output outputs one line per phase, in the order the phases began, followed
+// by the total across all phases.
+func (pt *PhaseTimer) Output(w io.Writer) {
+	var total time.Duration
+	for _, n := range pt.names {
+		fmt.Fprintf(w, "#TIME %s %f\n", n, pt.durs[n].Seconds())
+		total += pt.durs[n]
+	}
+	fmt.Fprintf(w, "#TIME total %f\n", total.Seconds())
+}
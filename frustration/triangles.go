@@ -0,0 +1,134 @@
+/* This file measures the frustration of a dense graph through its triangles
+alone, without finding any cycles.  If S is the signed adjacency matrix, whose
+entries are the coupling signs, and A = |S|, then the diagonal entry
+(S^3)_ii counts the balanced minus the frustrated closed walks of length 3
+through vertex i, twice for each triangle, and (A^3)_ii counts all of them.
+Equivalently, S_ij (S^2)_ij is the number of balanced minus the number of
+frustrated triangles through edge ij, which is what is computed here, with
+bit-set intersections of each vertex's positive and negative neighborhoods.
+The cost is proportional to the number of edges times the number of vertices
+divided by 64, however many cycles the graph has. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+// largeCycleSpace is the cycle-space dimension beyond which the cycle basis
+// itself is too large to analyze comfortably.
+const largeCycleSpace = 1000000
+
+// WarnIfDense issues a warning if a graph's cycle basis would be
+// impractically large, suggesting an analysis that does not need one.
+func (g Graph) WarnIfDense() {
+	dim := len(g.Es) - len(g.Vs) + len(g.components())
+	if dim > largeCycleSpace {
+		Warn("W011-large-cycle-space", "", "The graph has %d base cycles; --triangles or --sample-cycles may be more practical than a cycle basis", dim)
+	}
+}
+
+// triangleCounts returns the number of triangles through each vertex and the
+// number of those that are frustrated.
+func (sg signedGraph) triangleCounts() ([]int, []int) {
+	// Represent each vertex's positive and negative neighborhoods as bit
+	// sets.
+	nv := len(sg.Names)
+	words := (nv + 63) / 64
+	pos := make([]uint64, nv*words)
+	neg := make([]uint64, nv*words)
+	for u, as := range sg.Adj {
+		for _, a := range as {
+			if a.Sign > 0 {
+				pos[u*words+a.To/64] |= 1 << uint(a.To%64)
+			} else {
+				neg[u*words+a.To/64] |= 1 << uint(a.To%64)
+			}
+		}
+	}
+
+	// Count the triangles through each edge, dividing the edges among one
+	// worker per CPU, each of which accumulates per-vertex counts of its
+	// own.
+	type partial struct {
+		tri, frust []int
+	}
+	nw := runtime.NumCPU()
+	parts := make([]partial, nw)
+	var wg sync.WaitGroup
+	for w := range parts {
+		parts[w] = partial{tri: make([]int, nv), frust: make([]int, nv)}
+		wg.Add(1)
+		go func(w int, p partial) {
+			defer wg.Done()
+			for i := w; i < len(sg.Edges); i += nw {
+				u, v := sg.Edges[i][0], sg.Edges[i][1]
+				posU, negU := pos[u*words:(u+1)*words], neg[u*words:(u+1)*words]
+				posV, negV := pos[v*words:(v+1)*words], neg[v*words:(v+1)*words]
+				same, diff := 0, 0 // Common neighbors reached by equal or opposite signs
+				for k := range posU {
+					same += bits.OnesCount64(posU[k]&posV[k]) + bits.OnesCount64(negU[k]&negV[k])
+					diff += bits.OnesCount64(posU[k]&negV[k]) + bits.OnesCount64(negU[k]&posV[k])
+				}
+
+				// A triangle uvw is frustrated if the product of
+				// its three signs is negative.
+				nf := diff
+				if sg.Signs[i] < 0 {
+					nf = same
+				}
+				for _, x := range [2]int{u, v} {
+					p.tri[x] += same + diff
+					p.frust[x] += nf
+				}
+			}
+		}(w, parts[w])
+	}
+	wg.Wait()
+
+	// Merge the workers' counts.  Each triangle through a vertex was
+	// counted once from each of the vertex's two edges in the triangle.
+	tri := make([]int, nv)
+	frust := make([]int, nv)
+	for _, p := range parts {
+		for x := range tri {
+			tri[x] += p.tri[x]
+			frust[x] += p.frust[x]
+		}
+	}
+	for x := range tri {
+		tri[x] /= 2
+		frust[x] /= 2
+	}
+	return tri, frust
+}
+
+// OutputTriangles outputs, for each vertex that lies on a frustrated
+// triangle, the number of frustrated triangles and the total number of
+// triangles through it, followed by the number of frustrated triangles in the
+// graph and the triangle balance, tr(S^3)/tr(A^3), which is 1 if every
+// triangle is balanced and -1 if every triangle is frustrated.
+func OutputTriangles(w io.Writer, g Graph) {
+	sg := g.signedGraph()
+	tri, frust := sg.triangleCounts()
+	nt, nf := 0, 0
+	for x, name := range sg.Names {
+		nt += tri[x]
+		nf += frust[x]
+		if frust[x] > 0 {
+			fmt.Fprintf(w, "TRI  %d %d | %s\n", frust[x], tri[x], name)
+		}
+	}
+	nt /= 3 // Each triangle was counted once from each of its vertices.
+	nf /= 3
+	fmt.Fprintf(w, "#TRI %d / %d = %f\n", nf, nt, fraction(nf, nt))
+	bal := 0.0
+	if nt > 0 {
+		bal = float64(nt-2*nf) / float64(nt)
+	}
+	fmt.Fprintf(w, "#TRIB %f\n", bal)
+}
@@ -0,0 +1,81 @@
+/* This file identifies frustrated cycles whose resolution is a foregone
+conclusion because one coupler is far weaker than all the others. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// A trivialCycle is a frustrated cycle that is trivially resolvable.
+type trivialCycle struct {
+	Path    []string // Cycle as a vertex path
+	Weakest float64  // Magnitude of the weakest coupler
+	Next    float64  // Magnitude of the second-weakest coupler
+}
+
+// trivialResolution says whether a frustrated cycle is trivially resolvable:
+// its weakest coupler is no stronger than ratio times every other coupler,
+// so every low-energy state breaks that coupler.  It also returns the
+// magnitudes of the weakest and second-weakest couplers.
+func (g Graph) trivialResolution(p []string, ratio float64) (float64, float64, bool) {
+	min1, min2 := math.Inf(1), math.Inf(1)
+	for _, e := range g.pathToEdges(p) {
+		j := math.Abs(g.Es[e])
+		switch {
+		case j < min1:
+			min1, min2 = j, min1
+		case j < min2:
+			min2 = j
+		}
+	}
+	return min1, min2, min1 <= ratio*min2
+}
+
+// FindTrivial records in a.Trivial each frustrated cycle whose weakest
+// coupler is no stronger than ratio times every other coupler.  If exclude
+// is true, those cycles are additionally removed from a.Cycles, a.Paths, and
+// a.Frustrated so that subsequent reports consider only the conflicts that
+// are not foregone conclusions.
+func (a *Analysis) FindTrivial(ratio float64, exclude bool) {
+	a.Trivial = a.Trivial[:0]
+	a.NumFrustrated = 0
+	keep := 0
+	for i, p := range a.Paths {
+		if a.Frustrated[i] {
+			a.NumFrustrated++
+			if wk, nx, ok := a.Graph.trivialResolution(p, ratio); ok {
+				a.Trivial = append(a.Trivial, trivialCycle{Path: p, Weakest: wk, Next: nx})
+				if exclude {
+					continue
+				}
+			}
+		}
+		a.Cycles[keep] = a.Cycles[i]
+		a.Paths[keep] = a.Paths[i]
+		a.Frustrated[keep] = a.Frustrated[i]
+		keep++
+	}
+	a.Cycles = a.Cycles[:keep]
+	a.Paths = a.Paths[:keep]
+	a.Frustrated = a.Frustrated[:keep]
+}
+
+// OutputTrivial outputs each trivially resolvable frustrated cycle followed
+// by the number of such cycles as a fraction of all frustrated cycles.
+func OutputTrivial(w io.Writer, a *Analysis) {
+	for _, tc := range a.Trivial {
+		fmt.Fprintf(w, "TRV  %v %v |", tc.Weakest, tc.Next)
+		for _, v := range tc.Path {
+			fmt.Fprintf(w, " %s", v)
+		}
+		fmt.Fprintln(w, "")
+	}
+	frac := 0.0
+	if a.NumFrustrated > 0 {
+		frac = float64(len(a.Trivial)) / float64(a.NumFrustrated)
+	}
+	fmt.Fprintf(w, "#TRV %d / %d = %f\n", len(a.Trivial), a.NumFrustrated, frac)
+}
@@ -0,0 +1,193 @@
+/* This file writes graphs in formats intended for visualization tools.
+Coupler magnitudes on hardware often span orders of magnitude, so edges are
+drawn with widths and opacities that reflect |J| only coarsely, by bin. */
+
+package frustration
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Edge colors by sign
+const (
+	ferroColor     = "#1f77b4" // Color of ferromagnetic (J < 0) edges
+	antiferroColor = "#d62728" // Color of antiferromagnetic (J > 0) edges
+)
+
+// Edge colors by frustration
+const (
+	frustColor   = "#d62728" // Color of frustrated edges
+	unfrustColor = "#7f7f7f" // Color of non-frustrated edges
+)
+
+// edgeBins assigns each edge to one of opts.EdgeBins bins by |J|, from 0
+// (weakest) to opts.EdgeBins-1 (strongest).  "quantile" binning places
+// roughly equally many edges in each bin (histogram equalization), so a few
+// dominant couplers cannot flatten the rest of the picture.  "linear" binning
+// divides the range of |J| into equal intervals.
+func (g Graph) edgeBins(opts WriteOptions) map[[2]string]int {
+	nb := opts.EdgeBins
+	if nb < 1 {
+		Abortf("At least one edge bin is required")
+	}
+	mags := make([]float64, 0, len(g.Es))
+	for _, wt := range g.Es {
+		mags = append(mags, math.Abs(wt))
+	}
+	sort.Float64s(mags)
+	bins := make(map[[2]string]int, len(g.Es))
+	for e, wt := range g.Es {
+		m := math.Abs(wt)
+		b := 0
+		switch opts.EdgeBinning {
+		case "quantile":
+			// Tied magnitudes share the bin of their lowest rank.
+			b = sort.SearchFloat64s(mags, m) * nb / len(mags)
+		case "linear":
+			lo, hi := mags[0], mags[len(mags)-1]
+			if hi > lo {
+				b = int(float64(nb) * (m - lo) / (hi - lo))
+			}
+		default:
+			Abortf("Unrecognized edge binning %q", opts.EdgeBinning)
+		}
+		if b >= nb {
+			b = nb - 1
+		}
+		bins[e] = b
+	}
+	return bins
+}
+
+// edgeStyle returns the line width and the color, including an opacity, with
+// which to draw an edge in a given bin.
+func edgeStyle(wt float64, bin, nb int) (float64, string) {
+	width := 1.0 + 2.0*float64(bin)
+	alpha := 255
+	if nb > 1 {
+		alpha = 64 + 191*bin/(nb-1)
+	}
+	color := antiferroColor
+	if wt < 0 {
+		color = ferroColor
+	}
+	return width, fmt.Sprintf("%s%02x", color, alpha)
+}
+
+// WriteDOTFile writes a graph in Graphviz DOT format.  Edges are colored by
+// sign and drawn with a width and opacity that increase with their |J| bin.
+// Any provenance in opts precedes the graph as "//" comment lines.
+func WriteDOTFile(w io.Writer, g Graph, opts WriteOptions) {
+	bins := g.edgeBins(opts)
+	bw := bufio.NewWriter(w)
+	if opts.Provenance != nil {
+		opts.Provenance.WriteText(bw, "//")
+	}
+	fmt.Fprintln(bw, "graph frustration {")
+	fmt.Fprintln(bw, "  node [shape=circle];")
+	for _, v := range g.sortedVertices() {
+		fmt.Fprintf(bw, "  %s [tooltip=%s];\n", strconv.Quote(v), strconv.Quote(fmt.Sprintf("h = %v", g.Vs[v])))
+	}
+	for _, e := range g.sortedEdges() {
+		wt := g.Es[e]
+		width, color := edgeStyle(wt, bins[e], opts.EdgeBins)
+		fmt.Fprintf(bw, "  %s -- %s [penwidth=%g, color=%q, tooltip=%s];\n",
+			strconv.Quote(e[0]), strconv.Quote(e[1]), width, color, strconv.Quote(fmt.Sprintf("J = %v", wt)))
+	}
+	fmt.Fprintln(bw, "}")
+	CheckError(bw.Flush())
+}
+
+// WriteFrustrationDOT writes a graph in Graphviz DOT format with its
+// frustration structure highlighted.  Edges deemed frustrated by rules.Edge
+// are drawn in red and all others in gray, each with a width that increases
+// with its |J| bin.  Vertices deemed frustrated by rules.Vertex are drawn as
+// filled boxes and all others as circles.  Each element's tooltip gives its
+// coefficient and the numbers of frustrated and non-frustrated cycles
+// through it.  Any provenance in opts precedes the graph as "//" comment
+// lines.
+func WriteFrustrationDOT(w io.Writer, g Graph, ps [][]string, isFrust []bool, rules ClassRules, opts WriteOptions) {
+	bins := g.edgeBins(opts)
+	vts, ets := g.tallyMembers(ps, isFrust, rules.Weighted())
+	counts := func(t *memberTally) string {
+		if t == nil {
+			return "no cycles"
+		}
+		return fmt.Sprintf("%d frustrated, %d non-frustrated cycles", t.F, t.NF)
+	}
+	bw := bufio.NewWriter(w)
+	if opts.Provenance != nil {
+		opts.Provenance.WriteText(bw, "//")
+	}
+	fmt.Fprintln(bw, "graph frustration {")
+	fmt.Fprintln(bw, "  node [shape=circle];")
+	for _, v := range g.sortedVertices() {
+		t := vts[v]
+		attrs := ""
+		if t != nil && t.frustrated(rules.Vertex) {
+			attrs = fmt.Sprintf("shape=box, style=filled, fillcolor=%q, ", frustColor)
+		}
+		fmt.Fprintf(bw, "  %s [%stooltip=%s];\n", strconv.Quote(v), attrs,
+			strconv.Quote(fmt.Sprintf("h = %v; %s", g.Vs[v], counts(t))))
+	}
+	for _, e := range g.sortedEdges() {
+		wt := g.Es[e]
+		width, _ := edgeStyle(wt, bins[e], opts.EdgeBins)
+		t := ets[e]
+		color := unfrustColor
+		if t != nil && t.frustrated(rules.Edge) {
+			color = frustColor
+		}
+		fmt.Fprintf(bw, "  %s -- %s [penwidth=%g, color=%q, tooltip=%s];\n",
+			strconv.Quote(e[0]), strconv.Quote(e[1]), width, color,
+			strconv.Quote(fmt.Sprintf("J = %v; %s", wt, counts(t))))
+	}
+	fmt.Fprintln(bw, "}")
+	CheckError(bw.Flush())
+}
+
+// xmlEscape returns a string with XML special characters escaped.
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	CheckError(xml.EscapeText(&sb, []byte(s)))
+	return sb.String()
+}
+
+// WriteGraphMLFile writes a graph in GraphML format.  Each vertex carries its
+// field, and each edge carries its coupler strength, its |J| bin, and the
+// line width and color that WriteDOTFile would use to draw it.  Any
+// provenance in opts follows the XML declaration as a comment.
+func WriteGraphMLFile(w io.Writer, g Graph, opts WriteOptions) {
+	bins := g.edgeBins(opts)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, `<?xml version="1.0" encoding="UTF-8"?>`)
+	if opts.Provenance != nil {
+		opts.Provenance.WriteXMLComment(bw)
+	}
+	fmt.Fprintln(bw, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(bw, `  <key id="h" for="node" attr.name="h" attr.type="double"/>`)
+	fmt.Fprintln(bw, `  <key id="J" for="edge" attr.name="J" attr.type="double"/>`)
+	fmt.Fprintln(bw, `  <key id="bin" for="edge" attr.name="bin" attr.type="int"/>`)
+	fmt.Fprintln(bw, `  <key id="width" for="edge" attr.name="width" attr.type="double"/>`)
+	fmt.Fprintln(bw, `  <key id="color" for="edge" attr.name="color" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <graph id="frustration" edgedefault="undirected">`)
+	for _, v := range g.sortedVertices() {
+		fmt.Fprintf(bw, "    <node id=\"%s\"><data key=\"h\">%v</data></node>\n", xmlEscape(v), g.Vs[v])
+	}
+	for _, e := range g.sortedEdges() {
+		wt := g.Es[e]
+		width, color := edgeStyle(wt, bins[e], opts.EdgeBins)
+		fmt.Fprintf(bw, "    <edge source=\"%s\" target=\"%s\"><data key=\"J\">%v</data><data key=\"bin\">%d</data><data key=\"width\">%g</data><data key=\"color\">%s</data></edge>\n",
+			xmlEscape(e[0]), xmlEscape(e[1]), wt, bins[e], width, color)
+	}
+	fmt.Fprintln(bw, "  </graph>")
+	fmt.Fprintln(bw, "</graphml>")
+	CheckError(bw.Flush())
+}
@@ -0,0 +1,166 @@
+/* This file collects warnings about the input and the analysis.  Each warning
+has a stable, machine-readable code so that automated pipelines can react to
+particular data-quality issues, suppress those they consider benign, or fail
+on any that remain. */
+
+package frustration
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A Severity says how much a warning matters.
+type Severity string
+
+// These are the severities a warning can have.  Only warnings of severity
+// SeverityWarning cause --fail-on-warning to fail.
+const (
+	SeverityWarning Severity = "warning" // Results may be misleading
+	SeverityInfo    Severity = "info"    // An option or input was ignored
+)
+
+// warningCodes maps each warning code to its severity.  Codes are never
+// renumbered or reused.
+var warningCodes = map[string]Severity{
+	"W001-duplicate-coupler":  SeverityWarning,
+	"W002-cancelled-couplers": SeverityWarning,
+	"W003-dropped-offset":     SeverityWarning,
+	"W004-baseline-rules":     SeverityWarning,
+	"W005-unknown-variables":  SeverityWarning,
+	"W006-incomplete-cycles":  SeverityWarning,
+	"W007-ignored-option":     SeverityInfo,
+	"W008-skipped-solver":     SeverityInfo,
+	"W009-skipped-file":       SeverityInfo,
+	"W010-unreadable-job":     SeverityWarning,
+	"W011-large-cycle-space":  SeverityInfo,
+}
+
+// warnNotifyLimit is the number of warnings with the same code that are
+// written to Notify; further warnings are recorded but not written.
+const warnNotifyLimit = 10
+
+// A Warning describes one issue that did not prevent the analysis.
+type Warning struct {
+	Code     string   `json:"code"`               // Stable identifier, e.g., "W001-duplicate-coupler"
+	Severity Severity `json:"severity"`           // How much the warning matters
+	Location string   `json:"location,omitempty"` // Vertex, edge, file, or option concerned ("" if none)
+	Message  string   `json:"message"`            // Human-readable description
+}
+
+// String formats a warning as a single line.
+func (wn Warning) String() string {
+	loc := ""
+	if wn.Location != "" {
+		loc = " at " + wn.Location
+	}
+	return fmt.Sprintf("Warning %s (%s)%s: %s", wn.Code, wn.Severity, loc, wn.Message)
+}
+
+// warnings holds the warnings issued so far.
+var warnings struct {
+	sync.Mutex
+	List       []Warning       // Unsuppressed warnings in the order issued
+	Counts     map[string]int  // Number of unsuppressed warnings with each code
+	Suppressed map[string]bool // Codes whose warnings are discarded
+}
+
+// lookupWarningCode returns the full code that a full code or its prefix
+// up to the hyphen (e.g., "W001") names.
+func lookupWarningCode(s string) (string, bool) {
+	if _, ok := warningCodes[s]; ok {
+		return s, true
+	}
+	for c := range warningCodes {
+		if strings.HasPrefix(c, s+"-") {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// SuppressWarnings discards all future warnings whose codes appear in a
+// comma-separated list.  Each code may be given in full or by its prefix up to
+// the hyphen.
+func SuppressWarnings(list string) {
+	warnings.Lock()
+	defer warnings.Unlock()
+	if warnings.Suppressed == nil {
+		warnings.Suppressed = make(map[string]bool)
+	}
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		c, ok := lookupWarningCode(s)
+		if !ok {
+			codes := make([]string, 0, len(warningCodes))
+			for c := range warningCodes {
+				codes = append(codes, c)
+			}
+			sort.Strings(codes)
+			Abortf("Unrecognized warning code %q (available: %s)", s, strings.Join(codes, ", "))
+		}
+		warnings.Suppressed[c] = true
+	}
+}
+
+// Warn issues a warning with a given code and location and a formatted
+// message.  Unless the code is suppressed, the warning is recorded and, for
+// the first few warnings with each code, written to Notify.
+func Warn(code, loc, format string, a ...interface{}) {
+	sev, ok := warningCodes[code]
+	if !ok {
+		panic(fmt.Sprintf("unregistered warning code %q", code))
+	}
+	wn := Warning{Code: code, Severity: sev, Location: loc, Message: fmt.Sprintf(format, a...)}
+	warnings.Lock()
+	defer warnings.Unlock()
+	if warnings.Suppressed[code] {
+		return
+	}
+	if warnings.Counts == nil {
+		warnings.Counts = make(map[string]int)
+	}
+	warnings.List = append(warnings.List, wn)
+	warnings.Counts[code]++
+	switch n := warnings.Counts[code]; {
+	case n <= warnNotifyLimit:
+		Notify.Print(wn)
+	case n == warnNotifyLimit+1:
+		Notify.Printf("Further %s warnings will not be shown", code)
+	}
+}
+
+// Warnings returns the unsuppressed warnings issued so far.
+func Warnings() []Warning {
+	warnings.Lock()
+	defer warnings.Unlock()
+	return append([]Warning(nil), warnings.List...)
+}
+
+// SevereWarnings returns the number of unsuppressed warnings issued so far
+// whose severity is SeverityWarning.
+func SevereWarnings() int {
+	warnings.Lock()
+	defer warnings.Unlock()
+	n := 0
+	for _, wn := range warnings.List {
+		if wn.Severity == SeverityWarning {
+			n++
+		}
+	}
+	return n
+}
+
+// OutputWarnings outputs each unsuppressed warning issued so far as a tagged
+// line.
+func OutputWarnings(w io.Writer) {
+	for _, wn := range Warnings() {
+		fmt.Fprintf(w, "WARN %s %s | %s\n", wn.Code, wn.Severity, wn.Location)
+	}
+}
@@ -0,0 +1,189 @@
+/* This file provides functions for writing graphs in various formats. */
+
+package frustration
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteOptions specifies how a graph is written.  Only visualization formats
+// bin edges.
+type WriteOptions struct {
+	EdgeBins    int         // Number of distinct edge widths and opacities
+	EdgeBinning string      // How |J| maps to a bin: "quantile" or "linear"
+	Provenance  *Provenance // Provenance recorded at the top of the output (nil for none)
+}
+
+// graphWriters maps each output format to a function that writes a graph in
+// that format.
+var graphWriters = map[string]func(w io.Writer, g Graph, opts WriteOptions){
+	"ffg":     func(w io.Writer, g Graph, opts WriteOptions) { WriteFFGFile(w, g, opts.Provenance) },
+	"qubist":  func(w io.Writer, g Graph, opts WriteOptions) { WriteQubistFile(w, g, opts.Provenance) },
+	"qmasm":   func(w io.Writer, g Graph, opts WriteOptions) { WriteQMASMFile(w, g, opts.Provenance) },
+	"dot":     WriteDOTFile,
+	"graphml": WriteGraphMLFile,
+}
+
+// WriteGraph writes a graph in the named format.
+func WriteGraph(outFmt string, w io.Writer, g Graph, opts WriteOptions) {
+	write, ok := graphWriters[outFmt]
+	if !ok {
+		names := make([]string, 0, len(graphWriters))
+		for n := range graphWriters {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		Abortf("Unrecognized output format %q (available: %s)", outFmt, strings.Join(names, ", "))
+	}
+	write(w, g, opts)
+}
+
+// ffgMagic identifies a file as a find-frustration graph.
+const ffgMagic = "find-frustration graph"
+
+// ffgVersion is the version of the find-frustration graph format that we
+// write.
+const ffgVersion = 1
+
+// An ffgHeader precedes the graph in a find-frustration graph file.
+type ffgHeader struct {
+	Magic      string      // Always ffgMagic
+	Version    int         // Format version
+	Provenance *Provenance // Provenance of the graph (nil if not recorded)
+}
+
+// WriteFFGFile writes a graph in find-frustration's compact, binary graph
+// format, which can be read back much faster than any textual format.  prov,
+// if non-nil, is recorded in the header.
+func WriteFFGFile(w io.Writer, g Graph, prov *Provenance) {
+	enc := gob.NewEncoder(w)
+	CheckError(enc.Encode(ffgHeader{Magic: ffgMagic, Version: ffgVersion, Provenance: prov}))
+	CheckError(enc.Encode(g))
+}
+
+// WriteQubistFile writes a graph in Qubist format: a header line giving the
+// number of qubits and the number of subsequent lines, then one "i i h" line
+// per nonzero external field (or per isolated vertex) and one "i j J" line
+// per edge.  The number of qubits is one more than the largest vertex name
+// if all names are nonnegative integers and the number of vertices
+// otherwise.  Qubist has no notion of an energy offset, so any offset is
+// dropped.  prov, if non-nil, precedes the header as "#" comment lines.
+func WriteQubistFile(w io.Writer, g Graph, prov *Provenance) {
+	// Determine which vertices need a line of their own.
+	deg := make(map[string]int, len(g.Vs))
+	for e := range g.Es {
+		deg[e[0]]++
+		deg[e[1]]++
+	}
+	vs := make([]string, 0, len(g.Vs))
+	for _, v := range g.sortedVertices() {
+		if strings.ContainsAny(v, " \t\r\n") || v == "" {
+			Abortf("Vertex name %q cannot be represented in Qubist format", v)
+		}
+		if g.Vs[v] != 0 || deg[v] == 0 {
+			vs = append(vs, v)
+		}
+	}
+	if g.Offset != 0 {
+		Warn("W003-dropped-offset", "qubist", "Dropping an energy offset of %v, which Qubist format cannot represent", g.Offset)
+	}
+
+	// Determine the number of qubits.
+	nq := 0
+	for v := range g.Vs {
+		q, err := strconv.Atoi(v)
+		if err != nil || q < 0 {
+			nq = len(g.Vs)
+			break
+		}
+		if q >= nq {
+			nq = q + 1
+		}
+	}
+
+	// Write the provenance, the header, then the fields and couplers.
+	bw := bufio.NewWriter(w)
+	if prov != nil {
+		prov.WriteText(bw, "#")
+	}
+	fmt.Fprintf(bw, "%d %d\n", nq, len(vs)+len(g.Es))
+	for _, v := range vs {
+		fmt.Fprintf(bw, "%s %s %v\n", v, v, g.Vs[v])
+	}
+	for _, e := range g.sortedEdges() {
+		fmt.Fprintf(bw, "%s %s %v\n", e[0], e[1], g.Es[e])
+	}
+	CheckError(bw.Flush())
+}
+
+// checkQMASMName aborts if a vertex name cannot be written to a QMASM file
+// and read back unchanged.
+func checkQMASMName(v string) {
+	if v == "" || strings.ContainsAny(v, " \t\r\n#") || strings.HasPrefix(v, "!") {
+		Abortf("Vertex name %q cannot be represented in QMASM format", v)
+	}
+}
+
+// WriteQMASMFile writes a graph as a flat QMASM program: one "v h" line per
+// nonzero external field (or per isolated vertex) and one "u v J" line per
+// edge.  Edges known to be chains are written as "u <-> v" statements, plus
+// a "u v J" line for any strength beyond the -1 that "<->" implies, so the
+// program reads back as the same graph.  Macro structure is not
+// reconstructed; vertices retain their fully qualified names.  QMASM has no
+// notion of an energy offset, so any offset is dropped.  prov, if non-nil,
+// is written first as "#" comment lines.
+func WriteQMASMFile(w io.Writer, g Graph, prov *Provenance) {
+	if g.Offset != 0 {
+		Warn("W003-dropped-offset", "qmasm", "Dropping an energy offset of %v, which QMASM format cannot represent", g.Offset)
+	}
+	bw := bufio.NewWriter(w)
+	if prov != nil {
+		prov.WriteText(bw, "#")
+	}
+
+	// Write the external fields.
+	deg := make(map[string]int, len(g.Vs))
+	for e := range g.Es {
+		deg[e[0]]++
+		deg[e[1]]++
+	}
+	for _, v := range g.sortedVertices() {
+		checkQMASMName(v)
+		if g.Vs[v] != 0 || deg[v] == 0 {
+			fmt.Fprintf(bw, "%s %v\n", v, g.Vs[v])
+		}
+	}
+
+	// Write the chains then the remaining couplers.
+	es := g.sortedEdges()
+	nChains := 0
+	for _, e := range es {
+		if g.EKind[e] == EdgeChain {
+			if nChains == 0 {
+				fmt.Fprintln(bw, "\n# Chains")
+			}
+			fmt.Fprintf(bw, "%s <-> %s\n", e[0], e[1])
+			nChains++
+		}
+	}
+	if len(es) > nChains {
+		fmt.Fprintln(bw, "\n# Couplers")
+	}
+	for _, e := range es {
+		wt := g.Es[e]
+		if g.EKind[e] == EdgeChain {
+			wt++ // Strength beyond that of the chain
+			if wt == 0 {
+				continue
+			}
+		}
+		fmt.Fprintf(bw, "%s %s %v\n", e[0], e[1], wt)
+	}
+	CheckError(bw.Flush())
+}